@@ -0,0 +1,79 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"bytes"
+	"github.com/go-chi/chi/v5"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/games"
+	"github.com/mdhender/wraithe/pkg/wraith"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+)
+
+// clusterMap serves the interactive map of the cluster for a nation.
+// The page doesn't contain any systems; it fetches them from the REST API.
+func clusterMap(reg *games.Registry, templates, apiPrefix string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, err := reg.Get(chi.URLParam(r, authz.GameParam))
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		nation := chi.URLParam(r, authz.NationParam)
+		if !g.HasNation(nation) {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+
+		base := apiPrefix + "/games/" + url.PathEscape(g.Id) + "/nations/" + url.PathEscape(nation) + "/systems"
+		data := struct {
+			Game       string
+			Nation     string
+			SystemsURL string
+			SystemURL  string
+			Legend     []wraith.LegendEntry
+		}{
+			Game:       g.Name,
+			Nation:     nation,
+			SystemsURL: base,
+			SystemURL:  base + "/",
+			Legend:     wraith.Legend(),
+		}
+
+		t, err := template.ParseFiles(filepath.Join(templates, "map.gohtml"))
+		if err != nil {
+			log.Printf("[html] map: %v\n", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		bw := &bytes.Buffer{}
+		if err = t.Execute(bw, data); err != nil {
+			log.Printf("[html] map: %v\n", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(bw.Bytes())
+	}
+}
@@ -21,13 +21,19 @@ package handlers
 
 import (
 	"github.com/go-chi/chi/v5"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/games"
 	"net/http"
 )
 
-func Routes() http.Handler {
+// Routes returns the router for the HTML pages.
+// Templates are loaded from the templates directory.
+// Pages that need data fetch it from the REST API mounted at apiPrefix.
+func Routes(reg *games.Registry, templates, apiPrefix string) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write([]byte("Hello World!"))
 	})
+	r.With(authz.RequireNation).Get("/games/{gameId}/nations/{nation}/map", clusterMap(reg, templates, apiPrefix))
 	return r
 }
@@ -0,0 +1,204 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/games"
+	"github.com/mdhender/wraithe/pkg/wraith"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// listSystems returns the systems in the game's cluster that the caller can see.
+// If the radius query parameter is given, only the systems within that
+// distance of the point (x, y, z) are returned, sorted by distance.
+// The point defaults to the origin.
+func listSystems(reg *games.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, ok := fetchGame(w, r, reg)
+		if !ok {
+			return
+		}
+		c, visible, ok := fetchCluster(w, r, g)
+		if !ok {
+			return
+		}
+
+		q := r.URL.Query()
+		if q.Get("radius") == "" {
+			render.JSON(w, r, c.Systems(visible))
+			return
+		}
+		var pt [4]float64 // x, y, z, radius
+		for i, key := range []string{"x", "y", "z", "radius"} {
+			if q.Get(key) == "" {
+				continue
+			}
+			val, err := strconv.ParseFloat(q.Get(key), 64)
+			if err != nil {
+				http.Error(w, key+": "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			pt[i] = val
+		}
+		if pt[3] < 0 {
+			http.Error(w, "radius: must not be negative", http.StatusBadRequest)
+			return
+		}
+		render.JSON(w, r, c.Within(pt[0], pt[1], pt[2], pt[3], visible))
+	}
+}
+
+// getSystem returns a single system with its stars and planets.
+// Systems the caller can't see are reported as not found.
+func getSystem(reg *games.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, ok := fetchGame(w, r, reg)
+		if !ok {
+			return
+		}
+		c, visible, ok := fetchCluster(w, r, g)
+		if !ok {
+			return
+		}
+		id, err := strconv.Atoi(chi.URLParam(r, "systemId"))
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		sys, ok := c.System(id, visible)
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		render.JSON(w, r, sys)
+	}
+}
+
+// listNationSystems returns the systems in the game's cluster that the
+// nation named in the URL knows about.
+func listNationSystems(reg *games.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, ok := fetchGame(w, r, reg)
+		if !ok {
+			return
+		}
+		c, visible, ok := fetchNationCluster(w, r, g)
+		if !ok {
+			return
+		}
+		render.JSON(w, r, c.Systems(visible))
+	}
+}
+
+// getNationSystem returns a single system with its stars and planets
+// if the nation named in the URL knows about it.
+func getNationSystem(reg *games.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, ok := fetchGame(w, r, reg)
+		if !ok {
+			return
+		}
+		c, visible, ok := fetchNationCluster(w, r, g)
+		if !ok {
+			return
+		}
+		id, err := strconv.Atoi(chi.URLParam(r, "systemId"))
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		sys, ok := c.System(id, visible)
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		render.JSON(w, r, sys)
+	}
+}
+
+// fetchNationCluster is a helper that returns the game's cluster and the
+// view of it for the nation named in the URL. It writes a 404 Not Found
+// if the nation doesn't exist.
+// The caller should return from the handler if this returns false.
+func fetchNationCluster(w http.ResponseWriter, r *http.Request, g *games.Game) (*wraith.Cluster, wraith.Visibility, bool) {
+	c, err := g.Cluster()
+	if err != nil {
+		log.Printf("[rest] game %q: cluster: %v\n", g.Id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	s, err := g.State()
+	if err != nil {
+		log.Printf("[rest] game %q: state: %v\n", g.Id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	n, ok := s.Nations[chi.URLParam(r, authz.NationParam)]
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return nil, nil, false
+	}
+	return c, wraith.NationVisibility(n), true
+}
+
+// fetchCluster is a helper that returns the game's cluster and the
+// caller's view of it. It writes a 500 Internal Server Error if the
+// cluster can't be loaded.
+// The caller should return from the handler if this returns false.
+func fetchCluster(w http.ResponseWriter, r *http.Request, g *games.Game) (*wraith.Cluster, wraith.Visibility, bool) {
+	c, err := g.Cluster()
+	if err != nil {
+		log.Printf("[rest] game %q: cluster: %v\n", g.Id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	visible, err := visibility(r, g)
+	if err != nil {
+		log.Printf("[rest] game %q: state: %v\n", g.Id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	return c, visible, true
+}
+
+// visibility implements the fog of war.
+// Referees and observers see every system.
+// Players see only the systems known to their nations.
+func visibility(r *http.Request, g *games.Game) (wraith.Visibility, error) {
+	az := authz.FromContext(r.Context())
+	if az.IsReferee(g.Id) || az.HasRole(g.Id, authz.Observer) {
+		return wraith.All, nil
+	}
+	s, err := g.State()
+	if err != nil {
+		return nil, err
+	}
+	var nations []*wraith.Nation
+	for _, id := range az.Nations(g.Id) {
+		if n, ok := s.Nations[id]; ok {
+			nations = append(nations, n)
+		}
+	}
+	return wraith.NationVisibility(nations...), nil
+}
@@ -0,0 +1,56 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/games"
+	"github.com/mdhender/wraithe/pkg/wraith"
+	"log"
+	"net/http"
+)
+
+// getContacts returns the foreign ships and colonies that the nation
+// named in the URL detected during the last turn.
+func getContacts(reg *games.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, ok := fetchGame(w, r, reg)
+		if !ok {
+			return
+		}
+		s, err := g.State()
+		if err != nil {
+			log.Printf("[rest] game %q: state: %v\n", g.Id, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		n, ok := s.Nations[chi.URLParam(r, authz.NationParam)]
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		contacts := n.Contacts
+		if contacts == nil {
+			contacts = []*wraith.Contact{}
+		}
+		render.JSON(w, r, contacts)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/games"
+	"log"
+	"net/http"
+	"time"
+)
+
+// gameResponse is the public view of a game's metadata.
+// It doesn't include the seed or the user ids of the players.
+type gameResponse struct {
+	Id       string     `json:"id"`
+	Name     string     `json:"name"`
+	Turn     int        `json:"turn"`
+	Status   string     `json:"status"`
+	Created  time.Time  `json:"created"`
+	Deadline *time.Time `json:"deadline,omitempty"`
+	Nations  []string   `json:"nations,omitempty"`
+}
+
+func newGameResponse(g *games.Game) gameResponse {
+	p := g.Progress()
+	rsp := gameResponse{
+		Id:      g.Id,
+		Name:    g.Name,
+		Turn:    p.Turn,
+		Status:  string(p.Status),
+		Created: g.Created,
+	}
+	if !p.Deadline.IsZero() {
+		rsp.Deadline = &p.Deadline
+	}
+	for _, p := range g.Players {
+		rsp.Nations = append(rsp.Nations, p.Nation)
+	}
+	return rsp
+}
+
+// listGames returns the metadata for every game the user may read.
+// Anonymous users are not told which games are on the server.
+func listGames(reg *games.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		az := authz.FromContext(r.Context())
+		if !az.IsAuthenticated() {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		list := []gameResponse{}
+		for _, g := range reg.List() {
+			if az.Can(g.Id, authz.ReadGame, "") {
+				list = append(list, newGameResponse(g))
+			}
+		}
+		render.JSON(w, r, list)
+	}
+}
+
+// getGame returns the metadata for a single game.
+func getGame(reg *games.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, ok := fetchGame(w, r, reg)
+		if !ok {
+			return
+		}
+		render.JSON(w, r, newGameResponse(g))
+	}
+}
+
+// activateGame starts or resumes a game.
+// The scheduler runs the game's turns once it is active.
+func activateGame(reg *games.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, ok := fetchGame(w, r, reg)
+		if !ok {
+			return
+		}
+		if err := g.Activate(); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		saveGame(w, r, reg, g)
+	}
+}
+
+// scheduleGame replaces the game's schedule with the one in the request body.
+func scheduleGame(reg *games.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, ok := fetchGame(w, r, reg)
+		if !ok {
+			return
+		}
+		var s games.Schedule
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&s); err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		if err := g.SetSchedule(s); errors.Is(err, games.ErrFinished) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		saveGame(w, r, reg, g)
+	}
+}
+
+// saveGame is a helper that saves the game and returns its metadata.
+func saveGame(w http.ResponseWriter, r *http.Request, reg *games.Registry, g *games.Game) {
+	if err := reg.Save(g); err != nil {
+		log.Printf("[rest] game %q: %v\n", g.Id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	render.JSON(w, r, newGameResponse(g))
+}
+
+// fetchGame is a helper that returns the game named in the URL.
+// It writes a 404 Not Found if the game doesn't exist.
+// The caller should return from the handler if this returns false.
+func fetchGame(w http.ResponseWriter, r *http.Request, reg *games.Registry) (*games.Game, bool) {
+	g, err := reg.Get(chi.URLParam(r, authz.GameParam))
+	if errors.Is(err, games.ErrNotFound) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return nil, false
+	} else if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, false
+	}
+	return g, true
+}
@@ -0,0 +1,130 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/games"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGameAdmin(t *testing.T) {
+	reg, err := games.Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = reg.Create(&games.Game{Id: "g1", Players: []games.Player{{UserId: "p1", Nation: "n1"}}}); err != nil {
+		t.Fatal(err)
+	}
+	referee := authz.Authorization{UserId: "ref", Grants: []authz.Grant{{GameId: "g1", Role: authz.Referee}}}
+	player := authz.Authorization{UserId: "p1", Grants: []authz.Grant{{GameId: "g1", Role: authz.Player, Nation: "n1"}}}
+	h := Routes(reg)
+
+	do := func(az authz.Authorization, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), "authz", az))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, tc := range []struct {
+		id     int
+		az     authz.Authorization
+		method string
+		path   string
+		body   string
+		code   int
+		status string
+	}{
+		{1, player, "POST", "/games/g1/activate", "", http.StatusForbidden, ""},
+		{2, player, "PUT", "/games/g1/schedule", `{"time":"18:00"}`, http.StatusForbidden, ""},
+		{3, referee, "PUT", "/games/g1/schedule", `{"time":"6pm"}`, http.StatusUnprocessableEntity, ""},
+		{4, referee, "PUT", "/games/g1/schedule", `{"time":"18:00"}`, http.StatusOK, "setup"},
+		{5, referee, "POST", "/games/g1/activate", "", http.StatusOK, "active"},
+		{6, referee, "POST", "/games/g1/activate", "", http.StatusConflict, ""},
+	} {
+		w := do(tc.az, tc.method, tc.path, tc.body)
+		if w.Code != tc.code {
+			t.Errorf("%d: expected %d: got %d %s\n", tc.id, tc.code, w.Code, w.Body.String())
+			continue
+		} else if tc.status == "" {
+			continue
+		}
+		var rsp gameResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil || rsp.Status != tc.status {
+			t.Errorf("%d: expected status %q: got %s\n", tc.id, tc.status, w.Body.String())
+		}
+	}
+	if g, err := reg.Get("g1"); err != nil {
+		t.Fatal(err)
+	} else if p := g.Progress(); p.Status != games.Active || p.Schedule.Time != "18:00" {
+		t.Errorf("game: expected active at 18:00: got %q %+v\n", p.Status, p.Schedule)
+	}
+}
+
+func TestGameVisibility(t *testing.T) {
+	reg, err := games.Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range []string{"g1", "g2"} {
+		if err = reg.Create(&games.Game{Id: id, Players: []games.Player{{UserId: fmt.Sprintf("p%d", i+1), Nation: "n1"}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	player := authz.Authorization{UserId: "p1", Grants: []authz.Grant{{GameId: "g1", Role: authz.Player, Nation: "n1"}}}
+	h := Routes(reg)
+
+	for _, tc := range []struct {
+		id    int
+		az    authz.Authorization
+		path  string
+		code  int
+		games int
+	}{
+		{1, authz.Authorization{}, "/games", http.StatusUnauthorized, 0},
+		{2, authz.Authorization{UserId: "stranger"}, "/games", http.StatusOK, 0},
+		{3, player, "/games", http.StatusOK, 1},
+		{4, authz.Authorization{}, "/games/g1", http.StatusUnauthorized, 0},
+		{5, authz.Authorization{UserId: "stranger"}, "/games/g1", http.StatusForbidden, 0},
+		{6, player, "/games/g1", http.StatusOK, 0},
+		{7, player, "/games/g2", http.StatusForbidden, 0},
+	} {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), "authz", tc.az))
+		w := httptest.NewRecorder()
+		if h.ServeHTTP(w, req); w.Code != tc.code {
+			t.Errorf("%d: expected %d: got %d %s\n", tc.id, tc.code, w.Code, w.Body.String())
+			continue
+		} else if tc.path != "/games" || w.Code != http.StatusOK {
+			continue
+		}
+		var list []gameResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list) != tc.games {
+			t.Errorf("%d: expected %d games: got %s\n", tc.id, tc.games, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,71 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/render"
+	"github.com/mdhender/wraithe/pkg/authn"
+	"log"
+	"net/http"
+	"time"
+)
+
+// loginRequest is the body of a login request.
+type loginRequest struct {
+	UserId string `json:"user-id"`
+	Secret string `json:"secret"`
+}
+
+// loginResponse is returned when the user logs in.
+type loginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires-at"`
+}
+
+// Login checks the user's secret and returns a token.
+// The token is also set as a cookie so that browsers can use the UI.
+func Login(ids *authn.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req loginRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		t, err := ids.Authenticate(req.UserId, req.Secret)
+		if errors.Is(err, authn.ErrBadCredentials) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			log.Printf("[rest] login %q: %v\n", req.UserId, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     authn.CookieName,
+			Value:    t.String(),
+			Path:     "/",
+			Expires:  t.ExpiresAt,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		render.JSON(w, r, loginResponse{Token: t.String(), ExpiresAt: t.ExpiresAt})
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/games"
+	"github.com/mdhender/wraithe/pkg/wraith"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// maxMapSize is the largest map, in pixels, the server renders.
+// A PNG that size is 16 MB in memory; larger maps come from the map command.
+const maxMapSize = 2048
+
+// getNationMap returns a printable map of the systems that the nation
+// named in the URL knows about, as SVG or PNG depending on the extension.
+// The projection query parameter selects the view (xy, xz, yz, or iso),
+// size sets the width and height in pixels (up to maxMapSize), and
+// labels=false omits the system names.
+func getNationMap(reg *games.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, ok := fetchGame(w, r, reg)
+		if !ok {
+			return
+		}
+		c, visible, ok := fetchNationCluster(w, r, g)
+		if !ok {
+			return
+		}
+		s, err := g.State()
+		if err != nil {
+			log.Printf("[rest] game %q: state: %v\n", g.Id, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		n := s.Nations[chi.URLParam(r, authz.NationParam)]
+
+		q := r.URL.Query()
+		opts := wraith.MapOptions{Visible: visible, Owners: s.Owners(n), Labels: q.Get("labels") != "false"}
+		if q.Get("projection") != "" {
+			if opts.Projection, err = wraith.ParseProjection(q.Get("projection")); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if q.Get("size") != "" {
+			if opts.Size, err = strconv.Atoi(q.Get("size")); err != nil || opts.Size < 64 || opts.Size > maxMapSize {
+				http.Error(w, fmt.Sprintf("size: must be between 64 and %d", maxMapSize), http.StatusBadRequest)
+				return
+			}
+		}
+		opts.Title = fmt.Sprintf("%s turn %d: %s (%s)", g.Name, s.Turn, n.Name, opts.Projection)
+
+		bw := &bytes.Buffer{}
+		switch chi.URLParam(r, "format") {
+		case "svg":
+			err = c.RenderSVG(bw, opts)
+			w.Header().Set("Content-Type", "image/svg+xml")
+		case "png":
+			err = c.RenderPNG(bw, opts)
+			w.Header().Set("Content-Type", "image/png")
+		default:
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("[rest] game %q: map: %v\n", g.Id, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bw.Bytes())
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/games"
+	"github.com/mdhender/wraithe/pkg/orders"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxOrdersSize is the largest orders file we will accept.
+const maxOrdersSize = 64 * 1024
+
+// ordersResponse is returned when orders are accepted or fetched.
+type ordersResponse struct {
+	Turn    int    `json:"turn"`
+	Nation  string `json:"nation"`
+	Version int    `json:"version"`
+	Orders  string `json:"orders,omitempty"`
+}
+
+// errorsResponse is returned when orders have errors.
+type errorsResponse struct {
+	Errors []*orders.Error `json:"errors"`
+}
+
+// validateOrders parses the orders in the request body and returns any errors.
+// The orders are never saved.
+func validateOrders(reg *games.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := fetchTurn(w, r, reg); !ok {
+			return
+		}
+		data, ok := readOrders(w, r)
+		if !ok {
+			return
+		}
+		_, errs := orders.Parse(data)
+		if len(errs) != 0 {
+			render.Status(r, http.StatusUnprocessableEntity)
+		}
+		render.JSON(w, r, errorsResponse{Errors: append([]*orders.Error{}, errs...)})
+	}
+}
+
+// submitOrders parses the orders in the request body and saves them
+// as the nation's current orders if there are no errors.
+func submitOrders(reg *games.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, turn, ok := fetchTurn(w, r, reg)
+		if !ok {
+			return
+		}
+		data, ok := readOrders(w, r)
+		if !ok {
+			return
+		}
+		if _, errs := orders.Parse(data); len(errs) != 0 {
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, errorsResponse{Errors: errs})
+			return
+		}
+
+		nation := chi.URLParam(r, authz.NationParam)
+		version, err := g.SubmitOrders(turn, nation, data, time.Now().UTC())
+		if err != nil {
+			ordersError(w, g, err)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, ordersResponse{Turn: turn, Nation: nation, Version: version})
+	}
+}
+
+// getOrders returns the nation's current orders for the turn.
+func getOrders(reg *games.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, turn, ok := fetchTurn(w, r, reg)
+		if !ok {
+			return
+		}
+		nation := chi.URLParam(r, authz.NationParam)
+		data, version, err := g.Orders(turn, nation)
+		if err != nil {
+			ordersError(w, g, err)
+			return
+		}
+		render.JSON(w, r, ordersResponse{Turn: turn, Nation: nation, Version: version, Orders: string(data)})
+	}
+}
+
+// fetchTurn is a helper that returns the game and turn named in the URL.
+// The caller should return from the handler if this returns false.
+func fetchTurn(w http.ResponseWriter, r *http.Request, reg *games.Registry) (*games.Game, int, bool) {
+	g, ok := fetchGame(w, r, reg)
+	if !ok {
+		return nil, 0, false
+	}
+	turn, err := strconv.Atoi(chi.URLParam(r, "turn"))
+	if err != nil || turn < 1 {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return nil, 0, false
+	}
+	return g, turn, true
+}
+
+// readOrders is a helper that returns the request body.
+// It writes a 413 Request Entity Too Large if the body is too big and a
+// 400 Bad Request if it can't be read.
+// The caller should return from the handler if this returns false.
+func readOrders(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOrdersSize))
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return nil, false
+	} else if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return nil, false
+	}
+	return data, true
+}
+
+// ordersError writes the response for an error from the orders store.
+func ordersError(w http.ResponseWriter, g *games.Game, err error) {
+	switch {
+	case errors.Is(err, games.ErrNoOrders), errors.Is(err, games.ErrUnknownNation):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, games.ErrOrdersClosed), errors.Is(err, games.ErrWrongTurn):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("[rest] game %q: orders: %v\n", g.Id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/games"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestOrders(t *testing.T) {
+	reg, err := games.Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = reg.Create(&games.Game{Id: "g1", Players: []games.Player{{UserId: "p1", Nation: "n1"}}}); err != nil {
+		t.Fatal(err)
+	}
+	player := authz.Authorization{UserId: "p1", Grants: []authz.Grant{{GameId: "g1", Role: authz.Player, Nation: "n1"}}}
+	h := Routes(reg)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), "authz", player))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	// validation returns errors with line numbers and saves nothing
+	w := do("POST", "/games/g1/turns/1/orders/n1/validate", "move S1 42\njump S1 7\n")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("validate: expected %d: got %d\n", http.StatusUnprocessableEntity, w.Code)
+	}
+	var rsp errorsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil || len(rsp.Errors) != 1 || rsp.Errors[0].Line != 2 {
+		t.Errorf("validate: expected one error on line 2: got %s\n", w.Body.String())
+	}
+	if w = do("GET", "/games/g1/turns/1/orders/n1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("get: expected %d: got %d\n", http.StatusNotFound, w.Code)
+	}
+
+	// every accepted submission is a new version
+	for version := 1; version <= 2; version++ {
+		if w = do("POST", "/games/g1/turns/1/orders/n1", "move S1 42\n"); w.Code != http.StatusCreated {
+			t.Fatalf("submit: expected %d: got %d %s\n", http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+	w = do("GET", "/games/g1/turns/1/orders/n1", "")
+	var got ordersResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Version != 2 || got.Orders != "move S1 42\n" {
+		t.Errorf("get: expected version 2: got %s\n", w.Body.String())
+	}
+
+	// orders for the wrong turn or another nation are rejected
+	if w = do("POST", "/games/g1/turns/2/orders/n1", "move S1 42\n"); w.Code != http.StatusConflict {
+		t.Errorf("wrong turn: expected %d: got %d\n", http.StatusConflict, w.Code)
+	}
+	if w = do("POST", "/games/g1/turns/1/orders/n2", "move S1 42\n"); w.Code != http.StatusForbidden {
+		t.Errorf("other nation: expected %d: got %d\n", http.StatusForbidden, w.Code)
+	}
+
+	// bodies that are too big or can't be read are rejected
+	if w = do("POST", "/games/g1/turns/1/orders/n1", strings.Repeat("move S1 42\n", maxOrdersSize/10)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("too big: expected %d: got %d\n", http.StatusRequestEntityTooLarge, w.Code)
+	}
+	req := httptest.NewRequest("POST", "/games/g1/turns/1/orders/n1", iotest.ErrReader(errors.New("connection reset")))
+	req = req.WithContext(context.WithValue(req.Context(), "authz", player))
+	w = httptest.NewRecorder()
+	if h.ServeHTTP(w, req); w.Code != http.StatusBadRequest {
+		t.Errorf("broken body: expected %d: got %d\n", http.StatusBadRequest, w.Code)
+	}
+}
@@ -22,10 +22,12 @@ package handlers
 import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/games"
 	"net/http"
 )
 
-func Routes() http.Handler {
+func Routes(reg *games.Registry) http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(render.SetContentType(render.ContentTypeJSON))
@@ -38,6 +40,32 @@ func Routes() http.Handler {
 		_, _ = w.Write([]byte("pong"))
 	})
 
+	r.Get("/games", listGames(reg))
+	r.Route("/games/{gameId}", func(r chi.Router) {
+		r.With(authz.Require(authz.ReadGame)).Get("/", getGame(reg))
+		r.With(authz.Require(authz.AdminGame)).Post("/activate", activateGame(reg))
+		r.With(authz.Require(authz.AdminGame)).Put("/schedule", scheduleGame(reg))
+		r.Route("/cluster", func(r chi.Router) {
+			r.Use(authz.Require(authz.ReadCluster))
+			r.Get("/systems", listSystems(reg))
+			r.Get("/systems/{systemId}", getSystem(reg))
+		})
+		r.Route("/nations/{nation}", func(r chi.Router) {
+			r.Use(authz.RequireNation)
+			r.Get("/systems", listNationSystems(reg))
+			r.Get("/systems/{systemId}", getNationSystem(reg))
+			r.Get("/map.{format}", getNationMap(reg))
+			r.Get("/travel", getTravelMatrix(reg))
+			r.Get("/contacts", getContacts(reg))
+		})
+		r.With(authz.Require(authz.ReadReports)).Get("/turns/{turn}/reports/{nation}", getReport(reg))
+		r.Route("/turns/{turn}/orders/{nation}", func(r chi.Router) {
+			r.With(authz.Require(authz.ReadOrders)).Get("/", getOrders(reg))
+			r.With(authz.Require(authz.SubmitOrders)).Post("/", submitOrders(reg))
+			r.With(authz.Require(authz.SubmitOrders)).Post("/validate", validateOrders(reg))
+		})
+	})
+
 	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
 		panic("test")
 	})
@@ -0,0 +1,51 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/games"
+	"log"
+	"net/http"
+)
+
+// getTravelMatrix returns the distances and turns to arrive between
+// every pair of systems known to the nation named in the URL, for each
+// ship design that can move.
+func getTravelMatrix(reg *games.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, ok := fetchGame(w, r, reg)
+		if !ok {
+			return
+		}
+		c, _, ok := fetchNationCluster(w, r, g)
+		if !ok {
+			return
+		}
+		s, err := g.State()
+		if err != nil {
+			log.Printf("[rest] game %q: state: %v\n", g.Id, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		render.JSON(w, r, c.TravelMatrix(s, s.Nations[chi.URLParam(r, authz.NationParam)]))
+	}
+}
@@ -20,19 +20,11 @@
 package main
 
 import (
-	"context"
-	"errors"
-	"github.com/mdhender/wraithe/pkg/cedar"
 	"github.com/mdhender/wraithe/pkg/cfg"
 	"github.com/mdhender/wraithe/pkg/cli"
-	"github.com/mitchellh/go-homedir"
 	"log"
-	"math/rand"
-	"net"
 	"net/http"
-	"os"
 	"path"
-	"path/filepath"
 	"strings"
 	"time"
 )
@@ -46,73 +38,9 @@ func main() {
 		log.Printf("wraith: total time %v\n", elapsed)
 	}(time.Now())
 
-	// find starting directory
-	cwd, err := os.Getwd()
-	if err != nil {
+	if err := cli.Execute(cfg.Default()); err != nil {
 		log.Fatal(err)
 	}
-
-	// find home directory
-	home, err := homedir.Dir()
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Printf("%-30s == %q\n", "home", home)
-
-	// seed the default PRNG source.
-	seed, err := cedar.Seed()
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Printf("%-30s == %d\n", "seed", seed)
-	rand.Seed(seed)
-
-	if err := cli.Execute(nil); err != nil {
-		log.Fatal(err)
-	}
-
-	c, err := cfg.Read(filepath.Join("testdata", "wraith.cfg"))
-	if err != nil {
-		log.Fatal(err)
-	}
-	c.Home = home
-	c.PRNG.Seed = seed
-	c.WorkingDir = cwd
-
-	err = run(c)
-	if err != nil {
-		log.Fatal(err)
-	}
-}
-
-func run(c *cfg.Config) error {
-	if c == nil {
-		return cli.Execute(c)
-	}
-
-	ctx, cancelCtx := context.WithCancel(context.Background())
-
-	s := &server{}
-	s.Addr = ":8080"
-	s.BaseContext = func(l net.Listener) context.Context {
-		ctx = context.WithValue(ctx, "addr", l.Addr().String())
-		return ctx
-	}
-	s.Handler = s.routes()
-
-	err := s.ListenAndServe()
-	if errors.Is(err, http.ErrServerClosed) {
-		err = nil
-	}
-
-	cancelCtx()
-
-	return err
-
-}
-
-type server struct {
-	http.Server
 }
 
 // shiftPath splits the given path into the first segment (head) and
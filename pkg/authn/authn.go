@@ -21,71 +21,96 @@
 package authn
 
 import (
-	"bytes"
 	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/store"
+	"golang.org/x/crypto/bcrypt"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 )
 
 // Store is our in-memory store for identification records.
+// Identities are loaded from, and saved to, the persistence layer.
 type Store struct {
+	// store persists the identities.
+	store store.IdentityStore
+
+	// auth issues the tokens for successful challenges.
+	auth *Authenticator
+
+	private struct {
+		sync.RWMutex
+	}
 	// identities stores all identification records.
 	// The map key is UserId.
 	identities map[string]*Identity
-
-	// keys stores all signing keys.
-	// The map key is SigningKey.KeyId
-	keys map[string]*SigningKey
-
-	// ttl is the maximum time-to-live for tokens.
-	// it is constrained by the expiration of the signing key.
-	ttl time.Duration
 }
 
-func New(filename string, ttl time.Duration) (*Store, error) {
-	return &Store{
+// ErrBadCredentials is returned when the user id or secret doesn't match.
+var ErrBadCredentials = errors.New("invalid user id or secret")
+
+// New returns a store holding every identity in the persistence layer.
+// Tokens for successful challenges are issued by the authenticator.
+func New(st store.IdentityStore, auth *Authenticator) (*Store, error) {
+	s := &Store{
+		store:      st,
+		auth:       auth,
 		identities: make(map[string]*Identity),
-		keys:       make(map[string]*SigningKey),
-		ttl:        ttl,
-	}, nil
+	}
+	list, err := st.ListIdentities()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range list {
+		s.identities[id.UserId] = &Identity{Id: id.Id, UserId: id.UserId, HashedSecret: id.HashedSecret}
+	}
+	return s, nil
 }
 
-func (s *Store) Authenticate(userId, userSecret string) Token {
-	if s == nil || s.identities == nil {
-		return Token{}
+// AddIdentity saves the identity to the persistence layer and adds it to the store.
+// It replaces any identity for the same user.
+func (s *Store) AddIdentity(id Identity) error {
+	if id.Id == "" || id.UserId == "" {
+		return fmt.Errorf("identity: id and user id are required")
 	}
-	id, ok := s.identities[userId]
-	if !ok {
-		return Token{}
+	if err := s.store.PutIdentity(store.Identity{Id: id.Id, UserId: id.UserId, HashedSecret: id.HashedSecret}); err != nil {
+		return err
 	}
+	s.private.Lock()
+	defer s.private.Unlock()
+	s.identities[id.UserId] = &id
+	return nil
+}
 
-	hashedSecret := []byte("do-a-bcrypt-here")
-	if !bytes.Equal(hashedSecret, id.HashedSecret) {
-		return Token{}
+// Authenticate returns a signed token if the secret matches the one
+// stored for the user. It returns ErrBadCredentials if it doesn't.
+func (s *Store) Authenticate(userId, userSecret string) (Token, error) {
+	if s == nil {
+		return Token{}, ErrBadCredentials
 	}
-
-	t := Token{
-		Id:        id.Id,
-		ExpiresAt: time.Now().Add(s.ttl).UTC(),
+	s.private.RLock()
+	id, ok := s.identities[userId]
+	s.private.RUnlock()
+	if !ok || len(id.HashedSecret) == 0 {
+		return Token{}, ErrBadCredentials
 	}
-
-	// find a valid signing key
-	for _, key := range s.keys {
-		// never use a key that will expire before the token
-		if t.ExpiresAt.Before(key.expiresAt) {
-			continue
-		}
-		// use the key to sign the token
-		if err := t.Sign(key); err != nil {
-			continue
-		}
-		// we have signed our token, so quit the loop
-		break
+	if err := bcrypt.CompareHashAndPassword(id.HashedSecret, []byte(userSecret)); err != nil {
+		return Token{}, ErrBadCredentials
 	}
+	// note: it is possible that the authenticator has no valid keys.
+	// if that happens, no token is issued.
+	return s.auth.NewToken(id.Id)
+}
 
-	// note: it is possible that the store has no valid keys.
-	// if that happens, our token will not be signed and will not be valid.
-	return t
+// HashSecret returns the hash of the secret to store in an Identity.
+func HashSecret(secret string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
 }
 
 // Identity is used to store a user's information for authentication.
@@ -97,24 +122,111 @@ type Identity struct {
 
 // Token is returned from an authentication challenge.
 type Token struct {
-	Id        string    // Identity.Id
-	ExpiresAt time.Time // moment (in UTC) the token expires
-	KeyId     string    // SigningKey.KeyId
-	Signature []byte    // generated signature
+	Id        string    `json:"id"`         // Identity.Id
+	ExpiresAt time.Time `json:"expires-at"` // moment (in UTC) the token expires
+	KeyId     string    `json:"key-id"`     // SigningKey.KeyId
+	Signature []byte    `json:"signature"`  // generated signature
 }
 
+// Sign sets the token's key id and signs the token with the key.
 func (t *Token) Sign(k *SigningKey) (err error) {
 	t.KeyId = k.keyId
-	var msg string
-	t.Signature, err = k.Sign(msg)
+	t.Signature, err = k.Sign(t.message())
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// message returns the fields of the token that are signed.
+func (t *Token) message() string {
+	return fmt.Sprintf("%s|%d|%s", t.Id, t.ExpiresAt.Unix(), t.KeyId)
+}
+
+// String returns the token encoded for a cookie or bearer token.
+func (t Token) String() string {
+	data, err := json.Marshal(t)
+	if err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseToken decodes a token returned by Token.String.
+// It does not verify the signature.
+func ParseToken(s string) (Token, error) {
+	var t Token
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Token{}, err
+	} else if err = json.Unmarshal(data, &t); err != nil {
+		return Token{}, err
+	}
+	return t, nil
+}
+
+// CookieName is the name of the cookie that holds the token for
+// browser requests.
+const CookieName = "wraith-token"
+
+// ErrNoKey is returned when there is no signing key for a new token.
+var ErrNoKey = errors.New("no valid signing key")
+
+// Authenticator issues tokens and verifies the tokens presented with requests.
+// The keys are set when the authenticator is created and never change.
 type Authenticator struct {
 	ttl time.Duration
+	// keys stores all signing keys.
+	// The map key is SigningKey.KeyId
+	keys map[string]*SigningKey
+}
+
+// NewAuthenticator returns an authenticator that issues tokens that are
+// valid for the ttl and signs them with the keys.
+func NewAuthenticator(ttl time.Duration, keys ...*SigningKey) *Authenticator {
+	a := &Authenticator{ttl: ttl, keys: make(map[string]*SigningKey)}
+	for _, k := range keys {
+		a.keys[k.keyId] = k
+	}
+	return a
+}
+
+// NewToken returns a signed token for the identity.
+// The token never outlives the key that signs it.
+func (a *Authenticator) NewToken(id string) (Token, error) {
+	t := Token{Id: id, ExpiresAt: time.Now().Add(a.ttl).UTC().Truncate(time.Second)}
+	for _, key := range a.keys {
+		// never use a key that will expire before the token
+		if key.expiresAt.Before(t.ExpiresAt) {
+			continue
+		}
+		if err := t.Sign(key); err == nil {
+			return t, nil
+		}
+	}
+	return Token{}, ErrNoKey
+}
+
+// Verify returns the authentication for the encoded token.
+// It returns an unauthenticated value if the token is malformed,
+// expired, or not signed by one of the authenticator's keys.
+func (a *Authenticator) Verify(s string) Authentication {
+	if a == nil || s == "" {
+		return Authentication{}
+	}
+	t, err := ParseToken(s)
+	if err != nil {
+		return Authentication{}
+	}
+	key, ok := a.keys[t.KeyId]
+	if !ok || key.expiresAt.Before(t.ExpiresAt) || !time.Now().Before(t.ExpiresAt) {
+		return Authentication{}
+	}
+	signature, err := key.Sign(t.message())
+	if err != nil || !hmac.Equal(signature, t.Signature) {
+		return Authentication{}
+	}
+	return Authentication{ID: t.Id, expiresAt: t.ExpiresAt, authenticated: true}
 }
 
 type Authentication struct {
@@ -135,18 +247,14 @@ func (a Authentication) IsValid() bool {
 
 // FromRequest extracts credentials from the http request.
 // It adds the result to the request's context.
+//
+// RESTish clients send the token in an "Authorization: Bearer" header.
+// Browsers send it in a cookie. If both are present, the header wins.
 func (a *Authenticator) FromRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var an Authentication
-
-		// source for authentication depends on the type of request.
-		switch r.Header.Get("Content-type") {
-		case "application/json":
-			// request is RESTish, so try to fetch a bearer token
-		case "text/html":
-			// request is plain HTML, so try to fetch a cookie
-		default:
-			// don't authenticate other content types
+		an := a.fromToken(r)
+		if !an.IsAuthenticated() {
+			an = a.fromCookie(r)
 		}
 
 		// add the authentication to the context
@@ -158,10 +266,28 @@ func (a *Authenticator) FromRequest(next http.Handler) http.Handler {
 	})
 }
 
-func fromCookie(r http.Request) Authentication {
+// FromContext returns the authentication added to the context by FromRequest.
+// It returns an unauthenticated value if there isn't one.
+func FromContext(ctx context.Context) Authentication {
+	if an, ok := ctx.Value("authn").(Authentication); ok {
+		return an
+	}
 	return Authentication{}
 }
 
-func fromToken(r http.Request) Authentication {
-	return Authentication{}
+func (a *Authenticator) fromCookie(r *http.Request) Authentication {
+	c, err := r.Cookie(CookieName)
+	if err != nil {
+		return Authentication{}
+	}
+	return a.Verify(c.Value)
+}
+
+func (a *Authenticator) fromToken(r *http.Request) Authentication {
+	const prefix = "Bearer "
+	h := r.Header.Get("Authorization")
+	if len(h) < len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return Authentication{}
+	}
+	return a.Verify(strings.TrimSpace(h[len(prefix):]))
 }
@@ -0,0 +1,161 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package authn
+
+import (
+	"context"
+	"github.com/mdhender/wraithe/pkg/store"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokens(t *testing.T) {
+	later := time.Now().Add(24 * time.Hour)
+	a := NewAuthenticator(time.Hour, NewSigningKey("k1", "secret", later))
+	other := NewAuthenticator(time.Hour, NewSigningKey("k1", "another secret", later))
+	short := NewAuthenticator(48*time.Hour, NewSigningKey("k1", "secret", later))
+
+	tok, err := a.NewToken("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = short.NewToken("u1"); err != ErrNoKey {
+		t.Errorf("token outlives key: expected ErrNoKey: got %v\n", err)
+	}
+
+	expired := tok
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Truncate(time.Second)
+	_ = expired.Sign(a.keys["k1"])
+	forged := tok
+	forged.Id = "u2"
+
+	for _, tc := range []struct {
+		id     int
+		a      *Authenticator
+		token  string
+		expect string
+	}{
+		{1, a, tok.String(), "u1"},
+		{2, other, tok.String(), ""},
+		{3, a, forged.String(), ""},
+		{4, a, expired.String(), ""},
+		{5, a, "not-a-token", ""},
+		{6, a, "", ""},
+		{7, nil, tok.String(), ""},
+	} {
+		an := tc.a.Verify(tc.token)
+		if an.ID != tc.expect || an.IsValid() != (tc.expect != "") {
+			t.Errorf("%d: verify: expected %q %v: got %q %v\n", tc.id, tc.expect, tc.expect != "", an.ID, an.IsValid())
+		}
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	a := NewAuthenticator(time.Hour, NewSigningKey("k1", "secret", time.Now().Add(24*time.Hour)))
+	tok, err := a.NewToken("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Authentication
+	h := a.FromRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+
+	for _, tc := range []struct {
+		id     int
+		header string
+		cookie string
+		expect string
+	}{
+		{1, "", "", ""},
+		{2, "Bearer " + tok.String(), "", "u1"},
+		{3, "bearer " + tok.String(), "", "u1"},
+		{4, "Basic " + tok.String(), "", ""},
+		{5, "", tok.String(), "u1"},
+		{6, "Bearer garbage", tok.String(), "u1"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		if tc.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: CookieName, Value: tc.cookie})
+		}
+		got = Authentication{}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if got.ID != tc.expect || got.IsValid() != (tc.expect != "") {
+			t.Errorf("%d: expected %q: got %q %v\n", tc.id, tc.expect, got.ID, got.IsValid())
+		}
+	}
+
+	if an := FromContext(context.Background()); an.IsAuthenticated() {
+		t.Errorf("context: expected unauthenticated: got %+v\n", an)
+	}
+}
+
+func TestStore(t *testing.T) {
+	st, err := store.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	a := NewAuthenticator(time.Hour, NewSigningKey("k1", "secret", time.Now().Add(24*time.Hour)))
+	s, err := New(st, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed, err := HashSecret("swordfish")
+	if err != nil {
+		t.Fatal(err)
+	} else if err = s.AddIdentity(Identity{Id: "u1", UserId: "frodo", HashedSecret: hashed}); err != nil {
+		t.Fatal(err)
+	}
+
+	// identities are loaded from the persistence layer
+	if s, err = New(st, a); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		id     int
+		userId string
+		secret string
+		expect string
+	}{
+		{1, "frodo", "swordfish", "u1"},
+		{2, "frodo", "Swordfish", ""},
+		{3, "u1", "swordfish", ""},
+		{4, "sam", "swordfish", ""},
+	} {
+		tok, err := s.Authenticate(tc.userId, tc.secret)
+		if tc.expect == "" {
+			if err != ErrBadCredentials {
+				t.Errorf("%d: authenticate: expected ErrBadCredentials: got %v\n", tc.id, err)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%d: authenticate: %v\n", tc.id, err)
+			continue
+		}
+		if an := a.Verify(tok.String()); an.ID != tc.expect || !an.IsValid() {
+			t.Errorf("%d: token: expected %q: got %q %v\n", tc.id, tc.expect, an.ID, an.IsValid())
+		}
+	}
+}
@@ -36,7 +36,8 @@ type SigningKey struct {
 	expiresAt time.Time
 }
 
-func newKey(id, secret string, expiresAt time.Time) *SigningKey {
+// NewSigningKey returns a key for signing tokens.
+func NewSigningKey(id, secret string, expiresAt time.Time) *SigningKey {
 	return &SigningKey{
 		keyId:     id,
 		secret:    []byte(secret),
@@ -17,27 +17,94 @@
  */
 
 // Package authz implements an authorization middleware.
-// It relies on the authn package adding an Authorization to the request context.
+// It relies on the authn package adding an Authentication to the request context.
+//
+// Users are given roles (referee, player, or observer) in a game.
+// Handlers use the middleware helpers to check those roles against the
+// game and nation named in the request's URL parameters.
 package authz
 
 import (
 	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/mdhender/wraithe/pkg/authn"
 	"net/http"
+	"sync"
 )
 
-type Authorizer struct{}
+const (
+	// GameParam is the name of the URL parameter holding the game id.
+	GameParam = "gameId"
+	// NationParam is the name of the URL parameter holding the nation.
+	NationParam = "nation"
+)
+
+// Authorizer maps users to their grants.
+type Authorizer struct {
+	private struct {
+		sync.RWMutex
+	}
+	// grants stores the grants for each user.
+	// The map key is the authenticated user's ID.
+	grants map[string][]Grant
+}
 
-type Authorization struct{}
+// New returns an Authorizer with no grants.
+func New() *Authorizer {
+	return &Authorizer{grants: make(map[string][]Grant)}
+}
+
+// Grant adds a grant for the user.
+// Duplicate grants are ignored.
+func (a *Authorizer) Grant(userId string, g Grant) {
+	a.private.Lock()
+	defer a.private.Unlock()
+	for _, grant := range a.grants[userId] {
+		if grant == g {
+			return
+		}
+	}
+	a.grants[userId] = append(a.grants[userId], g)
+}
 
+// Revoke removes all the user's grants for the game.
+func (a *Authorizer) Revoke(userId, gameId string) {
+	a.private.Lock()
+	defer a.private.Unlock()
+	var grants []Grant
+	for _, grant := range a.grants[userId] {
+		if grant.GameId != gameId {
+			grants = append(grants, grant)
+		}
+	}
+	a.grants[userId] = grants
+}
+
+// Authorization returns the authorization for the user.
+func (a *Authorizer) Authorization(userId string) Authorization {
+	az := Authorization{UserId: userId}
+	if a == nil || userId == "" {
+		return az
+	}
+	a.private.RLock()
+	defer a.private.RUnlock()
+	az.Grants = append(az.Grants, a.grants[userId]...)
+	return az
+}
+
+// FromRequest builds the authorization for the authenticated user.
+// It adds the result to the request's context.
 func (a *Authorizer) FromRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var az Authorization
 
-		// fetch the authorization from the context
-
-		// if the request is HTML (not RESTish), try to fetch a cookie
+		// fetch the authentication from the context.
+		// users that aren't authenticated get the anonymous authorization.
+		if an := authn.FromContext(r.Context()); an.IsValid() {
+			az = a.Authorization(an.ID)
+		}
 
-		// add the authentication to the context
+		// add the authorization to the context
 		ctx := context.WithValue(r.Context(), "authz", az)
 
 		// call the next handler in the chain,
@@ -45,3 +112,73 @@ func (a *Authorizer) FromRequest(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// FromContext returns the authorization added to the context by FromRequest.
+// It returns the anonymous authorization if there isn't one.
+func FromContext(ctx context.Context) Authorization {
+	if az, ok := ctx.Value("authz").(Authorization); ok {
+		return az
+	}
+	return Authorization{}
+}
+
+// RequireRole is a middleware that allows the request only if the user
+// has one of the roles in the game named by the GameParam URL parameter.
+// Referees are always allowed.
+func RequireRole(roles ...Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			az, gameId := FromContext(r.Context()), chi.URLParam(r, GameParam)
+			if !az.IsAuthenticated() {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			allowed := az.IsReferee(gameId)
+			for _, role := range roles {
+				allowed = allowed || az.HasRole(gameId, role)
+			}
+			if !allowed {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireNation is a middleware that allows the request only if the user
+// is the player for the nation named by the NationParam URL parameter
+// in the game named by the GameParam URL parameter.
+// Referees are always allowed.
+func RequireNation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		az, gameId, nation := FromContext(r.Context()), chi.URLParam(r, GameParam), chi.URLParam(r, NationParam)
+		if !az.IsAuthenticated() {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		} else if !(az.IsReferee(gameId) || az.IsPlayer(gameId, nation)) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Require is a middleware that allows the request only if the policy
+// allows the user to take the action in the game and nation named by
+// the URL parameters.
+func Require(action Action) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			az := FromContext(r.Context())
+			if !az.IsAuthenticated() {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			} else if !az.Can(chi.URLParam(r, GameParam), action, chi.URLParam(r, NationParam)) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package authz
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCan(t *testing.T) {
+	referee := Authorization{UserId: "ref", Grants: []Grant{{GameId: "g1", Role: Referee}}}
+	player := Authorization{UserId: "p1", Grants: []Grant{{GameId: "g1", Role: Player, Nation: "n1"}}}
+	observer := Authorization{UserId: "o1", Grants: []Grant{{GameId: "g1", Role: Observer}}}
+	anonymous := Authorization{}
+
+	for _, tc := range []struct {
+		id     int
+		az     Authorization
+		game   string
+		action Action
+		nation string
+		expect bool
+	}{
+		{1, referee, "g1", AdminGame, "", true},
+		{2, referee, "g1", SubmitOrders, "n2", true},
+		{3, referee, "g2", ReadGame, "", false},
+		{4, player, "g1", ReadCluster, "", true},
+		{5, player, "g1", SubmitOrders, "n1", true},
+		{6, player, "g1", SubmitOrders, "n2", false},
+		{7, player, "g1", ReadReports, "n2", false},
+		{8, player, "g1", AdminGame, "", false},
+		{9, player, "g2", ReadGame, "", false},
+		{10, observer, "g1", ReadGame, "", true},
+		{11, observer, "g1", ReadReports, "n1", false},
+		{12, anonymous, "g1", ReadGame, "", false},
+	} {
+		if got := tc.az.Can(tc.game, tc.action, tc.nation); got != tc.expect {
+			t.Errorf("%d: expected %v: got %v\n", tc.id, tc.expect, got)
+		}
+	}
+}
+
+func TestRequireNation(t *testing.T) {
+	r := chi.NewRouter()
+	r.With(RequireNation).Get("/games/{gameId}/nations/{nation}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	player := Authorization{UserId: "p1", Grants: []Grant{{GameId: "g1", Role: Player, Nation: "n1"}}}
+	for _, tc := range []struct {
+		id     int
+		az     Authorization
+		path   string
+		expect int
+	}{
+		{1, player, "/games/g1/nations/n1", http.StatusOK},
+		{2, player, "/games/g1/nations/n2", http.StatusForbidden},
+		{3, Authorization{}, "/games/g1/nations/n1", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), "authz", tc.az))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.expect {
+			t.Errorf("%d: expected %d: got %d\n", tc.id, tc.expect, w.Code)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package authz
+
+// Role is the part a user plays in a single game.
+type Role string
+
+const (
+	// Referee is the game master. The referee may do anything in the game.
+	Referee Role = "referee"
+	// Player controls a single nation in the game.
+	Player Role = "player"
+	// Observer may watch the game but not take part in it.
+	Observer Role = "observer"
+)
+
+// Action is something a user wants to do in a game.
+type Action string
+
+const (
+	ReadGame     Action = "read-game"     // read the game metadata
+	ReadCluster  Action = "read-cluster"  // read the cluster map
+	ReadReports  Action = "read-reports"  // read a nation's turn reports
+	ReadOrders   Action = "read-orders"   // read a nation's submitted orders
+	SubmitOrders Action = "submit-orders" // submit orders for a nation
+	AdminGame    Action = "admin-game"    // run turns, change settings, etc.
+)
+
+// Grant gives a user a role in a game.
+// Nation is required for players and ignored for everyone else.
+type Grant struct {
+	GameId string `json:"game-id"`
+	Role   Role   `json:"role"`
+	Nation string `json:"nation,omitempty"`
+}
+
+// Authorization is the set of grants for an authenticated user.
+// The zero value is an anonymous user with no grants.
+type Authorization struct {
+	UserId string
+	Grants []Grant
+}
+
+// IsAuthenticated returns true if the authorization belongs to a user.
+func (az Authorization) IsAuthenticated() bool {
+	return az.UserId != ""
+}
+
+// HasRole returns true if the user has the role in the game.
+func (az Authorization) HasRole(gameId string, role Role) bool {
+	for _, g := range az.Grants {
+		if g.GameId == gameId && g.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReferee returns true if the user is a referee for the game.
+func (az Authorization) IsReferee(gameId string) bool {
+	return az.HasRole(gameId, Referee)
+}
+
+// IsPlayer returns true if the user controls the nation in the game.
+func (az Authorization) IsPlayer(gameId, nation string) bool {
+	for _, g := range az.Grants {
+		if g.GameId == gameId && g.Role == Player && g.Nation == nation {
+			return true
+		}
+	}
+	return false
+}
+
+// Nations returns the nations the user controls in the game.
+func (az Authorization) Nations(gameId string) (nations []string) {
+	for _, g := range az.Grants {
+		if g.GameId == gameId && g.Role == Player {
+			nations = append(nations, g.Nation)
+		}
+	}
+	return nations
+}
+
+// Can implements the policy check.
+// Referees may do anything in their game.
+// Players may read the game and cluster, and may read reports and orders
+// and submit orders only for their own nation.
+// Observers may read the game and cluster.
+// Nobody may do anything in a game they have no role in.
+func (az Authorization) Can(gameId string, action Action, nation string) bool {
+	if !az.IsAuthenticated() {
+		return false
+	} else if az.IsReferee(gameId) {
+		return true
+	}
+	switch action {
+	case ReadGame, ReadCluster:
+		return az.HasRole(gameId, Player) || az.HasRole(gameId, Observer)
+	case ReadReports, ReadOrders, SubmitOrders:
+		return nation != "" && az.IsPlayer(gameId, nation)
+	}
+	return false
+}
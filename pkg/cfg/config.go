@@ -17,16 +17,71 @@
  */
 
 // Package cfg implements a store for configuration data.
+//
+// Configuration is loaded in layers.
+// Each layer overrides the values set by the layers before it:
+//  1. the defaults from Default
+//  2. a JSON configuration file (see Load)
+//  3. WRAITH_* environment variables (see LoadEnv)
+//  4. command line flags (applied by the cli package)
 package cfg
 
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
 // Config is the configuration data.
 // All fields are public.
 type Config struct {
-	Meta       Meta   `json:"meta"`
-	Home       string `json:"home"`
-	WorkingDir string `json:"working-dir"`
-	Server     Server `json:"server"`
-	PRNG       PRNG   `json:"prng"`
+	Meta         Meta      `json:"meta"`
+	Home         string    `json:"home"`
+	WorkingDir   string    `json:"working-dir"`
+	GamesDir     string    `json:"games-dir,omitempty"`
+	TemplatesDir string    `json:"templates-dir,omitempty"`
+	Server       Server    `json:"server"`
+	Store        Store     `json:"store"`
+	Email        Email     `json:"email"`
+	Scheduler    Scheduler `json:"scheduler"`
+	PRNG         PRNG      `json:"prng"`
+}
+
+// Store is the configuration for the persistence layer.
+type Store struct {
+	// Driver is either "file" or "sqlite".
+	Driver string `json:"driver,omitempty"`
+	// Path is the root directory for the file driver
+	// or the database file for the sqlite driver.
+	// If empty, the file driver uses the games directory.
+	Path string `json:"path,omitempty"`
+}
+
+// Email is the configuration for the play-by-email gateway.
+type Email struct {
+	// From is the address that reports and replies are sent from.
+	From string `json:"from,omitempty"`
+	// Outbox is the directory that outgoing messages are written to
+	// when no SMTP server is configured.
+	Outbox string `json:"outbox,omitempty"`
+	SMTP   SMTP   `json:"smtp"`
+}
+
+// SMTP is the configuration for sending email.
+// If Host is empty, messages are written to the outbox instead.
+type SMTP struct {
+	Host     string `json:"host,omitempty"`
+	Port     string `json:"port,omitempty"`
+	Username string `json:"username,omitempty"`
+	// Password should be set from the environment, not the configuration file.
+	Password string `json:"-"`
+}
+
+// Scheduler is the configuration for the turn scheduler.
+type Scheduler struct {
+	// Interval is how often the scheduler checks for turns that are due.
+	Interval Duration `json:"interval,omitempty"`
 }
 
 // Meta is meta-data about the configuration file.
@@ -42,9 +97,129 @@ type PRNG struct {
 // Server is the configuration for the wraith server.
 type Server struct {
 	Http Http `json:"http"`
+	Auth Auth `json:"auth"`
+}
+
+// Auth is the configuration for authenticating requests.
+type Auth struct {
+	// TokenTTL is how long the tokens issued to users are valid.
+	TokenTTL Duration `json:"token-ttl,omitempty"`
+	// SigningKey is the secret used to sign tokens. It should be set
+	// from the environment, not the configuration file. If it is empty,
+	// the server makes a new key every time it starts, and tokens don't
+	// survive a restart.
+	SigningKey string `json:"-"`
 }
 
 // Http is the configuration for the http server.
 type Http struct {
+	Host string `json:"host,omitempty"`
 	Port string `json:"port,omitempty"`
+	// ReadTimeout is the maximum time to read an entire request.
+	ReadTimeout Duration `json:"read-timeout,omitempty"`
+	// WriteTimeout is the maximum time to write a response.
+	WriteTimeout Duration `json:"write-timeout,omitempty"`
+	// IdleTimeout is the maximum time to wait for the next request on a keep-alive connection.
+	IdleTimeout Duration `json:"idle-timeout,omitempty"`
+	// ShutdownTimeout is the maximum time to wait for in-flight requests when shutting down.
+	ShutdownTimeout Duration `json:"shutdown-timeout,omitempty"`
+}
+
+// Duration is a time.Duration that is stored as a string like "1m30s".
+type Duration struct {
+	time.Duration
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(data []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	d.Duration, err = time.ParseDuration(s)
+	return err
+}
+
+// Default returns the default configuration.
+func Default() *Config {
+	return &Config{
+		GamesDir:     "games",
+		TemplatesDir: "templates",
+		Server: Server{
+			Http: Http{
+				Port:            "8080",
+				ReadTimeout:     Duration{5 * time.Second},
+				WriteTimeout:    Duration{10 * time.Second},
+				IdleTimeout:     Duration{2 * time.Minute},
+				ShutdownTimeout: Duration{30 * time.Second},
+			},
+			Auth: Auth{
+				TokenTTL: Duration{24 * time.Hour},
+			},
+		},
+		Store: Store{
+			Driver: "file",
+		},
+		Email: Email{
+			From:   "wraith@localhost",
+			Outbox: "outbox",
+			SMTP: SMTP{
+				Port: "25",
+			},
+		},
+		Scheduler: Scheduler{
+			Interval: Duration{time.Minute},
+		},
+	}
+}
+
+// Validate returns an error if the configuration is not usable.
+func (c *Config) Validate() error {
+	if c.GamesDir == "" {
+		return fmt.Errorf("games-dir: must not be empty")
+	} else if c.TemplatesDir == "" {
+		return fmt.Errorf("templates-dir: must not be empty")
+	}
+	if port, err := strconv.Atoi(c.Server.Http.Port); err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("server.http.port: %q: must be a number between 1 and 65535", c.Server.Http.Port)
+	}
+	for _, timeout := range []struct {
+		name  string
+		value Duration
+	}{
+		{"read-timeout", c.Server.Http.ReadTimeout},
+		{"write-timeout", c.Server.Http.WriteTimeout},
+		{"idle-timeout", c.Server.Http.IdleTimeout},
+		{"shutdown-timeout", c.Server.Http.ShutdownTimeout},
+	} {
+		if timeout.value.Duration <= 0 {
+			return fmt.Errorf("server.http.%s: %v: must be positive", timeout.name, timeout.value)
+		}
+	}
+	if c.Server.Auth.TokenTTL.Duration <= 0 {
+		return fmt.Errorf("server.auth.token-ttl: %v: must be positive", c.Server.Auth.TokenTTL)
+	}
+	switch c.Store.Driver {
+	case "file":
+	case "sqlite":
+		if c.Store.Path == "" {
+			return fmt.Errorf("store.path: must not be empty for the sqlite driver")
+		}
+	default:
+		return fmt.Errorf("store.driver: %q: must be file or sqlite", c.Store.Driver)
+	}
+	if c.Email.From == "" {
+		return fmt.Errorf("email.from: must not be empty")
+	} else if c.Email.SMTP.Host == "" && c.Email.Outbox == "" {
+		return fmt.Errorf("email: either outbox or smtp.host must be set")
+	}
+	if c.Scheduler.Interval.Duration <= 0 {
+		return fmt.Errorf("scheduler.interval: %v: must be positive", c.Scheduler.Interval)
+	}
+	return nil
 }
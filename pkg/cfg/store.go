@@ -20,27 +20,107 @@ package cfg
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 )
 
 // Read loads configuration data from a JSON file.
 func Read(filename string) (*Config, error) {
+	var c Config
+	if err := c.Load(filename); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Load merges configuration data from a JSON file.
+// Values in the file replace the current values.
+// Values not in the file are left unchanged.
+func (c *Config) Load(filename string) error {
 	filename = filepath.Clean(filename)
 
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	var c Config
-	err = json.Unmarshal(data, &c)
+	err = json.Unmarshal(data, c)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("%s: %w", filename, err)
 	}
 	c.Meta.FileName = filename
 
-	return &c, nil
+	return nil
+}
+
+// LoadEnv merges configuration data from WRAITH_* environment variables.
+// The lookup function is normally os.LookupEnv.
+// Variables that aren't set are ignored.
+func (c *Config) LoadEnv(lookup func(key string) (string, bool)) error {
+	if val, ok := lookup("WRAITH_GAMES_DIR"); ok {
+		c.GamesDir = val
+	}
+	if val, ok := lookup("WRAITH_TEMPLATES_DIR"); ok {
+		c.TemplatesDir = val
+	}
+	if val, ok := lookup("WRAITH_HTTP_HOST"); ok {
+		c.Server.Http.Host = val
+	}
+	if val, ok := lookup("WRAITH_HTTP_PORT"); ok {
+		c.Server.Http.Port = val
+	}
+	if val, ok := lookup("WRAITH_STORE_DRIVER"); ok {
+		c.Store.Driver = val
+	}
+	if val, ok := lookup("WRAITH_STORE_PATH"); ok {
+		c.Store.Path = val
+	}
+	for _, str := range []struct {
+		key   string
+		value *string
+	}{
+		{"WRAITH_EMAIL_FROM", &c.Email.From},
+		{"WRAITH_EMAIL_OUTBOX", &c.Email.Outbox},
+		{"WRAITH_SMTP_HOST", &c.Email.SMTP.Host},
+		{"WRAITH_SMTP_PORT", &c.Email.SMTP.Port},
+		{"WRAITH_SMTP_USERNAME", &c.Email.SMTP.Username},
+		{"WRAITH_SMTP_PASSWORD", &c.Email.SMTP.Password},
+		{"WRAITH_SIGNING_KEY", &c.Server.Auth.SigningKey},
+	} {
+		if val, ok := lookup(str.key); ok {
+			*str.value = val
+		}
+	}
+	for _, timeout := range []struct {
+		key   string
+		value *Duration
+	}{
+		{"WRAITH_HTTP_READ_TIMEOUT", &c.Server.Http.ReadTimeout},
+		{"WRAITH_HTTP_WRITE_TIMEOUT", &c.Server.Http.WriteTimeout},
+		{"WRAITH_HTTP_IDLE_TIMEOUT", &c.Server.Http.IdleTimeout},
+		{"WRAITH_HTTP_SHUTDOWN_TIMEOUT", &c.Server.Http.ShutdownTimeout},
+		{"WRAITH_SCHEDULER_INTERVAL", &c.Scheduler.Interval},
+		{"WRAITH_TOKEN_TTL", &c.Server.Auth.TokenTTL},
+	} {
+		if val, ok := lookup(timeout.key); ok {
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return fmt.Errorf("%s: %w", timeout.key, err)
+			}
+			timeout.value.Duration = d
+		}
+	}
+	if val, ok := lookup("WRAITH_SEED"); ok {
+		seed, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return fmt.Errorf("WRAITH_SEED: %w", err)
+		}
+		c.PRNG.Seed = seed
+	}
+	return nil
 }
 
 // Write saves configuration data to a JSON file.
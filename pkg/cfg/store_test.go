@@ -0,0 +1,64 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cfg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLayers(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "wraith.cfg")
+	if err := os.WriteFile(filename, []byte(`{"games-dir": "from-file", "server": {"http": {"host": "localhost"}}}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"WRAITH_GAMES_DIR": "from-env", "WRAITH_SEED": "42"}
+	lookup := func(key string) (string, bool) {
+		val, ok := env[key]
+		return val, ok
+	}
+
+	c := Default()
+	if err := c.Load(filename); err != nil {
+		t.Fatalf("load: %v\n", err)
+	}
+	if c.GamesDir != "from-file" || c.Server.Http.Host != "localhost" || c.Server.Http.Port != "8080" {
+		t.Errorf("file: expected from-file, localhost, 8080: got %q %q %q\n", c.GamesDir, c.Server.Http.Host, c.Server.Http.Port)
+	}
+	if err := c.LoadEnv(lookup); err != nil {
+		t.Fatalf("env: %v\n", err)
+	}
+	if c.GamesDir != "from-env" || c.PRNG.Seed != 42 || c.Server.Http.Host != "localhost" {
+		t.Errorf("env: expected from-env, 42, localhost: got %q %d %q\n", c.GamesDir, c.PRNG.Seed, c.Server.Http.Host)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("validate: %v\n", err)
+	}
+
+	env["WRAITH_SEED"] = "forty-two"
+	if err := c.LoadEnv(lookup); err == nil {
+		t.Errorf("env: expected error for invalid seed: got nil\n")
+	}
+
+	c.Server.Http.Port = "0"
+	if err := c.Validate(); err == nil {
+		t.Errorf("validate: expected error for port 0: got nil\n")
+	}
+}
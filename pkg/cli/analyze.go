@@ -0,0 +1,120 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/wraith"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+var analyzeArgs struct {
+	clusters  int
+	seed      int64
+	systems   int
+	minStars  int
+	radius    float64
+	format    string
+	output    string
+	generator string
+	starTypes string
+	starSizes string
+	stars     wraith.StarDistribution
+	anomalies string
+	weights   map[string]int // parsed anomalies
+}
+
+// analyzeCmd reports the distributions of a batch of generated clusters.
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "analyze generated clusters",
+	Long: `Generate a batch of clusters and report the distributions of
+systems per ring, stars per system, nearest-neighbor distances, and
+habitable planets by distance from the home system, stars by
+spectral type, and anomalies in systems without stars.
+
+Cluster i is generated with seed+i, so a batch can be reproduced.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if analyzeArgs.clusters < 1 {
+			return fmt.Errorf("clusters must be at least 1")
+		} else if analyzeArgs.format != "text" && analyzeArgs.format != "csv" {
+			return fmt.Errorf("format must be text or csv")
+		}
+		var err error
+		if analyzeArgs.stars, err = starDistribution(analyzeArgs.starTypes, analyzeArgs.starSizes); err != nil {
+			return err
+		} else if analyzeArgs.weights, err = anomalyWeights(analyzeArgs.anomalies); err != nil {
+			return err
+		}
+		p := generatorParams(analyzeArgs.systems, analyzeArgs.minStars, analyzeArgs.radius, 0)
+		p.Anomalies = analyzeArgs.weights
+		return p.Validate()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		seed := analyzeArgs.seed
+		if seed == 0 {
+			seed = config.PRNG.Seed
+		}
+
+		a := wraith.NewAnalysis()
+		for i := 0; i < analyzeArgs.clusters; i++ {
+			p := generatorParams(analyzeArgs.systems, analyzeArgs.minStars, analyzeArgs.radius, seed+int64(i))
+			p.Stars, p.Anomalies = analyzeArgs.stars, analyzeArgs.weights
+			c, err := wraith.Generate(analyzeArgs.generator, p)
+			if err != nil {
+				return err
+			}
+			a.Add(c)
+		}
+		log.Printf("[analyze] generated %d clusters from seed %d\n", a.Clusters(), seed)
+
+		var w io.Writer = cmd.OutOrStdout()
+		if analyzeArgs.output != "" {
+			fd, err := os.Create(resolvePath(analyzeArgs.output))
+			if err != nil {
+				return err
+			}
+			defer fd.Close()
+			w = fd
+		}
+		if analyzeArgs.format == "csv" {
+			return a.WriteCSV(w)
+		}
+		return a.WriteText(w)
+	},
+}
+
+func init() {
+	cmdCLI.AddCommand(analyzeCmd)
+	analyzeCmd.Flags().IntVar(&analyzeArgs.clusters, "clusters", 10, "number of clusters to generate")
+	analyzeCmd.Flags().Int64Var(&analyzeArgs.seed, "seed", 0, "seed for the first cluster (zero uses the configured seed)")
+	analyzeCmd.Flags().IntVar(&analyzeArgs.systems, "systems", 512, "number of systems in each cluster")
+	analyzeCmd.Flags().IntVar(&analyzeArgs.minStars, "min-stars", 128, "minimum number of stars in each cluster")
+	analyzeCmd.Flags().Float64Var(&analyzeArgs.radius, "radius", 15.0, "radius of each cluster in light years")
+	analyzeCmd.Flags().StringVar(&analyzeArgs.format, "format", "text", "output format (text or csv)")
+	analyzeCmd.Flags().StringVar(&analyzeArgs.generator, "generator", "sphere", "cluster generator ("+strings.Join(wraith.Generators(), ", ")+")")
+	analyzeCmd.Flags().StringVar(&analyzeArgs.starTypes, "star-types", "", "weights for spectral types (see create)")
+	analyzeCmd.Flags().StringVar(&analyzeArgs.starSizes, "star-sizes", "", "weights for size classes (see create)")
+	analyzeCmd.Flags().StringVar(&analyzeArgs.anomalies, "anomalies", "", "weights for anomalies (see create)")
+	analyzeCmd.Flags().StringVar(&analyzeArgs.output, "output", "", "file to write the report to (defaults to stdout)")
+}
@@ -20,20 +20,32 @@
 package cli
 
 import (
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	html "github.com/mdhender/wraithe/handlers/html"
-	rest "github.com/mdhender/wraithe/handlers/rest"
 	"github.com/mdhender/wraithe/pkg/cedar"
 	"github.com/mdhender/wraithe/pkg/cfg"
+	"github.com/mdhender/wraithe/pkg/games"
+	"github.com/mdhender/wraithe/pkg/store"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"log"
 	"math/rand"
-	"net/http"
 	"os"
+	"path/filepath"
 )
 
+var globalArgs struct {
+	configFile   string
+	gamesDir     string
+	templatesDir string
+	httpHost     string
+	httpPort     string
+	storeDriver  string
+	storePath    string
+}
+
+// config is the configuration shared by all the commands.
+// It is set by Execute and updated by the root command's pre-run.
+var config *cfg.Config
+
 // cmdCLI represents the base command when called without any subcommands
 var cmdCLI = &cobra.Command{
 	Use:   "wraith",
@@ -56,40 +68,104 @@ This application provides an API to the game engine.`,
 		}
 		log.Printf("%-30s == %q\n", "home", home)
 
-		// seed the default PRNG source.
-		seed, err := cedar.Seed()
-		if err != nil {
+		// layer the configuration: file, then environment, then flags.
+		configFile := globalArgs.configFile
+		if configFile == "" {
+			configFile = os.Getenv("WRAITH_CONFIG")
+		}
+		if configFile != "" {
+			if !filepath.IsAbs(configFile) {
+				configFile = filepath.Join(cwd, configFile)
+			}
+			if err := config.Load(configFile); err != nil {
+				return err
+			}
+			log.Printf("%-30s == %q\n", "config", config.Meta.FileName)
+		}
+		if err := config.LoadEnv(os.LookupEnv); err != nil {
 			return err
 		}
-		log.Printf("%-30s == %d\n", "seed", seed)
-		rand.Seed(seed)
-
-		return nil
-	},
-	Run: func(cmd *cobra.Command, args []string) {
-		r := chi.NewRouter()
+		flags := cmd.Flags()
+		if flags.Changed("games-dir") {
+			config.GamesDir = globalArgs.gamesDir
+		}
+		if flags.Changed("templates-dir") {
+			config.TemplatesDir = globalArgs.templatesDir
+		}
+		if flags.Changed("http-host") {
+			config.Server.Http.Host = globalArgs.httpHost
+		}
+		if flags.Changed("http-port") {
+			config.Server.Http.Port = globalArgs.httpPort
+		}
+		if flags.Changed("store-driver") {
+			config.Store.Driver = globalArgs.storeDriver
+		}
+		if flags.Changed("store-path") {
+			config.Store.Path = globalArgs.storePath
+		}
 
-		r.Use(middleware.RequestID)
-		r.Use(middleware.Logger)
-		r.Use(middleware.Recoverer)
-		r.Use(middleware.URLFormat)
+		// the working and home directories always come from the environment
+		config.WorkingDir, config.Home = cwd, home
 
-		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-			_, _ = w.Write([]byte("This is my index!"))
-		})
-		r.Route("/api", func(r chi.Router) {
-			r.Mount("/", rest.Routes())
-		})
-		r.Route("/ui", func(r chi.Router) {
-			r.Mount("/", html.Routes())
-		})
+		// seed the default PRNG source.
+		if config.PRNG.Seed == 0 {
+			if config.PRNG.Seed, err = cedar.Seed(); err != nil {
+				return err
+			}
+		}
+		log.Printf("%-30s == %d\n", "seed", config.PRNG.Seed)
+		rand.Seed(config.PRNG.Seed)
 
-		_ = http.ListenAndServe(":8080", r)
+		return config.Validate()
 	},
 }
 
+func init() {
+	defaults := cfg.Default()
+	cmdCLI.PersistentFlags().StringVar(&globalArgs.configFile, "config", "", "configuration file (overrides WRAITH_CONFIG)")
+	cmdCLI.PersistentFlags().StringVar(&globalArgs.gamesDir, "games-dir", defaults.GamesDir, "directory containing the games")
+	cmdCLI.PersistentFlags().StringVar(&globalArgs.templatesDir, "templates-dir", defaults.TemplatesDir, "directory containing the templates")
+	cmdCLI.PersistentFlags().StringVar(&globalArgs.httpHost, "http-host", defaults.Server.Http.Host, "host for the http server to listen on")
+	cmdCLI.PersistentFlags().StringVar(&globalArgs.httpPort, "http-port", defaults.Server.Http.Port, "port for the http server to listen on")
+	cmdCLI.PersistentFlags().StringVar(&globalArgs.storeDriver, "store-driver", defaults.Store.Driver, "storage driver (file or sqlite)")
+	cmdCLI.PersistentFlags().StringVar(&globalArgs.storePath, "store-path", defaults.Store.Path, "storage directory or database file (defaults to the games directory)")
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the root Command.
 func Execute(c *cfg.Config) error {
+	if c == nil {
+		c = cfg.Default()
+	}
+	config = c
 	return cmdCLI.Execute()
 }
+
+// resolvePath returns the path relative to the working directory.
+// Absolute paths are returned unchanged.
+func resolvePath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(config.WorkingDir, path)
+}
+
+// openRegistry opens the configured store and loads the games from it.
+// The caller must close the store when done.
+func openRegistry() (*games.Registry, error) {
+	path := config.Store.Path
+	if path == "" && config.Store.Driver == "file" {
+		path = config.GamesDir
+	}
+	st, err := store.Open(config.Store.Driver, resolvePath(path))
+	if err != nil {
+		return nil, err
+	}
+	reg, err := games.Open(st)
+	if err != nil {
+		_ = st.Close()
+		return nil, err
+	}
+	return reg, nil
+}
@@ -0,0 +1,53 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+// configCmd groups the commands for working with the configuration.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "manage configuration",
+	Long:  `Commands for working with the configuration.`,
+}
+
+// configShowCmd prints the configuration after all the layers are merged.
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "show the configuration",
+	Long: `Show the configuration after merging the defaults, the configuration
+file, the WRAITH_* environment variables, and the command line flags.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := json.MarshalIndent(config, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		return err
+	},
+}
+
+func init() {
+	cmdCLI.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+}
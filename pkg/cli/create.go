@@ -19,15 +19,34 @@
 package cli
 
 import (
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/games"
 	"github.com/mdhender/wraithe/pkg/wraith"
 	"github.com/spf13/cobra"
 	"html/template"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 var createArgs struct {
-	minStars int
+	game      string
+	name      string
+	outputDir string
+	systems   int
+	minStars  int
+	radius    float64
+	seed      int64
+	nations   []string
+	generator string
+	mapFile   string
+	starTypes string
+	starSizes string
+	stars     wraith.StarDistribution
+	anomalies string
+	weights   map[string]int // parsed anomalies
+	lanes     float64
 }
 
 // createCmd implements the commands needed to create a new game.
@@ -36,23 +55,142 @@ var createCmd = &cobra.Command{
 	Short:   "create a new game",
 	Long:    `Create a new game.`,
 	Version: "0.0.1",
-	Run: func(cmd *cobra.Command, args []string) {
-		//wraith.F(createArgs.minStars)
-		c := wraith.G(512, 128, 15.0)
-		b, err := c.ToHTML("D:\\wraithe\\templates", "cluster.gohtml", template.FuncMap{})
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if createArgs.mapFile != "" && !cmd.Flags().Changed("generator") {
+			createArgs.generator = "file"
+		}
+		if createArgs.generator == "file" && createArgs.mapFile == "" {
+			return fmt.Errorf("the file generator needs a --map")
+		} else if createArgs.generator != "file" && createArgs.mapFile != "" {
+			return fmt.Errorf("--map needs the file generator, not %q", createArgs.generator)
+		}
+		var err error
+		if createArgs.stars, err = starDistribution(createArgs.starTypes, createArgs.starSizes); err != nil {
+			return err
+		} else if createArgs.weights, err = anomalyWeights(createArgs.anomalies); err != nil {
+			return err
+		}
+		p := generatorParams(createArgs.systems, createArgs.minStars, createArgs.radius, 0)
+		p.Stars, p.Anomalies, p.Lanes = createArgs.stars, createArgs.weights, createArgs.lanes
+		if len(createArgs.nations) != 0 {
+			p.Homes = len(createArgs.nations)
+		}
+		return p.Validate()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := openRegistry()
+		if err != nil {
+			return err
+		}
+		defer reg.Store().Close()
+
+		// the seed is saved with the game so that the cluster can be re-created.
+		seed := createArgs.seed
+		if seed == 0 {
+			seed = config.PRNG.Seed
+		}
+		g := &games.Game{Id: createArgs.game, Name: createArgs.name, Seed: seed, Generator: createArgs.generator}
+		if g.Name == "" {
+			g.Name = g.Id
+		}
+
+		// generate the cluster before creating the game so that a bad map
+		// or a crowded cluster doesn't leave a game without one behind.
+		p := generatorParams(createArgs.systems, createArgs.minStars, createArgs.radius, g.Seed)
+		p.File = resolvePath(createArgs.mapFile)
+		p.Stars, p.Anomalies, p.Lanes = createArgs.stars, createArgs.weights, createArgs.lanes
+		if len(createArgs.nations) != 0 {
+			p.Homes = len(createArgs.nations)
+		}
+		c, err := wraith.Generate(g.Generator, p)
+		if err != nil {
+			return err
+		}
+		s, err := wraith.NewState(c, createArgs.nations...)
 		if err != nil {
-			log.Fatalf("%+v\n", err)
+			return err
 		}
-		fname := "D:\\wraithe\\testdata\\gencluster.html"
-		err = os.WriteFile(fname, b, 0666)
+
+		if err = reg.Create(g); err != nil {
+			return err
+		} else if err = g.SaveCluster(c); err != nil {
+			return fmt.Errorf("game %q: %w", g.Id, err)
+		} else if err = g.SaveState(s); err != nil {
+			return fmt.Errorf("game %q: %w", g.Id, err)
+		}
+		log.Printf("[create] created game %q with %d nations\n", g.Id, len(createArgs.nations))
+
+		outputDir := resolvePath(createArgs.outputDir)
+		if outputDir == "" {
+			outputDir = resolvePath(filepath.Join(config.GamesDir, g.Id))
+		}
+		if err = os.MkdirAll(outputDir, 0777); err != nil {
+			return err
+		}
+		b, err := c.ToHTML(resolvePath(config.TemplatesDir), "cluster.gohtml", template.FuncMap{})
 		if err != nil {
-			log.Fatalf("%+v\n", err)
+			return err
+		}
+		fname := filepath.Join(outputDir, "cluster.html")
+		if err = os.WriteFile(fname, b, 0666); err != nil {
+			return err
 		}
 		log.Printf("[create] created %q\n", fname)
+		return nil
 	},
 }
 
 func init() {
 	cmdCLI.AddCommand(createCmd)
-	createCmd.Flags().IntVar(&createArgs.minStars, "min-stars", 125, "minimum number of stars in the cluster")
+	createCmd.Flags().StringVar(&createArgs.game, "game", "", "id of the new game")
+	_ = createCmd.MarkFlagRequired("game")
+	createCmd.Flags().StringVar(&createArgs.name, "name", "", "name of the new game (defaults to the id)")
+	createCmd.Flags().StringVar(&createArgs.outputDir, "output-dir", "", "directory for generated files (defaults to the game's directory under games-dir)")
+	createCmd.Flags().IntVar(&createArgs.systems, "systems", 512, "number of systems in the cluster")
+	createCmd.Flags().IntVar(&createArgs.minStars, "min-stars", 128, "minimum number of stars in the cluster")
+	createCmd.Flags().Float64Var(&createArgs.radius, "radius", 15.0, "radius of the cluster in light years")
+	createCmd.Flags().StringSliceVar(&createArgs.nations, "nations", nil, "ids of the nations in the game")
+	createCmd.Flags().Int64Var(&createArgs.seed, "seed", 0, "seed for the cluster generator (zero uses the configured seed)")
+	createCmd.Flags().StringVar(&createArgs.generator, "generator", "sphere", "cluster generator ("+strings.Join(wraith.Generators(), ", ")+")")
+	createCmd.Flags().StringVar(&createArgs.mapFile, "map", "", "map file (.csv or .json) to import; implies --generator file")
+	createCmd.Flags().Float64Var(&createArgs.lanes, "lanes", 0, "longest jump lane in light years (zero lets ships fly anywhere)")
+	createCmd.Flags().StringVar(&createArgs.starTypes, "star-types", "", "weights for spectral types, like O=1,B=3,A=6,F=14,G=25,K=26,M=25")
+	createCmd.Flags().StringVar(&createArgs.starSizes, "star-sizes", "", "weights for size classes, like dwarf=6,main-sequence=80,subgiant=8,giant=5,supergiant=1")
+	createCmd.Flags().StringVar(&createArgs.anomalies, "anomalies", "", "weights for anomalies in systems without stars, like dead=40,nebula=25,dust=15,rogue=20")
+}
+
+// generatorParams returns the parameters for the cluster generators.
+func generatorParams(systems, minStars int, radius float64, seed int64) wraith.Params {
+	p := wraith.DefaultParams()
+	p.Seed, p.Systems, p.MinStars, p.Radius = seed, systems, minStars, radius
+	return p
+}
+
+// starDistribution returns the distribution of stars from the weights
+// given on the command line. Empty weights use the defaults.
+func starDistribution(types, sizes string) (d wraith.StarDistribution, err error) {
+	if types != "" {
+		if d.Types, err = wraith.ParseWeights(types); err != nil {
+			return d, fmt.Errorf("star-types: %w", err)
+		}
+	}
+	if sizes != "" {
+		if d.Sizes, err = wraith.ParseWeights(sizes); err != nil {
+			return d, fmt.Errorf("star-sizes: %w", err)
+		}
+	}
+	return d, d.Validate()
+}
+
+// anomalyWeights returns the weights for anomalies given on the command line.
+// Empty weights use the defaults.
+func anomalyWeights(s string) (map[string]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	weights, err := wraith.ParseWeights(s)
+	if err != nil {
+		return nil, fmt.Errorf("anomalies: %w", err)
+	}
+	return weights, nil
 }
@@ -0,0 +1,67 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"github.com/mdhender/wraithe/pkg/cfg"
+	"path/filepath"
+	"testing"
+)
+
+// TestCreate checks that a game is only created when its cluster is.
+func TestCreate(t *testing.T) {
+	config = cfg.Default()
+	config.WorkingDir, config.GamesDir = "../..", t.TempDir()
+	createArgs.systems, createArgs.minStars, createArgs.radius, createArgs.seed = 32, 8, 10, 9
+	createArgs.nations = []string{"n1", "n2"}
+
+	// a missing map must not leave a game behind
+	createArgs.game, createArgs.generator, createArgs.mapFile = "g1", "file", filepath.Join(config.GamesDir, "missing.csv")
+	if err := createCmd.RunE(createCmd, nil); err == nil {
+		t.Errorf("missing map: expected error: got nil\n")
+	}
+	reg, err := openRegistry()
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err = reg.Get("g1"); err == nil {
+		t.Errorf("missing map: expected no game: got g1\n")
+	}
+	_ = reg.Store().Close()
+
+	createArgs.generator, createArgs.mapFile = "sphere", ""
+	if err := createCmd.RunE(createCmd, nil); err != nil {
+		t.Fatalf("create: %v\n", err)
+	}
+	if reg, err = openRegistry(); err != nil {
+		t.Fatal(err)
+	}
+	defer reg.Store().Close()
+	g, err := reg.Get("g1")
+	if err != nil {
+		t.Fatalf("create: expected g1: got %v\n", err)
+	}
+	if _, err = g.Cluster(); err != nil {
+		t.Errorf("cluster: expected nil: got %v\n", err)
+	}
+	if s, err := g.State(); err != nil {
+		t.Errorf("state: expected nil: got %v\n", err)
+	} else if a, b := s.Nations["n1"].HomeSystem, s.Nations["n2"].HomeSystem; a == b {
+		t.Errorf("homes: expected two home systems: got %d and %d\n", a, b)
+	}
+}
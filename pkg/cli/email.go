@@ -0,0 +1,124 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/email"
+	"github.com/mdhender/wraithe/pkg/games"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"time"
+)
+
+var emailArgs struct {
+	maildir string
+	game    string
+	turn    int
+}
+
+// emailCmd groups the play-by-email commands.
+var emailCmd = &cobra.Command{
+	Use:   "email",
+	Short: "play-by-email gateway",
+	Long: `Commands for accepting orders and sending reports by email.
+Outgoing messages are sent through the configured SMTP server or, if
+there isn't one, written to the outbox directory.`,
+}
+
+// emailReceiveCmd accepts orders from inbound messages.
+var emailReceiveCmd = &cobra.Command{
+	Use:   "receive",
+	Short: "accept orders from email",
+	Long: `Accept orders from email messages. With --maildir, every message in
+the maildir's "new" directory is processed. Otherwise a single message
+is read from stdin, which lets the command be used as a mail filter.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := openRegistry()
+		if err != nil {
+			return err
+		}
+		defer reg.Store().Close()
+		gw, err := newGateway(reg)
+		if err != nil {
+			return err
+		}
+
+		if emailArgs.maildir == "" {
+			return gw.Receive(os.Stdin, time.Now().UTC())
+		}
+		n, err := gw.ReceiveMaildir(resolvePath(emailArgs.maildir), time.Now().UTC())
+		log.Printf("[email] processed %d messages\n", n)
+		return err
+	},
+}
+
+// emailReportsCmd sends turn reports to the players.
+var emailReportsCmd = &cobra.Command{
+	Use:   "send-reports",
+	Short: "send turn reports by email",
+	Long:  `Send a turn's reports to every player that has an email address.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := openRegistry()
+		if err != nil {
+			return err
+		}
+		defer reg.Store().Close()
+		gw, err := newGateway(reg)
+		if err != nil {
+			return err
+		}
+
+		g, err := reg.Get(emailArgs.game)
+		if err != nil {
+			return fmt.Errorf("game %q: %w", emailArgs.game, err)
+		}
+		turn := emailArgs.turn
+		if turn == 0 {
+			turn = g.Progress().Turn
+		}
+		return gw.SendReports(g, turn)
+	},
+}
+
+func init() {
+	cmdCLI.AddCommand(emailCmd)
+	emailCmd.AddCommand(emailReceiveCmd)
+	emailReceiveCmd.Flags().StringVar(&emailArgs.maildir, "maildir", "", "maildir to read messages from (defaults to reading one message from stdin)")
+	emailCmd.AddCommand(emailReportsCmd)
+	emailReportsCmd.Flags().StringVar(&emailArgs.game, "game", "", "id of the game")
+	_ = emailReportsCmd.MarkFlagRequired("game")
+	emailReportsCmd.Flags().IntVar(&emailArgs.turn, "turn", 0, "turn to send reports for (zero sends the latest turn)")
+}
+
+// newGateway returns a gateway using the configured sender.
+func newGateway(reg *games.Registry) (*email.Gateway, error) {
+	var sender email.Sender
+	if smtp := config.Email.SMTP; smtp.Host != "" {
+		sender = email.NewSMTP(smtp.Host, smtp.Port, smtp.Username, smtp.Password)
+	} else {
+		outbox, err := email.NewOutbox(resolvePath(config.Email.Outbox))
+		if err != nil {
+			return nil, err
+		}
+		sender = outbox
+	}
+	return email.NewGateway(reg, sender, config.Email.From), nil
+}
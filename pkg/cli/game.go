@@ -0,0 +1,111 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/games"
+	"github.com/spf13/cobra"
+	"log"
+)
+
+var gameArgs struct {
+	game     string
+	schedule games.Schedule
+}
+
+// gameCmd groups the referee commands for running a game.
+var gameCmd = &cobra.Command{
+	Use:   "game",
+	Short: "run a game",
+	Long:  `Commands for the referee to start, schedule, and resume a game.`,
+}
+
+// gameActivateCmd lets the scheduler run turns for a game.
+var gameActivateCmd = &cobra.Command{
+	Use:   "activate",
+	Short: "start or resume a game",
+	Long: `Activate a game so that the scheduler runs its turns.
+Use this to start a new game once it has been set up and to resume
+a game that was paused after a turn failed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := openRegistry()
+		if err != nil {
+			return err
+		}
+		defer reg.Store().Close()
+
+		g, err := reg.Get(gameArgs.game)
+		if err != nil {
+			return fmt.Errorf("game %q: %w", gameArgs.game, err)
+		}
+		if err = g.Activate(); err != nil {
+			return fmt.Errorf("game %q: %w", g.Id, err)
+		}
+		if err = reg.Save(g); err != nil {
+			return err
+		}
+		log.Printf("[game] %s: activated for turn %d\n", g.Id, g.Progress().Turn+1)
+		return nil
+	},
+}
+
+// gameScheduleCmd sets or changes the schedule for a game.
+var gameScheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "set when turns are due",
+	Long: `Set the schedule for a game's turns. All times are UTC.
+Without --weekday, turns are due every day at --time.
+With --when-all-submitted, a turn runs as soon as every nation has
+submitted orders, even if the deadline hasn't passed.
+The next deadline is set from the new schedule.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := openRegistry()
+		if err != nil {
+			return err
+		}
+		defer reg.Store().Close()
+
+		g, err := reg.Get(gameArgs.game)
+		if err != nil {
+			return fmt.Errorf("game %q: %w", gameArgs.game, err)
+		}
+		if err = g.SetSchedule(gameArgs.schedule); err != nil {
+			return fmt.Errorf("game %q: %w", g.Id, err)
+		}
+		if err = reg.Save(g); err != nil {
+			return err
+		}
+		log.Printf("[game] %s: schedule %+v\n", g.Id, gameArgs.schedule)
+		return nil
+	},
+}
+
+func init() {
+	cmdCLI.AddCommand(gameCmd)
+	gameCmd.AddCommand(gameActivateCmd)
+	gameActivateCmd.Flags().StringVar(&gameArgs.game, "game", "", "id of the game")
+	_ = gameActivateCmd.MarkFlagRequired("game")
+	gameCmd.AddCommand(gameScheduleCmd)
+	gameScheduleCmd.Flags().StringVar(&gameArgs.game, "game", "", "id of the game")
+	_ = gameScheduleCmd.MarkFlagRequired("game")
+	gameScheduleCmd.Flags().StringVar(&gameArgs.schedule.Weekday, "weekday", "", "day of the week that turns are due")
+	gameScheduleCmd.Flags().StringVar(&gameArgs.schedule.Time, "time", "", "time of day (HH:MM, UTC) that turns are due")
+	gameScheduleCmd.Flags().BoolVar(&gameArgs.schedule.WhenAllSubmitted, "when-all-submitted", false, "run the turn once every nation has submitted orders")
+}
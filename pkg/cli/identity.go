@@ -0,0 +1,82 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/authn"
+	"github.com/spf13/cobra"
+	"log"
+	"time"
+)
+
+var identityArgs struct {
+	id     string
+	login  string
+	secret string
+}
+
+// identityCmd groups the commands for managing the identities users log in with.
+var identityCmd = &cobra.Command{
+	Use:   "identity",
+	Short: "manage identities",
+	Long:  `Commands for managing the identities that users log in to the server with.`,
+}
+
+// identitySetCmd adds or replaces an identity.
+var identitySetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "add or replace an identity",
+	Long: `Add the identity for a user or replace the existing one.
+Users log in with the login name and secret. The id is the user id
+that games grant roles to (see player set --user).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := openRegistry()
+		if err != nil {
+			return err
+		}
+		defer reg.Store().Close()
+
+		// the identity store only issues tokens, which this command doesn't need
+		ids, err := authn.New(reg.Store(), authn.NewAuthenticator(time.Minute))
+		if err != nil {
+			return err
+		}
+		hashed, err := authn.HashSecret(identityArgs.secret)
+		if err != nil {
+			return fmt.Errorf("secret: %w", err)
+		}
+		if err = ids.AddIdentity(authn.Identity{Id: identityArgs.id, UserId: identityArgs.login, HashedSecret: hashed}); err != nil {
+			return err
+		}
+		log.Printf("[identity] %s: saved identity for %q\n", identityArgs.login, identityArgs.id)
+		return nil
+	},
+}
+
+func init() {
+	cmdCLI.AddCommand(identityCmd)
+	identityCmd.AddCommand(identitySetCmd)
+	identitySetCmd.Flags().StringVar(&identityArgs.id, "id", "", "id of the user in games")
+	_ = identitySetCmd.MarkFlagRequired("id")
+	identitySetCmd.Flags().StringVar(&identityArgs.login, "login", "", "name the user logs in with")
+	_ = identitySetCmd.MarkFlagRequired("login")
+	identitySetCmd.Flags().StringVar(&identityArgs.secret, "secret", "", "secret the user logs in with")
+	_ = identitySetCmd.MarkFlagRequired("secret")
+}
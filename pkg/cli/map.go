@@ -0,0 +1,131 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/wraith"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var mapArgs struct {
+	game       string
+	nation     string
+	projection string
+	format     string
+	output     string
+	size       int
+	labels     bool
+}
+
+// mapCmd writes a printable map of a game's cluster.
+var mapCmd = &cobra.Command{
+	Use:   "map",
+	Short: "export a printable map",
+	Long: `Draw the cluster as an SVG or PNG image, projected onto a flat view:
+xy (from above), xz (from the front), yz (from the side), or iso.
+
+With --nation, the map shows only the systems that the nation knows
+about and only that nation's holdings. Without it, the map shows the
+whole cluster and every nation, as the referee sees it.
+
+The format defaults to the extension of --output, or svg.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := wraith.ParseProjection(mapArgs.projection); err != nil {
+			return err
+		}
+		if mapArgs.format == "" {
+			mapArgs.format = strings.TrimPrefix(strings.ToLower(filepath.Ext(mapArgs.output)), ".")
+			if mapArgs.format == "" {
+				mapArgs.format = "svg"
+			}
+		}
+		if mapArgs.format != "svg" && mapArgs.format != "png" {
+			return fmt.Errorf("format must be svg or png")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := openRegistry()
+		if err != nil {
+			return err
+		}
+		defer reg.Store().Close()
+
+		g, err := reg.Get(mapArgs.game)
+		if err != nil {
+			return fmt.Errorf("game %q: %w", mapArgs.game, err)
+		}
+		c, err := g.Cluster()
+		if err != nil {
+			return err
+		}
+		s, err := g.State()
+		if err != nil {
+			return err
+		}
+		proj, _ := wraith.ParseProjection(mapArgs.projection)
+		opts := wraith.MapOptions{
+			Projection: proj,
+			Size:       mapArgs.size,
+			Visible:    wraith.All,
+			Owners:     s.Owners(),
+			Labels:     mapArgs.labels,
+			Title:      fmt.Sprintf("%s turn %d (%s)", g.Name, s.Turn, proj),
+		}
+		if mapArgs.nation != "" {
+			n, ok := s.Nations[mapArgs.nation]
+			if !ok {
+				return fmt.Errorf("game %q: no nation %q", g.Id, mapArgs.nation)
+			}
+			opts.Visible, opts.Owners = wraith.NationVisibility(n), s.Owners(n)
+			opts.Title = fmt.Sprintf("%s turn %d: %s (%s)", g.Name, s.Turn, n.Name, proj)
+		}
+
+		var w io.Writer = cmd.OutOrStdout()
+		if mapArgs.output != "" {
+			fd, err := os.Create(resolvePath(mapArgs.output))
+			if err != nil {
+				return err
+			}
+			defer fd.Close()
+			w = fd
+		}
+		if mapArgs.format == "png" {
+			return c.RenderPNG(w, opts)
+		}
+		return c.RenderSVG(w, opts)
+	},
+}
+
+func init() {
+	cmdCLI.AddCommand(mapCmd)
+	mapCmd.Flags().StringVar(&mapArgs.game, "game", "", "id of the game")
+	_ = mapCmd.MarkFlagRequired("game")
+	mapCmd.Flags().StringVar(&mapArgs.nation, "nation", "", "draw the map as the nation sees it")
+	mapCmd.Flags().StringVar(&mapArgs.projection, "projection", "xy", "projection (xy, xz, yz, or iso)")
+	mapCmd.Flags().StringVar(&mapArgs.format, "format", "", "image format (svg or png)")
+	mapCmd.Flags().StringVar(&mapArgs.output, "output", "", "file to write the map to (defaults to stdout)")
+	mapCmd.Flags().IntVar(&mapArgs.size, "size", 1024, "width and height of the map in pixels")
+	mapCmd.Flags().BoolVar(&mapArgs.labels, "labels", true, "label the systems with their names")
+}
@@ -0,0 +1,99 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/games"
+	"github.com/spf13/cobra"
+	"log"
+)
+
+var playerArgs struct {
+	game   string
+	nation string
+	userId string
+	email  string
+	secret string
+}
+
+// playerCmd groups the commands for managing players.
+var playerCmd = &cobra.Command{
+	Use:   "player",
+	Short: "manage players",
+	Long:  `Commands for managing the players in a game.`,
+}
+
+// playerSetCmd adds or updates the player for a nation.
+var playerSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "add or update a player",
+	Long: `Add the player for a nation or update the existing player.
+The email address and secret are used to accept orders by email.
+Flags that are not given leave the existing values unchanged.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := openRegistry()
+		if err != nil {
+			return err
+		}
+		defer reg.Store().Close()
+
+		g, err := reg.Get(playerArgs.game)
+		if err != nil {
+			return fmt.Errorf("game %q: %w", playerArgs.game, err)
+		}
+		p, ok := g.Player(playerArgs.nation)
+		if !ok {
+			p = games.Player{Nation: playerArgs.nation}
+		}
+		flags := cmd.Flags()
+		if flags.Changed("user") {
+			p.UserId = playerArgs.userId
+		}
+		if flags.Changed("email") {
+			p.Email = playerArgs.email
+		}
+		if flags.Changed("secret") {
+			if err = p.SetSecret(playerArgs.secret); err != nil {
+				return fmt.Errorf("secret: %w", err)
+			}
+		}
+		if p.UserId == "" {
+			return fmt.Errorf("user: required for a new player")
+		}
+		g.SetPlayer(p)
+		if err = reg.Save(g); err != nil {
+			return err
+		}
+		log.Printf("[player] %s/%s: saved player %q\n", g.Id, p.Nation, p.UserId)
+		return nil
+	},
+}
+
+func init() {
+	cmdCLI.AddCommand(playerCmd)
+	playerCmd.AddCommand(playerSetCmd)
+	playerSetCmd.Flags().StringVar(&playerArgs.game, "game", "", "id of the game")
+	_ = playerSetCmd.MarkFlagRequired("game")
+	playerSetCmd.Flags().StringVar(&playerArgs.nation, "nation", "", "id of the nation")
+	_ = playerSetCmd.MarkFlagRequired("nation")
+	playerSetCmd.Flags().StringVar(&playerArgs.userId, "user", "", "id of the user that plays the nation")
+	playerSetCmd.Flags().StringVar(&playerArgs.email, "email", "", "email address for orders and reports")
+	playerSetCmd.Flags().StringVar(&playerArgs.secret, "secret", "", "secret to include with orders sent by email")
+}
@@ -0,0 +1,71 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+var replayArgs struct {
+	game string
+	turn int
+}
+
+// replayCmd re-runs turns to verify that the engine is deterministic.
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "verify completed turns",
+	Long: `Re-run completed turns from the previous turn's snapshot, the stored
+orders, and the game's seed, and verify that the results are identical
+to the stored snapshot and reports. A difference means that the engine
+is not deterministic.
+
+By default every completed turn is replayed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := openRegistry()
+		if err != nil {
+			return err
+		}
+		defer reg.Store().Close()
+
+		g, err := reg.Get(replayArgs.game)
+		if err != nil {
+			return fmt.Errorf("game %q: %w", replayArgs.game, err)
+		}
+		first, last := 1, g.Progress().Turn
+		if replayArgs.turn != 0 {
+			first, last = replayArgs.turn, replayArgs.turn
+		}
+		for turn := first; turn <= last; turn++ {
+			if err := g.Replay(turn); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "turn %4d: ok\n", turn)
+		}
+		return nil
+	},
+}
+
+func init() {
+	cmdCLI.AddCommand(replayCmd)
+	replayCmd.Flags().StringVar(&replayArgs.game, "game", "", "id of the game to replay")
+	_ = replayCmd.MarkFlagRequired("game")
+	replayCmd.Flags().IntVar(&replayArgs.turn, "turn", 0, "turn to replay (zero replays every completed turn)")
+}
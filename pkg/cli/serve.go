@@ -0,0 +1,208 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	html "github.com/mdhender/wraithe/handlers/html"
+	rest "github.com/mdhender/wraithe/handlers/rest"
+	"github.com/mdhender/wraithe/pkg/authn"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/games"
+	"github.com/spf13/cobra"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// serveCmd runs the http server until it is interrupted.
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "run the game server",
+	Long: `Run the game server.
+The server shuts down gracefully on SIGINT or SIGTERM, waiting for
+in-flight requests (such as turn submissions) to finish.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := openRegistry()
+		if err != nil {
+			return err
+		}
+		defer reg.Store().Close()
+		log.Printf("%-30s == %d\n", "games", len(reg.List()))
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		an, err := newAuthenticator()
+		if err != nil {
+			return err
+		}
+		ids, err := authn.New(reg.Store(), an)
+		if err != nil {
+			return err
+		}
+		az := authz.New()
+		reg.Authorize(az)
+
+		return serve(ctx, reg, an, ids, az)
+	},
+}
+
+func init() {
+	cmdCLI.AddCommand(serveCmd)
+}
+
+// serve runs the server until the context is cancelled.
+// It then stops accepting new requests and waits for in-flight
+// requests to complete before returning.
+func serve(ctx context.Context, reg *games.Registry, an *authn.Authenticator, ids *authn.Store, az *authz.Authorizer) error {
+	// ready is set to 1 while the server is accepting requests
+	var ready int32
+
+	s := &http.Server{
+		Addr:              net.JoinHostPort(config.Server.Http.Host, config.Server.Http.Port),
+		Handler:           routes(reg, an, ids, az, &ready),
+		ReadTimeout:       config.Server.Http.ReadTimeout.Duration,
+		ReadHeaderTimeout: config.Server.Http.ReadTimeout.Duration,
+		WriteTimeout:      config.Server.Http.WriteTimeout.Duration,
+		IdleTimeout:       config.Server.Http.IdleTimeout.Duration,
+	}
+
+	// bind the port before anything else so that a port that is in use
+	// fails fast, and so that we are never ready without a listener.
+	l, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+
+	// run the scheduler in the background until we return.
+	// a turn that is in progress is allowed to finish, on every path
+	// out of this function, before the caller closes the store.
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		games.NewScheduler(reg, config.Scheduler.Interval.Duration).Run(schedulerCtx)
+	}()
+	defer func() {
+		stopScheduler()
+		<-schedulerDone
+	}()
+
+	// serve in the background so that we can wait for a signal.
+	// the port is bound, so requests wait for Serve instead of failing.
+	errch := make(chan error, 1)
+	go func() {
+		log.Printf("[serve] listening on %q\n", l.Addr().String())
+		errch <- s.Serve(l)
+	}()
+	atomic.StoreInt32(&ready, 1)
+
+	select {
+	case err := <-errch:
+		// the listener failed before we were asked to stop
+		return err
+	case <-ctx.Done():
+		log.Printf("[serve] shutting down\n")
+	}
+	atomic.StoreInt32(&ready, 0)
+
+	// note: requests don't use ctx as their base context so that a signal
+	// doesn't cancel them. Shutdown waits for them until the deadline.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.Server.Http.ShutdownTimeout.Duration)
+	defer cancel()
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errch; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	log.Printf("[serve] shut down cleanly\n")
+
+	return nil
+}
+
+// newAuthenticator returns the authenticator for the server's tokens.
+// Tokens are signed with the configured key, or with a random key if
+// there isn't one.
+func newAuthenticator() (*authn.Authenticator, error) {
+	secret := config.Server.Auth.SigningKey
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(buf)
+		log.Printf("[serve] no signing key configured: tokens will not survive a restart\n")
+	}
+	// the key doesn't expire. change the secret to invalidate every token.
+	key := authn.NewSigningKey("default", secret, time.Now().AddDate(100, 0, 0))
+	return authn.NewAuthenticator(config.Server.Auth.TokenTTL.Duration, key), nil
+}
+
+// routes returns the router for the server.
+func routes(reg *games.Registry, an *authn.Authenticator, ids *authn.Store, az *authz.Authorizer, ready *int32) http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+	r.Use(middleware.URLFormat)
+
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("This is my index!"))
+	})
+
+	// health reports that the process is alive.
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	// ready reports whether the server is accepting requests.
+	r.Get("/ready", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(ready) != 1 {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ready"))
+	})
+
+	r.Route("/api", func(r chi.Router) {
+		r.Use(an.FromRequest)
+		r.Use(az.FromRequest)
+		r.Post("/login", rest.Login(ids))
+		r.Mount("/", rest.Routes(reg))
+	})
+	r.Route("/ui", func(r chi.Router) {
+		r.Use(an.FromRequest)
+		r.Use(az.FromRequest)
+		r.Mount("/", html.Routes(reg, resolvePath(config.TemplatesDir), "/api"))
+	})
+
+	return r
+}
@@ -0,0 +1,192 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mdhender/wraithe/pkg/authn"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/cfg"
+	"github.com/mdhender/wraithe/pkg/games"
+	"github.com/mdhender/wraithe/pkg/wraith"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRoutes sends requests through the server's router, so the tokens
+// are checked by authn and the grants by authz, the way they are in
+// production.
+func TestRoutes(t *testing.T) {
+	config = cfg.Default()
+	reg, err := games.Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &games.Game{Id: "g1", Seed: 3, Referees: []string{"ref"}, Players: []games.Player{{UserId: "p1", Nation: "n1"}, {UserId: "p2", Nation: "n2"}}}
+	if err = reg.Create(g); err != nil {
+		t.Fatal(err)
+	}
+	rand.Seed(g.Seed)
+	c := wraith.G(2, 32, 16, 12.0)
+	s, err := wraith.NewState(c, "n1", "n2")
+	if err != nil {
+		t.Fatal(err)
+	} else if err = g.SaveCluster(c); err != nil {
+		t.Fatal(err)
+	} else if err = g.SaveState(s); err != nil {
+		t.Fatal(err)
+	}
+
+	an := authn.NewAuthenticator(time.Hour, authn.NewSigningKey("k1", "secret", time.Now().Add(24*time.Hour)))
+	ids, err := authn.New(reg.Store(), an)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed, err := authn.HashSecret("swordfish")
+	if err != nil {
+		t.Fatal(err)
+	} else if err = ids.AddIdentity(authn.Identity{Id: "ref", UserId: "referee", HashedSecret: hashed}); err != nil {
+		t.Fatal(err)
+	}
+	az := authz.New()
+	reg.Authorize(az)
+	ready := int32(1)
+	h := routes(reg, an, ids, az, &ready)
+
+	token := func(userId string) string {
+		tok, err := an.NewToken(userId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok.String()
+	}
+	forged := authn.NewAuthenticator(time.Hour, authn.NewSigningKey("k1", "guess", time.Now().Add(24*time.Hour)))
+	badToken, err := forged.NewToken("ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		id     int
+		method string
+		path   string
+		bearer string
+		cookie string
+		code   int
+	}{
+		{1, "GET", "/ready", "", "", http.StatusOK},
+		{2, "GET", "/api/games/g1/cluster/systems", "", "", http.StatusUnauthorized},
+		{3, "GET", "/api/games/g1/cluster/systems", token("p1"), "", http.StatusOK},
+		{4, "GET", "/api/games/g1/cluster/systems", "", token("p1"), http.StatusOK},
+		{5, "GET", "/api/games/g1/cluster/systems", badToken.String(), "", http.StatusUnauthorized},
+		{6, "GET", "/api/games/g1/nations/n1/systems", token("p1"), "", http.StatusOK},
+		{7, "GET", "/api/games/g1/nations/n2/systems", token("p1"), "", http.StatusForbidden},
+		{8, "GET", "/api/games/g1/turns/1/orders/n1", token("p1"), "", http.StatusNotFound},
+		{9, "POST", "/api/games/g1/activate", token("p1"), "", http.StatusForbidden},
+		{10, "POST", "/api/games/g1/activate", token("ref"), "", http.StatusOK},
+		{11, "GET", "/api/games/g1/cluster/systems", token("stranger"), "", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.bearer)
+		}
+		if tc.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: authn.CookieName, Value: tc.cookie})
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("%d: %s %s: expected %d: got %d %s\n", tc.id, tc.method, tc.path, tc.code, w.Code, w.Body.String())
+		}
+	}
+
+	// the referee logs in with the identity from the store
+	for _, tc := range []struct {
+		id   int
+		body string
+		code int
+	}{
+		{1, `{"user-id":"referee","secret":"sword"}`, http.StatusUnauthorized},
+		{2, `{"user-id":"ref","secret":"swordfish"}`, http.StatusUnauthorized},
+		{3, `{"user-id":"referee"`, http.StatusBadRequest},
+		{4, `{"user-id":"referee","secret":"swordfish"}`, http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/api/login", strings.NewReader(tc.body)))
+		if w.Code != tc.code {
+			t.Errorf("%d: login: expected %d: got %d %s\n", tc.id, tc.code, w.Code, w.Body.String())
+			continue
+		} else if w.Code != http.StatusOK {
+			continue
+		}
+		var rsp struct {
+			Token string `json:"token"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+			t.Fatalf("%d: login: %v\n", tc.id, err)
+		}
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != authn.CookieName || cookies[0].Value != rsp.Token {
+			t.Errorf("%d: login: expected the token in a cookie: got %v\n", tc.id, cookies)
+		}
+		req := httptest.NewRequest("PUT", "/api/games/g1/schedule", strings.NewReader(`{"time":"18:00"}`))
+		req.Header.Set("Authorization", "Bearer "+rsp.Token)
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("%d: schedule: expected %d: got %d %s\n", tc.id, http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestServeBusyPort checks that serve fails when it can't bind the port
+// instead of reporting that it is ready.
+func TestServeBusyPort(t *testing.T) {
+	config = cfg.Default()
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	if config.Server.Http.Host, config.Server.Http.Port, err = net.SplitHostPort(busy.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := games.Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	an := authn.NewAuthenticator(time.Hour)
+	ids, err := authn.New(reg.Store(), an)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = serve(ctx, reg, an, ids, authz.New()); err == nil {
+		t.Errorf("serve: expected error: got nil\n")
+	} else if ctx.Err() != nil {
+		t.Errorf("serve: expected to fail before the timeout: got %v\n", ctx.Err())
+	}
+}
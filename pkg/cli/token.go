@@ -0,0 +1,60 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+var tokenArgs struct {
+	userId string
+}
+
+// tokenCmd prints a token for a user.
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "issue a token for a user",
+	Long: `Print a token that authenticates the user with the server.
+Clients send it in an "Authorization: Bearer" header, and browsers in
+the wraith-token cookie.
+The token is signed with WRAITH_SIGNING_KEY, which must be the same
+key the server uses.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if config.Server.Auth.SigningKey == "" {
+			return fmt.Errorf("token: WRAITH_SIGNING_KEY is not set")
+		}
+		an, err := newAuthenticator()
+		if err != nil {
+			return err
+		}
+		t, err := an.NewToken(tokenArgs.userId)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), t)
+		return err
+	},
+}
+
+func init() {
+	cmdCLI.AddCommand(tokenCmd)
+	tokenCmd.Flags().StringVar(&tokenArgs.userId, "user", "", "id of the user")
+	_ = tokenCmd.MarkFlagRequired("user")
+}
@@ -0,0 +1,230 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package email
+
+import (
+	"errors"
+	"github.com/mdhender/wraithe/pkg/games"
+	"github.com/mdhender/wraithe/pkg/wraith"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const plainMessage = "From: Frodo <frodo@example.com>\r\n" +
+	"To: wraith@example.com\r\n" +
+	"Subject: turn 1\r\n" +
+	"Message-ID: <1@example.com>\r\n" +
+	"\r\n" +
+	"game: g1\r\n" +
+	"nation: n1\r\n" +
+	"secret: swordfish\r\n" +
+	"\r\n" +
+	"build scout 1\r\n" +
+	"-- \r\n" +
+	"Sent from my phone\r\n"
+
+const multipartMessage = "From: frodo@example.com\r\n" +
+	"Subject: =?utf-8?q?orders_=E2=9C=93?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/alternative; boundary=XYZ\r\n" +
+	"\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: text/html\r\n" +
+	"\r\n" +
+	"<p>orders</p>\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"Z2FtZTogZzEKbmF0aW9uOiBuMQpzZWNyZXQ6IHN3b3JkZmlzaAp0dXJuOiAxCgpuYW1lIFMx\r\n" +
+	"IEV4cGxvcmVyCg==\r\n" +
+	"--XYZ--\r\n"
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		id      int
+		input   string
+		subject string
+		turn    int
+		orders  string
+	}{
+		{1, plainMessage, "turn 1", 0, "build scout 1\n"},
+		{2, multipartMessage, "orders ✓", 1, "name S1 Explorer\n"},
+	} {
+		sub, err := Parse(strings.NewReader(tc.input))
+		if err != nil {
+			t.Errorf("%d: unexpected error %v\n", tc.id, err)
+			continue
+		}
+		if sub.From.Address != "frodo@example.com" || sub.Game != "g1" || sub.Nation != "n1" || sub.Secret != "swordfish" {
+			t.Errorf("%d: expected frodo g1 n1 swordfish: got %q %q %q %q\n", tc.id, sub.From.Address, sub.Game, sub.Nation, sub.Secret)
+		}
+		if sub.Subject != tc.subject {
+			t.Errorf("%d: subject: expected %q: got %q\n", tc.id, tc.subject, sub.Subject)
+		}
+		if sub.Turn != tc.turn {
+			t.Errorf("%d: turn: expected %d: got %d\n", tc.id, tc.turn, sub.Turn)
+		}
+		if string(sub.Orders) != tc.orders {
+			t.Errorf("%d: orders: expected %q: got %q\n", tc.id, tc.orders, sub.Orders)
+		}
+	}
+
+	if _, err := Parse(strings.NewReader("From: frodo@example.com\r\n\r\nbuild scout 1\r\n")); err == nil {
+		t.Errorf("missing header: expected error: got nil\n")
+	}
+}
+
+// mailbag is a Sender that keeps the messages it is given.
+type mailbag []*Message
+
+func (mb *mailbag) Send(m *Message) error {
+	*mb = append(*mb, m)
+	return nil
+}
+
+// deadServer is a Sender whose mail server is down.
+type deadServer struct{}
+
+func (deadServer) Send(m *Message) error {
+	return errors.New("connection refused")
+}
+
+func TestGateway(t *testing.T) {
+	reg, err := games.Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := games.Player{UserId: "u1", Nation: "n1", Email: "Frodo@Example.com"}
+	if err = p.SetSecret("swordfish"); err != nil {
+		t.Fatal(err)
+	}
+	g := &games.Game{Id: "g1", Name: "Game One", Status: games.Active, Players: []games.Player{p}}
+	if err = reg.Create(g); err != nil {
+		t.Fatal(err)
+	}
+	c := wraith.G(1, 16, 4, 10.0)
+	s, err := wraith.NewState(c, "n1")
+	if err != nil {
+		t.Fatal(err)
+	} else if err = g.SaveCluster(c); err != nil {
+		t.Fatal(err)
+	} else if err = g.SaveState(s); err != nil {
+		t.Fatal(err)
+	}
+
+	var sent mailbag
+	gw := NewGateway(reg, &sent, "wraith@example.com")
+	now := time.Date(2022, 8, 3, 12, 0, 0, 0, time.UTC)
+
+	// the orders are accepted and confirmed
+	if err = gw.Receive(strings.NewReader(plainMessage), now); err != nil {
+		t.Fatalf("receive: %v\n", err)
+	}
+	if data, version, err := g.Orders(1, "n1"); err != nil || version != 1 || string(data) != "build scout 1\n" {
+		t.Errorf("orders: expected version 1: got %d %q %v\n", version, data, err)
+	}
+	if len(sent) != 1 || !strings.Contains(string(sent[0].Body), "accepted as version 1") || sent[0].InReplyTo != "<1@example.com>" {
+		t.Fatalf("reply: expected confirmation: got %d messages\n", len(sent))
+	}
+
+	// orders with errors get the errors and aren't submitted
+	bad := strings.Replace(plainMessage, "build scout 1", "launch missiles", 1)
+	if err = gw.Receive(strings.NewReader(bad), now); err != nil {
+		t.Fatalf("receive bad: %v\n", err)
+	}
+	if _, version, _ := g.Orders(1, "n1"); version != 1 {
+		t.Errorf("bad orders: expected version 1: got %d\n", version)
+	}
+	if len(sent) != 2 || !strings.Contains(string(sent[1].Body), "not accepted") {
+		t.Errorf("bad orders: expected error reply: got %d messages\n", len(sent))
+	}
+
+	// a stranger gets nothing
+	stranger := strings.Replace(plainMessage, "frodo@example.com", "gollum@example.com", 1)
+	if err = gw.Receive(strings.NewReader(stranger), now); err == nil {
+		t.Errorf("stranger: expected error: got nil\n")
+	} else if !Rejected(err) || !errors.Is(err, ErrUnidentified) {
+		t.Errorf("stranger: expected a rejection: got %v\n", err)
+	} else if len(sent) != 2 {
+		t.Errorf("stranger: expected no reply: got %d messages\n", len(sent))
+	}
+
+	// messages in a maildir are processed once
+	maildir := t.TempDir()
+	if err = os.MkdirAll(filepath.Join(maildir, "new"), 0777); err != nil {
+		t.Fatal(err)
+	} else if err = os.WriteFile(filepath.Join(maildir, "new", "1.msg"), []byte(plainMessage), 0666); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []int{1, 0} {
+		if n, err := gw.ReceiveMaildir(maildir, now); err != nil || n != expect {
+			t.Errorf("maildir: expected %d: got %d %v\n", expect, n, err)
+		}
+	}
+	if _, version, _ := g.Orders(1, "n1"); version != 2 {
+		t.Errorf("maildir: expected version 2: got %d\n", version)
+	}
+
+	// rejected messages are done with, but failures are tried again
+	if err = os.WriteFile(filepath.Join(maildir, "new", "2.msg"), []byte(stranger), 0666); err != nil {
+		t.Fatal(err)
+	} else if err = os.WriteFile(filepath.Join(maildir, "new", "3.msg"), []byte(plainMessage), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := NewGateway(reg, deadServer{}, "wraith@example.com").ReceiveMaildir(maildir, now); err != nil || n != 1 {
+		t.Errorf("maildir: dead server: expected 1: got %d %v\n", n, err)
+	}
+	if _, err = os.Stat(filepath.Join(maildir, "cur", "2.msg:2,S")); err != nil {
+		t.Errorf("maildir: stranger: expected it in cur: got %v\n", err)
+	} else if _, err = os.Stat(filepath.Join(maildir, "new", "3.msg")); err != nil {
+		t.Errorf("maildir: dead server: expected it in new: got %v\n", err)
+	}
+	if n, err := gw.ReceiveMaildir(maildir, now); err != nil || n != 1 {
+		t.Errorf("maildir: retry: expected 1: got %d %v\n", n, err)
+	}
+
+	// reports are written to the outbox
+	if err = g.RunTurn(now); err != nil {
+		t.Fatal(err)
+	}
+	outbox, err := NewOutbox(filepath.Join(t.TempDir(), "outbox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = NewGateway(reg, outbox, "wraith@example.com").SendReports(g, 1); err != nil {
+		t.Fatalf("reports: %v\n", err)
+	}
+	entries, err := os.ReadDir(outbox.dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("reports: expected 1 message: got %d %v\n", len(entries), err)
+	}
+	data, err := os.ReadFile(filepath.Join(outbox.dir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"To: Frodo@Example.com\r\n", "Subject: Game One: turn 1 report for n1\r\n", "Content-Transfer-Encoding: quoted-printable\r\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("reports: expected %q in message\n", want)
+		}
+	}
+}
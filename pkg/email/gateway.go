@@ -0,0 +1,207 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package email
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/games"
+	"github.com/mdhender/wraithe/pkg/orders"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUnidentified is returned when the sender and secret don't match
+// the player for the nation.
+var ErrUnidentified = errors.New("unable to identify the nation")
+
+// rejection marks an error from Receive for a message that will never be
+// accepted, so there is no point in receiving it again (see Rejected).
+type rejection struct {
+	err error
+}
+
+func (r rejection) Error() string { return r.err.Error() }
+func (r rejection) Unwrap() error { return r.err }
+
+// Rejected returns true if the error from Receive means that the message
+// will never be accepted: it can't be parsed, it isn't from a player, or
+// the game won't take the orders. Other errors, like a failing store or
+// mail server, may go away if the message is received again.
+func Rejected(err error) bool {
+	var r rejection
+	return errors.As(err, &r)
+}
+
+// Gateway connects email to the games in a registry.
+type Gateway struct {
+	reg    *games.Registry
+	sender Sender
+	from   string
+}
+
+// NewGateway returns a gateway that sends replies and reports from the address.
+func NewGateway(reg *games.Registry, sender Sender, from string) *Gateway {
+	return &Gateway{reg: reg, sender: sender, from: from}
+}
+
+// Receive processes a single inbound message.
+// Valid orders are submitted and the sender gets a confirmation.
+// Orders with errors are not submitted and the sender gets the errors.
+// Messages that can't be tied to a player are not answered.
+// Messages that will never be accepted return an error that is Rejected.
+func (gw *Gateway) Receive(r io.Reader, now time.Time) error {
+	sub, err := Parse(r)
+	if sub == nil {
+		return rejection{err}
+	} else if err != nil {
+		// we don't know who this is, so there is nobody to reply to
+		return rejection{fmt.Errorf("%s: %w", sub.From.Address, err)}
+	}
+
+	g, err := gw.reg.Get(sub.Game)
+	if err != nil {
+		return rejection{fmt.Errorf("%s: game %q: %w", sub.From.Address, sub.Game, err)}
+	}
+	p, ok := g.Player(sub.Nation)
+	if !ok || !strings.EqualFold(p.Email, sub.From.Address) {
+		return rejection{fmt.Errorf("%s: %s/%s: %w", sub.From.Address, sub.Game, sub.Nation, ErrUnidentified)}
+	} else if !p.CheckSecret(sub.Secret) {
+		// the sender is the player, so it is safe to tell them
+		_ = gw.reply(sub, "Your orders were not accepted.\n\nThe secret does not match our records.\n")
+		return rejection{fmt.Errorf("%s: %s/%s: %w", sub.From.Address, sub.Game, sub.Nation, ErrUnidentified)}
+	}
+
+	turn := sub.Turn
+	if turn == 0 {
+		turn = g.Progress().Turn + 1
+	}
+	if _, errs := orders.Parse(sub.Orders); len(errs) != 0 {
+		b := &bytes.Buffer{}
+		fmt.Fprintf(b, "Your orders for turn %d were not accepted.\n\nPlease correct these errors and send them again:\n\n", turn)
+		for _, e := range errs {
+			fmt.Fprintf(b, "  %s\n", e.Error())
+		}
+		return gw.reply(sub, b.String())
+	}
+	version, err := g.SubmitOrders(turn, sub.Nation, sub.Orders, now)
+	if errors.Is(err, games.ErrOrdersClosed) || errors.Is(err, games.ErrWrongTurn) || errors.Is(err, games.ErrUnknownNation) {
+		_ = gw.reply(sub, fmt.Sprintf("Your orders for turn %d were not accepted.\n\n%v\n", turn, err))
+		return rejection{fmt.Errorf("%s: %s/%s: %w", sub.From.Address, sub.Game, sub.Nation, err)}
+	} else if err != nil {
+		// don't tell the player; the orders will be submitted again
+		return fmt.Errorf("%s: %s/%s: %w", sub.From.Address, sub.Game, sub.Nation, err)
+	}
+	log.Printf("[email] %s/%s: accepted orders for turn %d (version %d)\n", sub.Game, sub.Nation, turn, version)
+	return gw.reply(sub, fmt.Sprintf("Your orders for turn %d were accepted as version %d.\n\n%s", turn, version, sub.Orders))
+}
+
+// reply sends a message back to the sender of the submission.
+func (gw *Gateway) reply(sub *Submission, body string) error {
+	subject := sub.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = strings.TrimSpace("Re: " + subject)
+	}
+	return gw.sender.Send(&Message{
+		From:      gw.from,
+		To:        sub.From.String(),
+		Subject:   subject,
+		Body:      []byte(body),
+		InReplyTo: sub.MessageId,
+	})
+}
+
+// ReceiveMaildir processes every message in the maildir's "new" directory.
+// Each message is moved to the "cur" directory and marked as seen once it
+// has been processed, even if it was rejected, so that it is never
+// processed twice. Rejected messages are logged. Messages that fail for
+// any other reason, like a store or mail server error, are logged and
+// left in "new" to be tried again.
+// It returns the number of messages processed.
+func (gw *Gateway) ReceiveMaildir(dir string, now time.Time) (int, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, "new"))
+	if err != nil {
+		return 0, err
+	}
+	if err = os.MkdirAll(filepath.Join(dir, "cur"), 0777); err != nil {
+		return 0, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	// maildir names start with a timestamp, so this is roughly arrival order
+	sort.Strings(names)
+
+	n := 0
+	for _, name := range names {
+		filename := filepath.Join(dir, "new", name)
+		fd, err := os.Open(filename)
+		if err != nil {
+			return n, err
+		}
+		err = gw.Receive(fd, now)
+		_ = fd.Close()
+		if Rejected(err) {
+			log.Printf("[email] %s: %v\n", name, err)
+		} else if err != nil {
+			log.Printf("[email] %s: %v: will try again\n", name, err)
+			continue
+		}
+		if err := os.Rename(filename, filepath.Join(dir, "cur", name+":2,S")); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// SendReports sends the turn's report to every player with an email address.
+func (gw *Gateway) SendReports(g *games.Game, turn int) error {
+	for _, p := range g.Players {
+		if p.Email == "" {
+			continue
+		}
+		data, err := g.Report(turn, p.Nation)
+		if errors.Is(err, games.ErrNoReport) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		m := &Message{
+			From:    gw.from,
+			To:      p.Email,
+			Subject: fmt.Sprintf("%s: turn %d report for %s", g.Name, turn, p.Nation),
+			Body:    data,
+		}
+		if err := gw.sender.Send(m); err != nil {
+			return fmt.Errorf("%s: %w", p.Nation, err)
+		}
+		log.Printf("[email] %s/%s: sent report for turn %d to %s\n", g.Id, p.Nation, turn, p.Email)
+	}
+	return nil
+}
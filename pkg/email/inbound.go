@@ -0,0 +1,182 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strconv"
+	"strings"
+)
+
+// ErrNoOrders is returned when a message doesn't contain a plain text body.
+var ErrNoOrders = errors.New("no plain text body")
+
+// Submission is the content of an inbound message.
+type Submission struct {
+	From      *mail.Address
+	Subject   string
+	MessageId string
+	Game      string
+	Nation    string
+	Secret    string
+	// Turn is zero if the message didn't specify one.
+	Turn int
+	// Orders is the body of the message after the header,
+	// with the signature removed and lines ending in "\n".
+	Orders []byte
+}
+
+// Parse reads a raw RFC 5322 message and extracts the submission.
+// For multipart messages, the first text/plain part is used.
+func Parse(r io.Reader) (*Submission, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return nil, fmt.Errorf("from: %w", err)
+	}
+	sub := &Submission{
+		From:      from,
+		Subject:   decodeHeader(msg.Header.Get("Subject")),
+		MessageId: msg.Header.Get("Message-Id"),
+	}
+
+	body, err := textBody(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return sub, err
+	}
+	if err = sub.parseBody(body); err != nil {
+		return sub, err
+	}
+	return sub, nil
+}
+
+// parseBody splits the body into the header and the orders.
+func (sub *Submission) parseBody(body []byte) error {
+	var lines []string
+	s := bufio.NewScanner(bytes.NewReader(body))
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		if line == "-- " {
+			break
+		}
+		lines = append(lines, line)
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	// skip leading blank lines, then read the header up to the first blank line
+	n := 0
+	for n < len(lines) && strings.TrimSpace(lines[n]) == "" {
+		n++
+	}
+	for ; n < len(lines) && strings.TrimSpace(lines[n]) != ""; n++ {
+		key, value, ok := strings.Cut(lines[n], ":")
+		if !ok {
+			return fmt.Errorf("header line %d: expected \"key: value\"", n+1)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "game":
+			sub.Game = value
+		case "nation":
+			sub.Nation = value
+		case "secret":
+			sub.Secret = value
+		case "turn":
+			turn, err := strconv.Atoi(value)
+			if err != nil || turn < 1 {
+				return fmt.Errorf("header line %d: invalid turn %q", n+1, value)
+			}
+			sub.Turn = turn
+		default:
+			return fmt.Errorf("header line %d: unknown key %q", n+1, key)
+		}
+	}
+	if sub.Game == "" || sub.Nation == "" || sub.Secret == "" {
+		return fmt.Errorf("header: game, nation, and secret are required")
+	}
+
+	orders := &bytes.Buffer{}
+	for _, line := range lines[n:] {
+		orders.WriteString(line + "\n")
+	}
+	sub.Orders = bytes.TrimLeft(orders.Bytes(), "\n")
+	return nil
+}
+
+// textBody returns the decoded plain text body of a message or part.
+func textBody(contentType, encoding string, r io.Reader) ([]byte, error) {
+	mediaType, params := "text/plain", map[string]string{}
+	if contentType != "" {
+		var err error
+		if mediaType, params, err = mime.ParseMediaType(contentType); err != nil {
+			return nil, fmt.Errorf("content-type: %w", err)
+		}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil, ErrNoOrders
+			} else if err != nil {
+				return nil, err
+			}
+			// NextPart has already decoded quoted-printable parts
+			body, err := textBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if errors.Is(err, ErrNoOrders) {
+				continue
+			}
+			return body, err
+		}
+	} else if mediaType != "text/plain" {
+		return nil, ErrNoOrders
+	}
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+	return io.ReadAll(r)
+}
+
+// decodeHeader decodes RFC 2047 encoded words.
+// It returns the raw value if it can't be decoded.
+func decodeHeader(value string) string {
+	if s, err := new(mime.WordDecoder).DecodeHeader(value); err == nil {
+		return s
+	}
+	return value
+}
@@ -0,0 +1,121 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package email implements the play-by-email gateway.
+//
+// Inbound messages carry a nation's orders. The body starts with a
+// short header that identifies the game, nation, and secret, followed
+// by a blank line and the orders:
+//
+//	game: alpha
+//	nation: gondor
+//	secret: swordfish
+//	turn: 3
+//
+//	build scout 1
+//	move S1 42
+//
+// The turn is optional and defaults to the game's next turn.
+// Everything after a signature delimiter ("-- ") is ignored.
+//
+// Outbound messages (replies and turn reports) are rendered as MIME
+// messages and handed to a Sender, which either writes them to an
+// outbox directory or sends them through an SMTP server.
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"strings"
+	"time"
+)
+
+// Message is an outgoing plain-text email message.
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Body    []byte
+	// InReplyTo is the Message-ID of the message being answered, if any.
+	InReplyTo string
+	// Date and MessageId are set by Bytes if they are empty.
+	Date      time.Time
+	MessageId string
+}
+
+// Bytes renders the message as RFC 5322 text with a quoted-printable,
+// UTF-8 body. Lines end with CRLF.
+func (m *Message) Bytes() ([]byte, error) {
+	if m.From == "" || m.To == "" {
+		return nil, fmt.Errorf("email: message needs a sender and a recipient")
+	}
+	if m.Date.IsZero() {
+		m.Date = time.Now()
+	}
+	if m.MessageId == "" {
+		id, err := newMessageId(m.From)
+		if err != nil {
+			return nil, err
+		}
+		m.MessageId = id
+	}
+
+	b := &bytes.Buffer{}
+	header := func(key, value string) {
+		b.WriteString(key + ": " + value + "\r\n")
+	}
+	header("From", m.From)
+	header("To", m.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", m.Date.Format(time.RFC1123Z))
+	header("Message-ID", m.MessageId)
+	if m.InReplyTo != "" {
+		header("In-Reply-To", m.InReplyTo)
+		header("References", m.InReplyTo)
+	}
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(b)
+	body := strings.ReplaceAll(strings.ReplaceAll(string(m.Body), "\r\n", "\n"), "\n", "\r\n")
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return nil, err
+	} else if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// newMessageId returns a random Message-ID in the sender's domain.
+func newMessageId(from string) (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i != -1 {
+		domain = strings.Trim(from[i+1:], "<> ")
+	}
+	return "<" + hex.EncodeToString(buf) + "@" + domain + ">", nil
+}
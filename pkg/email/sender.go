@@ -0,0 +1,97 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package email
+
+import (
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sender delivers outgoing messages.
+type Sender interface {
+	Send(m *Message) error
+}
+
+// Outbox is a Sender that writes each message to a file in a directory.
+// Something else (a cron job, an MTA, a person) is expected to deliver them.
+type Outbox struct {
+	dir string
+}
+
+// NewOutbox returns an outbox that writes to the directory.
+// The directory is created if it doesn't exist.
+func NewOutbox(dir string) (*Outbox, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	return &Outbox{dir: dir}, nil
+}
+
+// Send implements the Sender interface.
+// The message is written to a temporary file and renamed so that
+// readers never see a partial message.
+func (o *Outbox) Send(m *Message) error {
+	data, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+	name := m.Date.UTC().Format("20060102T150405") + "." + strings.Trim(strings.SplitN(m.MessageId, "@", 2)[0], "<") + ".eml"
+	tmp := filepath.Join(o.dir, "."+name)
+	if err = os.WriteFile(tmp, data, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(o.dir, name))
+}
+
+// SMTP is a Sender that delivers messages through an SMTP server.
+type SMTP struct {
+	addr string
+	auth smtp.Auth
+}
+
+// NewSMTP returns a sender for the server.
+// Authentication is used only if the username is not empty.
+func NewSMTP(host, port, username, password string) *SMTP {
+	s := &SMTP{addr: net.JoinHostPort(host, port)}
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+// Send implements the Sender interface.
+func (s *SMTP) Send(m *Message) error {
+	data, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, from.Address, []string{to.Address}, data)
+}
@@ -0,0 +1,96 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package email
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP accepts a single SMTP session on a local port and
+// sends the message data it receives on the channel.
+func fakeSMTP(t *testing.T) (addr string, received chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	received = make(chan string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		_ = tp.PrintfLine("220 localhost fake")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); verb {
+			case "EHLO", "HELO", "MAIL", "RCPT", "RSET", "NOOP":
+				_ = tp.PrintfLine("250 ok")
+			case "DATA":
+				_ = tp.PrintfLine("354 go ahead")
+				data, err := tp.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				received <- string(data)
+				_ = tp.PrintfLine("250 ok")
+			case "QUIT":
+				_ = tp.PrintfLine("221 bye")
+				return
+			default:
+				_ = tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	return l.Addr().String(), received
+}
+
+func TestSMTP(t *testing.T) {
+	addr, received := fakeSMTP(t)
+	host, port, _ := net.SplitHostPort(addr)
+
+	m := &Message{From: "wraith@example.com", To: "Frodo <frodo@example.com>", Subject: "turn 1", Body: []byte("Status\n  industry = 20\n")}
+	if err := NewSMTP(host, port, "", "").Send(m); err != nil {
+		t.Fatalf("send: %v\n", err)
+	}
+	data := <-received
+	tp := textproto.NewReader(bufio.NewReader(strings.NewReader(data)))
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("header: %v\n", err)
+	}
+	if got := header.Get("Subject"); got != "turn 1" {
+		t.Errorf("subject: expected %q: got %q\n", "turn 1", got)
+	}
+	if got := header.Get("Message-Id"); got != m.MessageId {
+		t.Errorf("message-id: expected %q: got %q\n", m.MessageId, got)
+	}
+	if !strings.Contains(data, "industry =3D 20") {
+		t.Errorf("body: expected quoted-printable body: got %q\n", data)
+	}
+}
@@ -0,0 +1,222 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package games implements a registry for the games hosted by a server.
+// The games, along with their clusters, turns, orders, and reports,
+// are kept in a store.Store.
+package games
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/store"
+	"github.com/mdhender/wraithe/pkg/wraith"
+	"golang.org/x/crypto/bcrypt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Status is the state of a game.
+type Status string
+
+const (
+	Setup    Status = "setup"    // game is being created
+	Active   Status = "active"   // game is accepting orders and running turns
+	Paused   Status = "paused"   // game is temporarily not running turns
+	Finished Status = "finished" // game is over
+)
+
+// Game is the metadata for a single game.
+type Game struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Seed int64  `json:"seed"`
+	// Generator is the name of the generator that created the cluster.
+	Generator string    `json:"generator,omitempty"`
+	Turn      int       `json:"turn"`
+	Status    Status    `json:"status"`
+	Created   time.Time `json:"created"`
+	Referees  []string  `json:"referees,omitempty"`  // user ids of the referees
+	Observers []string  `json:"observers,omitempty"` // user ids of the observers
+	// Players are stored as the game's nations, not in the metadata.
+	Players []Player `json:"-"`
+	// Schedule defines when turns are due.
+	Schedule Schedule `json:"schedule"`
+	// Deadline is the moment orders for the next turn are due.
+	// The zero value means there is no deadline.
+	Deadline time.Time `json:"deadline,omitempty"`
+	// LastFailure is set when the scheduler fails to run a turn.
+	LastFailure *Failure `json:"last-failure,omitempty"`
+
+	// progress guards Turn, Status, Schedule, Deadline, and LastFailure,
+	// which the scheduler changes while requests are being served.
+	// Once the game is in a registry, read them with Progress.
+	progress struct {
+		sync.RWMutex
+	}
+
+	// store holds the game's data.
+	// It is set when the game is loaded and is never saved.
+	store store.Store
+
+	// cache holds the cluster and state after they are loaded.
+	cache struct {
+		sync.Mutex
+		cluster *wraith.Cluster
+		state   *wraith.State
+	}
+
+	// submissions serializes reading and writing orders.
+	submissions struct {
+		sync.Mutex
+	}
+}
+
+// Player links a user to the nation they control.
+type Player struct {
+	UserId string `json:"user-id"`
+	Nation string `json:"nation"`
+	// Email is the address the player sends orders from and receives reports at.
+	Email string `json:"email,omitempty"`
+	// HashedSecret is the hash of the secret the player includes with orders sent by email.
+	HashedSecret []byte `json:"hashed-secret,omitempty"`
+}
+
+// SetSecret replaces the player's email secret.
+// Only a salted bcrypt hash of the secret is kept.
+func (p *Player) SetSecret(secret string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	p.HashedSecret = hashed
+	return nil
+}
+
+// CheckSecret returns true if the secret matches the player's email secret.
+// It always returns false if the player doesn't have a secret.
+func (p Player) CheckSecret(secret string) bool {
+	return len(p.HashedSecret) != 0 && bcrypt.CompareHashAndPassword(p.HashedSecret, []byte(secret)) == nil
+}
+
+// Progress is a copy of the game metadata that changes while the game runs.
+type Progress struct {
+	Turn        int
+	Status      Status
+	Schedule    Schedule
+	Deadline    time.Time
+	LastFailure *Failure
+}
+
+// Progress returns a consistent copy of the metadata that changes while
+// the game runs. It is safe to call while the scheduler is running turns.
+func (g *Game) Progress() Progress {
+	g.progress.RLock()
+	defer g.progress.RUnlock()
+	return Progress{Turn: g.Turn, Status: g.Status, Schedule: g.Schedule, Deadline: g.Deadline, LastFailure: g.LastFailure}
+}
+
+// validId restricts game ids to values that are safe to use as directory names.
+var validId = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// Grants returns the authorization grants for the game's users.
+func (g *Game) Grants() map[string][]authz.Grant {
+	grants := make(map[string][]authz.Grant)
+	for _, userId := range g.Referees {
+		grants[userId] = append(grants[userId], authz.Grant{GameId: g.Id, Role: authz.Referee})
+	}
+	for _, userId := range g.Observers {
+		grants[userId] = append(grants[userId], authz.Grant{GameId: g.Id, Role: authz.Observer})
+	}
+	for _, p := range g.Players {
+		grants[p.UserId] = append(grants[p.UserId], authz.Grant{GameId: g.Id, Role: authz.Player, Nation: p.Nation})
+	}
+	return grants
+}
+
+// HasNation returns true if a player controls the nation.
+func (g *Game) HasNation(nation string) bool {
+	_, ok := g.Player(nation)
+	return ok
+}
+
+// SetPlayer adds the player to the game, replacing any player
+// that controls the same nation. The caller must save the game.
+func (g *Game) SetPlayer(p Player) {
+	for i := range g.Players {
+		if g.Players[i].Nation == p.Nation {
+			g.Players[i] = p
+			return
+		}
+	}
+	g.Players = append(g.Players, p)
+}
+
+// Player returns the player that controls the nation.
+func (g *Game) Player(nation string) (Player, bool) {
+	for _, p := range g.Players {
+		if p.Nation == nation {
+			return p, true
+		}
+	}
+	return Player{}, false
+}
+
+// read loads the game metadata and players from the store.
+func read(st store.Store, id string) (*Game, error) {
+	data, err := st.GetGame(id)
+	if err != nil {
+		return nil, err
+	}
+	var g Game
+	if err = json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	} else if g.Id != id || !validId.MatchString(g.Id) {
+		return nil, fmt.Errorf("%s: invalid game id %q", id, g.Id)
+	}
+	nations, err := st.ListNations(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nations {
+		g.Players = append(g.Players, Player{UserId: n.UserId, Nation: n.Id, Email: n.Email, HashedSecret: n.HashedSecret})
+	}
+	g.store = st
+	return &g, nil
+}
+
+// write saves the game metadata and players to the store.
+func (g *Game) write() error {
+	g.progress.RLock()
+	data, err := json.MarshalIndent(g, "", "  ")
+	g.progress.RUnlock()
+	if err != nil {
+		return err
+	}
+	if err = g.store.PutGame(g.Id, data); err != nil {
+		return err
+	}
+	for _, p := range g.Players {
+		if err = g.store.PutNation(g.Id, store.Nation{Id: p.Nation, UserId: p.UserId, Email: p.Email, HashedSecret: p.HashedSecret}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
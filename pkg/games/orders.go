@@ -0,0 +1,84 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package games
+
+import (
+	"errors"
+	"github.com/mdhender/wraithe/pkg/store"
+	"time"
+)
+
+var (
+	// ErrNoOrders is returned when a nation hasn't submitted orders.
+	ErrNoOrders = errors.New("no orders submitted")
+	// ErrOrdersClosed is returned when the game isn't accepting orders.
+	ErrOrdersClosed = errors.New("orders are closed")
+	// ErrUnknownNation is returned when no player controls the nation.
+	ErrUnknownNation = errors.New("unknown nation")
+	// ErrWrongTurn is returned when orders are for a turn that isn't next.
+	ErrWrongTurn = errors.New("orders are not for the next turn")
+)
+
+// Orders are stored as plain text.
+// Every submission is kept as a new version.
+// The highest version is the current set of orders.
+
+// AcceptingOrders returns an error if the game won't accept orders
+// from the nation for the turn at the given time.
+// Orders are accepted only for the next turn and only until the deadline.
+func (g *Game) AcceptingOrders(turn int, nation string, now time.Time) error {
+	p := g.Progress()
+	if p.Status == Finished || p.Status == Paused {
+		return ErrOrdersClosed
+	} else if turn != p.Turn+1 {
+		return ErrWrongTurn
+	} else if !g.HasNation(nation) {
+		return ErrUnknownNation
+	} else if !p.Deadline.IsZero() && !now.Before(p.Deadline) {
+		return ErrOrdersClosed
+	}
+	return nil
+}
+
+// SubmitOrders saves a new version of the nation's orders for the turn.
+// The caller is responsible for parsing the orders before submitting them.
+// It returns the version number of the new orders.
+func (g *Game) SubmitOrders(turn int, nation string, data []byte, now time.Time) (int, error) {
+	g.submissions.Lock()
+	defer g.submissions.Unlock()
+
+	if err := g.AcceptingOrders(turn, nation, now); err != nil {
+		return 0, err
+	}
+
+	return g.store.PutOrders(g.Id, turn, nation, data)
+}
+
+// Orders returns the current version of the nation's orders for the turn.
+// It returns ErrNoOrders if the nation hasn't submitted any.
+func (g *Game) Orders(turn int, nation string) (data []byte, version int, err error) {
+	g.submissions.Lock()
+	defer g.submissions.Unlock()
+
+	data, version, err = g.store.GetOrders(g.Id, turn, nation)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, 0, ErrNoOrders
+	}
+	return data, version, err
+}
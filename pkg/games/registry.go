@@ -0,0 +1,154 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package games
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/authz"
+	"github.com/mdhender/wraithe/pkg/store"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when the registry doesn't have the game.
+var ErrNotFound = errors.New("game not found")
+
+// Registry is the set of games hosted by a server.
+type Registry struct {
+	store   store.Store
+	private struct {
+		sync.RWMutex
+	}
+	// games stores all games.
+	// The map key is Game.Id.
+	games map[string]*Game
+}
+
+// Open returns a registry containing every game in the store.
+func Open(st store.Store) (*Registry, error) {
+	r := &Registry{store: st, games: make(map[string]*Game)}
+
+	ids, err := st.ListGames()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		g, err := read(st, id)
+		if err != nil {
+			return nil, err
+		}
+		r.games[g.Id] = g
+	}
+
+	return r, nil
+}
+
+// Load returns a registry backed by a file store in the root directory.
+// The root directory is created if it doesn't exist.
+func Load(root string) (*Registry, error) {
+	st, err := store.NewFileStore(root)
+	if err != nil {
+		return nil, err
+	}
+	return Open(st)
+}
+
+// Store returns the store that holds the games.
+func (r *Registry) Store() store.Store {
+	return r.store
+}
+
+// Create adds a new game to the registry and saves the metadata.
+func (r *Registry) Create(g *Game) error {
+	if !validId.MatchString(g.Id) {
+		return fmt.Errorf("invalid game id %q", g.Id)
+	}
+
+	r.private.Lock()
+	defer r.private.Unlock()
+
+	if _, ok := r.games[g.Id]; ok {
+		return fmt.Errorf("game %q: already exists", g.Id)
+	}
+
+	g.store = r.store
+	if g.Status == "" {
+		g.Status = Setup
+	}
+	if g.Created.IsZero() {
+		g.Created = time.Now().UTC()
+	}
+	if err := g.write(); err != nil {
+		return err
+	}
+	r.games[g.Id] = g
+
+	return nil
+}
+
+// Get returns the game with the given id.
+func (r *Registry) Get(id string) (*Game, error) {
+	r.private.RLock()
+	defer r.private.RUnlock()
+
+	g, ok := r.games[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return g, nil
+}
+
+// List returns all games, sorted by id.
+func (r *Registry) List() []*Game {
+	r.private.RLock()
+	defer r.private.RUnlock()
+
+	var list []*Game
+	for _, g := range r.games {
+		list = append(list, g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Id < list[j].Id
+	})
+	return list
+}
+
+// Save writes the game's metadata to the store.
+func (r *Registry) Save(g *Game) error {
+	r.private.Lock()
+	defer r.private.Unlock()
+
+	if _, ok := r.games[g.Id]; !ok {
+		return ErrNotFound
+	}
+	return g.write()
+}
+
+// Authorize adds the grants for every game to the authorizer.
+func (r *Registry) Authorize(a *authz.Authorizer) {
+	for _, g := range r.List() {
+		for userId, grants := range g.Grants() {
+			for _, grant := range grants {
+				a.Grant(userId, grant)
+			}
+		}
+	}
+}
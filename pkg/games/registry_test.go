@@ -0,0 +1,92 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package games
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	root := t.TempDir()
+
+	reg, err := Load(root)
+	if err != nil {
+		t.Fatalf("load: %v\n", err)
+	}
+	for _, id := range []string{"beta", "alpha"} {
+		if err := reg.Create(&Game{Id: id, Seed: 42, Players: []Player{{UserId: "u1", Nation: "n1"}}}); err != nil {
+			t.Fatalf("create %q: %v\n", id, err)
+		}
+	}
+	if err := reg.Create(&Game{Id: "alpha"}); err == nil {
+		t.Errorf("create duplicate: expected error: got nil\n")
+	}
+	if err := reg.Create(&Game{Id: "../escape"}); err == nil {
+		t.Errorf("create invalid id: expected error: got nil\n")
+	}
+
+	// a fresh registry should find the games on disk
+	reg, err = Load(root)
+	if err != nil {
+		t.Fatalf("reload: %v\n", err)
+	}
+	list := reg.List()
+	if len(list) != 2 || list[0].Id != "alpha" || list[1].Id != "beta" {
+		t.Fatalf("list: expected [alpha beta]: got %d games\n", len(list))
+	}
+	g, err := reg.Get("alpha")
+	if err != nil {
+		t.Fatalf("get: %v\n", err)
+	} else if g.Seed != 42 || g.Status != Setup || !g.HasNation("n1") {
+		t.Errorf("get: expected seed 42, status setup, nation n1: got %d %q %v\n", g.Seed, g.Status, g.HasNation("n1"))
+	}
+	if _, err := reg.Get("gamma"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get missing: expected ErrNotFound: got %v\n", err)
+	}
+}
+
+func TestPlayerSecret(t *testing.T) {
+	var p, q Player
+	if p.CheckSecret("") {
+		t.Errorf("no secret: expected false: got true\n")
+	}
+	if err := p.SetSecret("swordfish"); err != nil {
+		t.Fatal(err)
+	} else if err = q.SetSecret("swordfish"); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(p.HashedSecret, q.HashedSecret) {
+		t.Errorf("salt: expected different hashes for the same secret\n")
+	}
+	for _, tc := range []struct {
+		id     int
+		secret string
+		expect bool
+	}{
+		{1, "swordfish", true},
+		{2, "Swordfish", false},
+		{3, "", false},
+	} {
+		if got := p.CheckSecret(tc.secret); got != tc.expect {
+			t.Errorf("%d: check %q: expected %v: got %v\n", tc.id, tc.secret, tc.expect, got)
+		}
+	}
+}
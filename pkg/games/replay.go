@@ -0,0 +1,96 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package games
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/store"
+	"sort"
+)
+
+// ErrMismatch is returned when a replayed turn doesn't match the stored turn.
+var ErrMismatch = errors.New("replay does not match")
+
+// Replay re-runs the turn from the previous turn's snapshot, the stored
+// orders, and the turn's seed. It returns ErrMismatch if the new state
+// or any report differs from what was stored when the turn was run.
+// A mismatch means that the engine isn't deterministic.
+func (g *Game) Replay(turn int) error {
+	if last := g.Progress().Turn; turn < 1 || turn > last {
+		return fmt.Errorf("turn %d: must be between 1 and %d", turn, last)
+	}
+	prev, err := g.Snapshot(turn - 1)
+	if err != nil {
+		return fmt.Errorf("turn %d: snapshot: %w", turn-1, err)
+	}
+	next, reports, err := g.process(turn, prev)
+	if err != nil {
+		return err
+	}
+
+	stored, err := g.store.GetTurn(g.Id, turn)
+	if err != nil {
+		return fmt.Errorf("turn %d: snapshot: %w", turn, err)
+	}
+	replayed, err := encodeState(next)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(stored, replayed) {
+		return fmt.Errorf("turn %d: state: %w: %s", turn, ErrMismatch, firstDifference(stored, replayed))
+	}
+
+	var nations []string
+	for nation := range reports {
+		nations = append(nations, nation)
+	}
+	sort.Strings(nations)
+	for _, nation := range nations {
+		stored, err := g.store.GetReport(g.Id, turn, nation)
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("turn %d: report %q: %w: report is missing", turn, nation, ErrMismatch)
+		} else if err != nil {
+			return err
+		}
+		if replayed := reports[nation].Text(); !bytes.Equal(stored, replayed) {
+			return fmt.Errorf("turn %d: report %q: %w: %s", turn, nation, ErrMismatch, firstDifference(stored, replayed))
+		}
+	}
+	return nil
+}
+
+// firstDifference describes the first line that differs between a and b.
+func firstDifference(a, b []byte) string {
+	al, bl := bytes.Split(a, []byte{'\n'}), bytes.Split(b, []byte{'\n'})
+	for i := 0; i < len(al) || i < len(bl); i++ {
+		var x, y []byte
+		if i < len(al) {
+			x = al[i]
+		}
+		if i < len(bl) {
+			y = bl[i]
+		}
+		if !bytes.Equal(x, y) {
+			return fmt.Sprintf("line %d: stored %q: replayed %q", i+1, bytes.TrimSpace(x), bytes.TrimSpace(y))
+		}
+	}
+	return "no difference"
+}
@@ -0,0 +1,95 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package games
+
+import (
+	"errors"
+	"github.com/mdhender/wraithe/pkg/wraith"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	reg, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Game{
+		Id:      "g1",
+		Seed:    11,
+		Status:  Active,
+		Players: []Player{{UserId: "p1", Nation: "n1"}, {UserId: "p2", Nation: "n2"}},
+	}
+	if err = reg.Create(g); err != nil {
+		t.Fatal(err)
+	}
+	rand.Seed(g.Seed)
+	c := wraith.G(2, 64, 32, 15.0)
+	state, err := wraith.NewState(c, "n1", "n2")
+	if err != nil {
+		t.Fatal(err)
+	} else if err = g.SaveCluster(c); err != nil {
+		t.Fatal(err)
+	} else if err = g.SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2022, 8, 3, 12, 0, 0, 0, time.UTC)
+	for turn, list := range [][2]string{
+		{"build scout 2\n", "name S3 Explorer\n"},
+		{"move S1 1\n", "build transport 1\n"},
+	} {
+		for i, nation := range []string{"n1", "n2"} {
+			if _, err = g.SubmitOrders(turn+1, nation, []byte(list[i]), now); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = g.RunTurn(now); err != nil {
+			t.Fatalf("turn %d: %v\n", turn+1, err)
+		}
+	}
+
+	for turn := 1; turn <= g.Turn; turn++ {
+		if err = g.Replay(turn); err != nil {
+			t.Errorf("replay %d: %v\n", turn, err)
+		}
+	}
+	if err = g.Replay(3); err == nil {
+		t.Errorf("replay 3: expected error: got nil\n")
+	}
+
+	// snapshots can't be replaced
+	s, err := g.Snapshot(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Nations["n1"].Industry++
+	if err = g.SaveState(s); err == nil {
+		t.Errorf("save snapshot: expected error: got nil\n")
+	}
+
+	// reports can be, so tampering with one must be caught
+	if err = g.store.PutReport(g.Id, 2, "n2", []byte("tampered\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err = g.Replay(2); !errors.Is(err, ErrMismatch) {
+		t.Errorf("replay tampered: expected ErrMismatch: got %v\n", err)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package games
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule defines when a game's turns are due.
+// All times are UTC.
+//
+// Examples:
+//
+//	{"weekday": "monday", "time": "18:00"}  every Monday at 18:00
+//	{"time": "06:00"}                       every day at 06:00
+//	{"when-all-submitted": true}            as soon as every nation has submitted orders
+//
+// A deadline and when-all-submitted may be combined; the turn runs
+// at whichever comes first.
+type Schedule struct {
+	Weekday          string `json:"weekday,omitempty"`
+	Time             string `json:"time,omitempty"`
+	WhenAllSubmitted bool   `json:"when-all-submitted,omitempty"`
+}
+
+// Next returns the first deadline after the given moment.
+// It returns the zero time if the schedule has no deadlines.
+func (s Schedule) Next(after time.Time) (time.Time, error) {
+	if s.Time == "" {
+		if s.Weekday != "" {
+			return time.Time{}, fmt.Errorf("schedule: weekday %q: missing time", s.Weekday)
+		}
+		return time.Time{}, nil
+	}
+	hm, err := time.Parse("15:04", s.Time)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("schedule: time %q: must be HH:MM", s.Time)
+	}
+
+	after = after.UTC()
+	next := time.Date(after.Year(), after.Month(), after.Day(), hm.Hour(), hm.Minute(), 0, 0, time.UTC)
+	if !next.After(after) {
+		next = next.AddDate(0, 0, 1)
+	}
+	if s.Weekday == "" {
+		return next, nil
+	}
+
+	weekday := -1
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s.Weekday, d.String()) {
+			weekday = int(d)
+		}
+	}
+	if weekday < 0 {
+		return time.Time{}, fmt.Errorf("schedule: weekday %q: unknown day", s.Weekday)
+	}
+	for int(next.Weekday()) != weekday {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next, nil
+}
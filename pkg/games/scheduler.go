@@ -0,0 +1,159 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package games
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+var (
+	// ErrActive is returned when activating a game that is already active.
+	ErrActive = errors.New("game is already active")
+	// ErrFinished is returned when changing a game that has finished.
+	ErrFinished = errors.New("game is finished")
+)
+
+// Activate lets the scheduler run turns for the game.
+// It starts a game that is being set up and resumes a game that the
+// scheduler paused. The caller must save the game.
+func (g *Game) Activate() error {
+	g.progress.Lock()
+	defer g.progress.Unlock()
+	switch g.Status {
+	case Active:
+		return ErrActive
+	case Finished:
+		return ErrFinished
+	}
+	g.Status = Active
+	return nil
+}
+
+// SetSchedule replaces the game's schedule.
+// The deadline is cleared so that the scheduler sets a new one from the
+// schedule on its next check. The caller must save the game.
+func (g *Game) SetSchedule(s Schedule) error {
+	if _, err := s.Next(time.Now()); err != nil {
+		return err
+	}
+	g.progress.Lock()
+	defer g.progress.Unlock()
+	if g.Status == Finished {
+		return ErrFinished
+	}
+	g.Schedule, g.Deadline = s, time.Time{}
+	return nil
+}
+
+// Scheduler runs turns for active games when they are due.
+type Scheduler struct {
+	reg      *Registry
+	interval time.Duration
+}
+
+// NewScheduler returns a scheduler that checks the games in the
+// registry every interval.
+func NewScheduler(reg *Registry, interval time.Duration) *Scheduler {
+	return &Scheduler{reg: reg, interval: interval}
+}
+
+// Run checks the games until the context is cancelled.
+// A turn that has started is always allowed to finish.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.Tick(now.UTC())
+		}
+	}
+}
+
+// Tick checks every active game and runs the turns that are due.
+func (s *Scheduler) Tick(now time.Time) {
+	for _, g := range s.reg.List() {
+		if g.Progress().Status == Active {
+			s.check(g, now)
+		}
+	}
+}
+
+// check runs the game's turn if it is due.
+// Failures (including panics) are recorded in the game, which is then
+// paused so that a referee can investigate.
+func (s *Scheduler) check(g *Game, now time.Time) {
+	p := g.Progress()
+	turn := p.Turn + 1
+	defer func() {
+		if r := recover(); r != nil {
+			s.fail(g, turn, now, fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	// set the first deadline for games that don't have one yet
+	if p.Deadline.IsZero() {
+		deadline, err := p.Schedule.Next(now)
+		if err != nil {
+			s.fail(g, turn, now, err)
+			return
+		} else if !deadline.IsZero() {
+			g.progress.Lock()
+			g.Deadline = deadline
+			g.progress.Unlock()
+			p.Deadline = deadline
+			if err := s.reg.Save(g); err != nil {
+				log.Printf("[scheduler] game %q: %v\n", g.Id, err)
+			}
+		}
+	}
+
+	due := !p.Deadline.IsZero() && !now.Before(p.Deadline)
+	if !due && p.Schedule.WhenAllSubmitted {
+		due = g.AllSubmitted(turn)
+	}
+	if !due {
+		return
+	}
+
+	log.Printf("[scheduler] game %q: running turn %d\n", g.Id, turn)
+	if err := g.RunTurn(now); err != nil {
+		s.fail(g, turn, now, err)
+		return
+	}
+	log.Printf("[scheduler] game %q: completed turn %d\n", g.Id, turn)
+}
+
+// fail records the failure and pauses the game.
+func (s *Scheduler) fail(g *Game, turn int, now time.Time, err error) {
+	log.Printf("[scheduler] game %q: turn %d: %v\n", g.Id, turn, err)
+	g.progress.Lock()
+	g.Status = Paused
+	g.LastFailure = &Failure{Turn: turn, At: now, Error: err.Error()}
+	g.progress.Unlock()
+	if err := s.reg.Save(g); err != nil {
+		log.Printf("[scheduler] game %q: %v\n", g.Id, err)
+	}
+}
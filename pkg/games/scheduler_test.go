@@ -0,0 +1,319 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package games
+
+import (
+	"github.com/mdhender/wraithe/pkg/wraith"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// 2022-08-03 is a Wednesday
+	now := time.Date(2022, 8, 3, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		id     int
+		s      Schedule
+		expect time.Time
+	}{
+		{1, Schedule{}, time.Time{}},
+		{2, Schedule{Time: "18:00"}, time.Date(2022, 8, 3, 18, 0, 0, 0, time.UTC)},
+		{3, Schedule{Time: "06:00"}, time.Date(2022, 8, 4, 6, 0, 0, 0, time.UTC)},
+		{4, Schedule{Weekday: "Monday", Time: "18:00"}, time.Date(2022, 8, 8, 18, 0, 0, 0, time.UTC)},
+		{5, Schedule{Weekday: "wednesday", Time: "12:00"}, time.Date(2022, 8, 10, 12, 0, 0, 0, time.UTC)},
+	} {
+		got, err := tc.s.Next(now)
+		if err != nil {
+			t.Errorf("%d: unexpected error %v\n", tc.id, err)
+		} else if !got.Equal(tc.expect) {
+			t.Errorf("%d: expected %v: got %v\n", tc.id, tc.expect, got)
+		}
+	}
+	if _, err := (Schedule{Weekday: "someday", Time: "18:00"}).Next(now); err == nil {
+		t.Errorf("unknown weekday: expected error: got nil\n")
+	}
+}
+
+func TestSchedulerTick(t *testing.T) {
+	reg, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Game{
+		Id:       "g1",
+		Seed:     7,
+		Status:   Active,
+		Schedule: Schedule{WhenAllSubmitted: true},
+		Players:  []Player{{UserId: "p1", Nation: "n1"}, {UserId: "p2", Nation: "n2"}},
+	}
+	if err = reg.Create(g); err != nil {
+		t.Fatal(err)
+	}
+	rand.Seed(g.Seed)
+	c := wraith.G(2, 64, 32, 15.0)
+	state, err := wraith.NewState(c, "n1", "n2")
+	if err != nil {
+		t.Fatal(err)
+	} else if err = g.SaveCluster(c); err != nil {
+		t.Fatal(err)
+	} else if err = g.SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2022, 8, 3, 12, 0, 0, 0, time.UTC)
+	s := NewScheduler(reg, time.Minute)
+
+	// the turn must wait until every nation has submitted orders
+	if _, err = g.SubmitOrders(1, "n1", []byte("build scout 1\n"), now); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick(now)
+	if g.Turn != 0 {
+		t.Fatalf("tick: expected turn 0: got %d\n", g.Turn)
+	}
+	if _, err = g.SubmitOrders(1, "n2", []byte("name S3 Explorer\n"), now); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick(now)
+	if g.Turn != 1 || g.Status != Active || g.LastFailure != nil {
+		t.Fatalf("tick: expected turn 1: got %d %q %+v\n", g.Turn, g.Status, g.LastFailure)
+	}
+	if _, err = g.Report(1, "n1"); err != nil {
+		t.Errorf("report: %v\n", err)
+	}
+	if _, err = g.SubmitOrders(1, "n1", []byte("build scout 1\n"), now); err != ErrWrongTurn {
+		t.Errorf("submit: expected ErrWrongTurn: got %v\n", err)
+	}
+
+	// a turn that fails pauses the game
+	g.Schedule = Schedule{Time: "bad"}
+	g.Deadline = time.Time{}
+	s.Tick(now)
+	if g.Status != Paused || g.LastFailure == nil {
+		t.Errorf("failure: expected paused game: got %q %+v\n", g.Status, g.LastFailure)
+	}
+
+	// the referee fixes the schedule and resumes the game
+	if err = g.SetSchedule(Schedule{WhenAllSubmitted: true}); err != nil {
+		t.Fatal(err)
+	} else if err = g.Activate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, nation := range []string{"n1", "n2"} {
+		if _, err = g.SubmitOrders(2, nation, []byte("\n"), now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Tick(now)
+	if g.Turn != 2 || g.Status != Active || g.LastFailure != nil {
+		t.Errorf("resume: expected turn 2: got %d %q %+v\n", g.Turn, g.Status, g.LastFailure)
+	}
+	if err = g.Activate(); err != ErrActive {
+		t.Errorf("activate: expected ErrActive: got %v\n", err)
+	}
+}
+
+// TestSchedulerLifecycle follows a game from creation to its first turn.
+// New games are not run until the referee activates them.
+func TestSchedulerLifecycle(t *testing.T) {
+	root := t.TempDir()
+	reg, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Game{Id: "g1", Seed: 7, Players: []Player{{UserId: "p1", Nation: "n1"}, {UserId: "p2", Nation: "n2"}}}
+	if err = reg.Create(g); err != nil {
+		t.Fatal(err)
+	}
+	rand.Seed(g.Seed)
+	c := wraith.G(2, 64, 32, 15.0)
+	state, err := wraith.NewState(c, "n1", "n2")
+	if err != nil {
+		t.Fatal(err)
+	} else if err = g.SaveCluster(c); err != nil {
+		t.Fatal(err)
+	} else if err = g.SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2022, 8, 3, 12, 0, 0, 0, time.UTC)
+	s := NewScheduler(reg, time.Minute)
+
+	// a game in setup is never run, even when its deadline has passed
+	if err = g.SetSchedule(Schedule{Time: "18:00"}); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick(now.Add(24 * time.Hour))
+	if p := g.Progress(); p.Status != Setup || p.Turn != 0 || !p.Deadline.IsZero() {
+		t.Fatalf("setup: expected turn 0 in setup: got %d %q %v\n", p.Turn, p.Status, p.Deadline)
+	}
+
+	// activating the game lets the scheduler set the deadline
+	if err = g.Activate(); err != nil {
+		t.Fatal(err)
+	} else if err = reg.Save(g); err != nil {
+		t.Fatal(err)
+	}
+	s.Tick(now)
+	expect := time.Date(2022, 8, 3, 18, 0, 0, 0, time.UTC)
+	if p := g.Progress(); p.Status != Active || p.Turn != 0 || !p.Deadline.Equal(expect) {
+		t.Fatalf("activate: expected deadline %v: got %d %q %v\n", expect, p.Turn, p.Status, p.Deadline)
+	}
+
+	// the turn runs once the deadline passes
+	s.Tick(expect)
+	if p := g.Progress(); p.Turn != 1 || p.Status != Active || p.LastFailure != nil {
+		t.Fatalf("deadline: expected turn 1: got %d %q %+v\n", p.Turn, p.Status, p.LastFailure)
+	}
+	if _, err = g.Report(1, "n1"); err != nil {
+		t.Errorf("report: %v\n", err)
+	}
+
+	// the activation and the new turn were saved
+	reg, err = Load(root)
+	if err != nil {
+		t.Fatal(err)
+	} else if g, err = reg.Get("g1"); err != nil {
+		t.Fatal(err)
+	} else if p := g.Progress(); p.Turn != 1 || p.Status != Active {
+		t.Errorf("reload: expected turn 1 active: got %d %q\n", p.Turn, p.Status)
+	}
+}
+
+// TestSchedulerConcurrentReads runs turns while other goroutines read the
+// game, the way the REST handlers do. Run it with -race.
+func TestSchedulerConcurrentReads(t *testing.T) {
+	reg, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Game{
+		Id:       "g1",
+		Seed:     7,
+		Status:   Active,
+		Schedule: Schedule{WhenAllSubmitted: true},
+		Players:  []Player{{UserId: "p1", Nation: "n1"}, {UserId: "p2", Nation: "n2"}},
+	}
+	if err = reg.Create(g); err != nil {
+		t.Fatal(err)
+	}
+	rand.Seed(g.Seed)
+	c := wraith.G(2, 64, 32, 15.0)
+	state, err := wraith.NewState(c, "n1", "n2")
+	if err != nil {
+		t.Fatal(err)
+	} else if err = g.SaveCluster(c); err != nil {
+		t.Fatal(err)
+	} else if err = g.SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2022, 8, 3, 12, 0, 0, 0, time.UTC)
+	s := NewScheduler(reg, time.Minute)
+
+	const turns = 3
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for turn := 1; turn <= turns; turn++ {
+			for _, nation := range []string{"n1", "n2"} {
+				if _, err := g.SubmitOrders(turn, nation, []byte("\n"), now); err != nil {
+					t.Errorf("submit: turn %d: %s: %v\n", turn, nation, err)
+					return
+				}
+			}
+			s.Tick(now)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				p := g.Progress()
+				_ = g.AcceptingOrders(p.Turn+1, "n1", now)
+				if _, err := g.State(); err != nil {
+					t.Errorf("state: %v\n", err)
+					return
+				}
+				for _, gg := range reg.List() {
+					_ = gg.Progress()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if p := g.Progress(); p.Turn != turns || p.Status != Active {
+		t.Errorf("tick: expected turn %d active: got %d %q\n", turns, p.Turn, p.Status)
+	}
+}
+
+// TestAllSubmitted checks that the scheduler waits for the same nations
+// whose orders the turn uses: those in the state that have a player.
+func TestAllSubmitted(t *testing.T) {
+	reg, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// n3 has a player but no nation in the state, and n4 is the reverse
+	g := &Game{Id: "g1", Seed: 7, Status: Active, Players: []Player{{UserId: "p1", Nation: "n1"}, {UserId: "p2", Nation: "n2"}, {UserId: "p3", Nation: "n3"}}}
+	if err = reg.Create(g); err != nil {
+		t.Fatal(err)
+	}
+	rand.Seed(g.Seed)
+	c := wraith.G(3, 64, 32, 15.0)
+	state, err := wraith.NewState(c, "n1", "n2", "n4")
+	if err != nil {
+		t.Fatal(err)
+	} else if err = g.SaveCluster(c); err != nil {
+		t.Fatal(err)
+	} else if err = g.SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+	if got := g.playing(state); len(got) != 2 || got[0] != "n1" || got[1] != "n2" {
+		t.Errorf("playing: expected [n1 n2]: got %v\n", got)
+	}
+
+	now := time.Date(2022, 8, 3, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		id     int
+		nation string
+		expect bool
+	}{
+		{1, "n1", false},
+		{2, "n2", true},
+	} {
+		if _, err = g.SubmitOrders(1, tc.nation, []byte("\n"), now); err != nil {
+			t.Fatalf("%d: submit: %v\n", tc.id, err)
+		}
+		if got := g.AllSubmitted(1); got != tc.expect {
+			t.Errorf("%d: all submitted: expected %v: got %v\n", tc.id, tc.expect, got)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package games
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/orders"
+	"github.com/mdhender/wraithe/pkg/store"
+	"github.com/mdhender/wraithe/pkg/wraith"
+	"sort"
+	"time"
+)
+
+// ErrNoReport is returned when a report doesn't exist.
+var ErrNoReport = errors.New("no report")
+
+// Failure records a turn that could not be processed.
+type Failure struct {
+	Turn  int       `json:"turn"`
+	At    time.Time `json:"at"`
+	Error string    `json:"error"`
+}
+
+// TurnSeed returns the seed for processing a turn.
+// It is derived from the game's seed so that turns can be replayed.
+func TurnSeed(seed int64, turn int) int64 {
+	return int64(uint64(seed) ^ uint64(turn)*0x9E3779B97F4A7C15)
+}
+
+// AllSubmitted returns true if every nation that plays the turn (see
+// playing) has submitted orders for it.
+func (g *Game) AllSubmitted(turn int) bool {
+	g.submissions.Lock()
+	defer g.submissions.Unlock()
+
+	s, err := g.State()
+	if err != nil {
+		return false
+	}
+	nations := g.playing(s)
+	if len(nations) == 0 {
+		return false
+	}
+	for _, nation := range nations {
+		if _, _, err := g.store.GetOrders(g.Id, turn, nation); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// playing returns the nations in the state that have a player, sorted.
+// Only players submit orders, so these are the nations whose orders
+// the turn uses.
+func (g *Game) playing(s *wraith.State) []string {
+	var nations []string
+	for nation := range s.Nations {
+		if g.HasNation(nation) {
+			nations = append(nations, nation)
+		}
+	}
+	sort.Strings(nations)
+	return nations
+}
+
+// RunTurn processes the next turn.
+// Order submission is locked while the turn runs. When it finishes,
+// the reports and the snapshot of the new state are saved, the game's
+// turn is advanced, and the deadline is set from the schedule.
+func (g *Game) RunTurn(now time.Time) error {
+	g.submissions.Lock()
+	defer g.submissions.Unlock()
+
+	p := g.Progress()
+	turn := p.Turn + 1
+
+	deadline, err := p.Schedule.Next(now)
+	if err != nil {
+		return err
+	}
+	s, err := g.State()
+	if err != nil {
+		return err
+	}
+	next, reports, err := g.process(turn, s)
+	if err != nil {
+		return err
+	}
+
+	for nation, r := range reports {
+		if err := g.store.PutReport(g.Id, turn, nation, r.Text()); err != nil {
+			return err
+		}
+	}
+	if err := g.SaveState(next); err != nil {
+		return err
+	}
+
+	g.progress.Lock()
+	g.Turn, g.Deadline, g.LastFailure = turn, deadline, nil
+	g.progress.Unlock()
+	return g.write()
+}
+
+// process runs the turn against the previous turn's state using the
+// stored orders and the turn's seed. It doesn't save anything.
+func (g *Game) process(turn int, prev *wraith.State) (*wraith.State, map[string]*wraith.Report, error) {
+	c, err := g.Cluster()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// orders were parsed when they were submitted, so errors here mean
+	// that someone has been editing the store by hand.
+	ordersByNation := make(map[string][]*orders.Order)
+	for _, nation := range g.playing(prev) {
+		data, _, err := g.store.GetOrders(g.Id, turn, nation)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		list, errs := orders.Parse(data)
+		if len(errs) != 0 {
+			return nil, nil, fmt.Errorf("turn %d: nation %q: %w", turn, nation, errs[0])
+		}
+		ordersByNation[nation] = list
+	}
+
+	next, reports := wraith.Process(c, prev, ordersByNation, TurnSeed(g.Seed, turn))
+	return next, reports, nil
+}
+
+// Report returns the nation's report for the turn.
+func (g *Game) Report(turn int, nation string) ([]byte, error) {
+	data, err := g.store.GetReport(g.Id, turn, nation)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNoReport
+	}
+	return data, err
+}
@@ -0,0 +1,116 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package games
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/store"
+	"github.com/mdhender/wraithe/pkg/wraith"
+)
+
+// Cluster returns the game's cluster.
+// It is loaded from the store the first time it is requested.
+func (g *Game) Cluster() (*wraith.Cluster, error) {
+	g.cache.Lock()
+	defer g.cache.Unlock()
+	if g.cache.cluster == nil {
+		data, err := g.store.GetCluster(g.Id)
+		if err != nil {
+			return nil, err
+		}
+		var c wraith.Cluster
+		if err = json.Unmarshal(data, &c); err != nil {
+			return nil, err
+		}
+		g.cache.cluster = &c
+	}
+	return g.cache.cluster, nil
+}
+
+// SaveCluster writes the cluster to the store.
+func (g *Game) SaveCluster(c *wraith.Cluster) error {
+	g.cache.Lock()
+	defer g.cache.Unlock()
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = g.store.PutCluster(g.Id, data); err != nil {
+		return err
+	}
+	g.cache.cluster = c
+	return nil
+}
+
+// State returns the current state of the game,
+// which is the snapshot at the end of the current turn.
+// It is loaded from the store the first time it is requested.
+func (g *Game) State() (*wraith.State, error) {
+	turn := g.Progress().Turn
+	g.cache.Lock()
+	defer g.cache.Unlock()
+	if g.cache.state == nil || g.cache.state.Turn != turn {
+		s, err := g.Snapshot(turn)
+		if err != nil {
+			return nil, err
+		}
+		g.cache.state = s
+	}
+	return g.cache.state, nil
+}
+
+// Snapshot returns the state of the game at the end of the turn.
+// Turn zero is the state at the start of the game.
+func (g *Game) Snapshot(turn int) (*wraith.State, error) {
+	data, err := g.store.GetTurn(g.Id, turn)
+	if err != nil {
+		return nil, err
+	}
+	return wraith.DecodeState(data)
+}
+
+// SaveState writes the state to the store as the snapshot for its turn.
+// Snapshots are immutable, so saving a state that differs from the
+// existing snapshot returns store.ErrExists. Saving an identical state
+// is allowed so that a turn that failed after saving can be re-run.
+func (g *Game) SaveState(s *wraith.State) error {
+	g.cache.Lock()
+	defer g.cache.Unlock()
+	data, err := encodeState(s)
+	if err != nil {
+		return err
+	}
+	if err = g.store.PutTurn(g.Id, s.Turn, data); errors.Is(err, store.ErrExists) {
+		if prev, _ := g.store.GetTurn(g.Id, s.Turn); !bytes.Equal(prev, data) {
+			return fmt.Errorf("turn %d: %w", s.Turn, err)
+		}
+	} else if err != nil {
+		return err
+	}
+	g.cache.state = s
+	return nil
+}
+
+// encodeState returns the stored form of the state.
+func encodeState(s *wraith.State) ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
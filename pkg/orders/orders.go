@@ -0,0 +1,207 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package orders implements the parser for a nation's orders.
+//
+// Orders are plain text, one order per line.
+// Each order starts with a verb followed by its arguments,
+// separated by spaces. Arguments containing spaces must be quoted.
+// Blank lines are ignored, as is anything following a semicolon.
+//
+//	; move the scouts to system 42 and to Kessa
+//	move S1 42
+//	move S2 Kessa
+//	build scout 2
+//	name S1 "Far Voyager"
+//	; carry goods from home to Kessa every trip
+//	supply S3 1 Kessa goods 20
+package orders
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Order is a single parsed order.
+type Order struct {
+	Line int      // line number in the source, starting at 1
+	Verb string   // always lower case
+	Args []string // arguments, with quotes removed
+}
+
+// String implements the Stringer interface.
+func (o *Order) String() string {
+	var sb strings.Builder
+	sb.WriteString(o.Verb)
+	for _, arg := range o.Args {
+		if strings.ContainsAny(arg, " \t;") {
+			arg = strconv.Quote(arg)
+		}
+		sb.WriteByte(' ')
+		sb.WriteString(arg)
+	}
+	return sb.String()
+}
+
+// Error is a problem with a single line of the orders.
+type Error struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// argKind is the type of value expected for an argument.
+type argKind int
+
+const (
+	identArg  argKind = iota // a letter followed by letters, digits, dashes, or underscores
+	systemArg                // a system id or the name of a system, star, or planet
+	countArg                 // a number greater than zero
+	cargoArg                 // people or goods
+	textArg                  // anything
+)
+
+// verbs defines the arguments for every order.
+var verbs = map[string][]argKind{
+	"build":    {identArg, countArg},                                 // build DESIGN QUANTITY
+	"load":     {identArg, cargoArg, countArg},                       // load SHIP CARGO QUANTITY
+	"move":     {identArg, systemArg},                                // move SHIP SYSTEM
+	"name":     {identArg, textArg},                                  // name SHIP TEXT
+	"supply":   {identArg, systemArg, systemArg, cargoArg, countArg}, // supply SHIP FROM TO CARGO QUANTITY
+	"transfer": {identArg, identArg, cargoArg, countArg},             // transfer SHIP SHIP CARGO QUANTITY
+	"unload":   {identArg, cargoArg, countArg},                       // unload SHIP CARGO QUANTITY
+}
+
+// Parse returns the orders in the source.
+// It returns all the errors it finds, not just the first.
+// The orders should not be used if there are any errors.
+func Parse(src []byte) (list []*Order, errs []*Error) {
+	for n, line := range strings.Split(string(src), "\n") {
+		o, err := parseLine(n+1, line)
+		if err != nil {
+			errs = append(errs, err)
+		} else if o != nil {
+			list = append(list, o)
+		}
+	}
+	return list, errs
+}
+
+// parseLine returns the order on the line.
+// It returns nil if the line is blank.
+func parseLine(n int, line string) (*Order, *Error) {
+	fields, err := split(line)
+	if err != nil {
+		return nil, &Error{Line: n, Message: err.Error()}
+	} else if len(fields) == 0 {
+		return nil, nil
+	}
+
+	o := &Order{Line: n, Verb: strings.ToLower(fields[0]), Args: fields[1:]}
+	kinds, ok := verbs[o.Verb]
+	if !ok {
+		return nil, &Error{Line: n, Message: fmt.Sprintf("unknown order %q", fields[0])}
+	} else if len(o.Args) != len(kinds) {
+		return nil, &Error{Line: n, Message: fmt.Sprintf("%s: expected %d arguments: got %d", o.Verb, len(kinds), len(o.Args))}
+	}
+	for i, kind := range kinds {
+		if msg := check(kind, o.Args[i]); msg != "" {
+			return nil, &Error{Line: n, Message: fmt.Sprintf("%s: argument %d: %q: %s", o.Verb, i+1, o.Args[i], msg)}
+		}
+	}
+
+	return o, nil
+}
+
+// check returns a message if the argument isn't the right kind.
+func check(kind argKind, arg string) string {
+	switch kind {
+	case identArg:
+		for i, r := range arg {
+			if i == 0 && !unicode.IsLetter(r) {
+				return "must start with a letter"
+			} else if !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_') {
+				return "must contain only letters, digits, dashes, or underscores"
+			}
+		}
+	case systemArg:
+		if n, err := strconv.Atoi(arg); err == nil {
+			if n < 0 {
+				return "must be a system id or name"
+			}
+			break
+		}
+		for i, r := range arg {
+			if i == 0 && !unicode.IsLetter(r) {
+				return "must be a system id or name"
+			} else if !(unicode.IsLetter(r) || r == '-' || r == ' ') {
+				return "must be a system id or name"
+			}
+		}
+	case countArg:
+		if n, err := strconv.Atoi(arg); err != nil || n < 1 {
+			return "must be a number greater than zero"
+		}
+	case cargoArg:
+		if s := strings.ToLower(arg); s != "people" && s != "goods" {
+			return "must be people or goods"
+		}
+	}
+	return ""
+}
+
+// split breaks the line into fields, removing comments and quotes.
+func split(line string) (fields []string, err error) {
+	var sb strings.Builder
+	inField, inQuote := false, false
+	for _, r := range line {
+		switch {
+		case inQuote && r == '"':
+			inQuote = false
+		case inQuote:
+			sb.WriteRune(r)
+		case r == '"':
+			inField, inQuote = true, true
+		case r == ';':
+			if inField {
+				fields = append(fields, sb.String())
+			}
+			return fields, nil
+		case unicode.IsSpace(r):
+			if inField {
+				fields, inField = append(fields, sb.String()), false
+				sb.Reset()
+			}
+		default:
+			inField = true
+			sb.WriteRune(r)
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote")
+	} else if inField {
+		fields = append(fields, sb.String())
+	}
+	return fields, nil
+}
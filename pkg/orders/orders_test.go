@@ -0,0 +1,83 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package orders
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := []byte(`; turn one orders
+move S1 42
+BUILD scout 2 ; two more scouts
+
+name S1 "Far Voyager"
+move S2 "Kessa B IV"
+supply S3 1 Kessa Goods 20
+`)
+	list, errs := Parse(src)
+	if len(errs) != 0 {
+		t.Fatalf("errors: expected none: got %v\n", errs)
+	}
+	for i, expect := range []struct {
+		line int
+		text string
+	}{
+		{2, "move S1 42"},
+		{3, "build scout 2"},
+		{5, `name S1 "Far Voyager"`},
+		{6, `move S2 "Kessa B IV"`},
+		{7, "supply S3 1 Kessa Goods 20"},
+	} {
+		if i >= len(list) {
+			t.Fatalf("orders: expected %d: got %d\n", 5, len(list))
+		}
+		if list[i].Line != expect.line || list[i].String() != expect.text {
+			t.Errorf("order %d: expected %d %q: got %d %q\n", i, expect.line, expect.text, list[i].Line, list[i].String())
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	src := []byte(`move S1
+jump S1 42
+build scout 0
+move 1S 42
+name S1 "unterminated
+move S2 4ever
+load S3 ore 5
+move S2 7
+`)
+	list, errs := Parse(src)
+	if len(list) != 1 || list[0].Line != 8 {
+		t.Errorf("orders: expected only line 8: got %d orders\n", len(list))
+	}
+	var lines []int
+	for _, err := range errs {
+		lines = append(lines, err.Line)
+	}
+	if len(lines) != 7 {
+		t.Fatalf("errors: expected lines 1-7: got %v\n", lines)
+	}
+	for i, line := range lines {
+		if line != i+1 {
+			t.Errorf("error %d: expected line %d: got %d\n", i, i+1, line)
+		}
+	}
+}
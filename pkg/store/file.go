@@ -0,0 +1,349 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FileStore keeps everything in a directory tree:
+//
+//	ROOT/identities.json
+//	ROOT/GAME/game.json
+//	ROOT/GAME/cluster.json
+//	ROOT/GAME/nations/NATION.json
+//	ROOT/GAME/turns/TTTT.json
+//	ROOT/GAME/orders/TTTT/NATION.VVV.txt
+//	ROOT/GAME/reports/TTTT/NATION.txt
+//
+// where TTTT is the turn number and VVV is the version number.
+type FileStore struct {
+	root    string
+	private struct {
+		sync.Mutex
+	}
+}
+
+// safeName restricts names to values that are safe to use in file names.
+var safeName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// NewFileStore returns a store rooted at the directory.
+// The directory is created if it doesn't exist.
+func NewFileStore(root string) (*FileStore, error) {
+	root = filepath.Clean(root)
+	if err := os.MkdirAll(root, 0777); err != nil {
+		return nil, err
+	}
+	return &FileStore{root: root}, nil
+}
+
+// Close implements the Store interface.
+func (fs *FileStore) Close() error {
+	return nil
+}
+
+// ListGames implements the GameStore interface.
+func (fs *FileStore) ListGames() ([]string, error) {
+	entries, err := os.ReadDir(fs.root)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, entry := range entries {
+		if !entry.IsDir() || !safeName.MatchString(entry.Name()) {
+			continue
+		} else if _, err := os.Stat(filepath.Join(fs.root, entry.Name(), "game.json")); err != nil {
+			continue
+		}
+		ids = append(ids, entry.Name())
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// GetGame implements the GameStore interface.
+func (fs *FileStore) GetGame(id string) ([]byte, error) {
+	return fs.read(id, "game.json")
+}
+
+// PutGame implements the GameStore interface.
+func (fs *FileStore) PutGame(id string, data []byte) error {
+	return fs.write(data, id, "game.json")
+}
+
+// GetCluster implements the GameStore interface.
+func (fs *FileStore) GetCluster(gameId string) ([]byte, error) {
+	return fs.read(gameId, "cluster.json")
+}
+
+// PutCluster implements the GameStore interface.
+func (fs *FileStore) PutCluster(gameId string, data []byte) error {
+	return fs.write(data, gameId, "cluster.json")
+}
+
+// GetTurn implements the TurnStore interface.
+func (fs *FileStore) GetTurn(gameId string, turn int) ([]byte, error) {
+	return fs.read(gameId, "turns", turnName(turn)+".json")
+}
+
+// PutTurn implements the TurnStore interface.
+// The snapshot is created exclusively so that it is never overwritten.
+func (fs *FileStore) PutTurn(gameId string, turn int, data []byte) error {
+	if !safeName.MatchString(gameId) {
+		return fmt.Errorf("invalid game id %q", gameId)
+	}
+	filename := filepath.Join(fs.root, gameId, "turns", turnName(turn)+".json")
+	if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+		return err
+	}
+	fd, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if errors.Is(err, os.ErrExist) {
+		return ErrExists
+	} else if err != nil {
+		return err
+	}
+	if _, err = fd.Write(data); err != nil {
+		_ = fd.Close()
+		_ = os.Remove(filename)
+		return err
+	}
+	return fd.Close()
+}
+
+// ListNations implements the NationStore interface.
+func (fs *FileStore) ListNations(gameId string) ([]Nation, error) {
+	if !safeName.MatchString(gameId) {
+		return nil, ErrNotFound
+	}
+	entries, err := os.ReadDir(filepath.Join(fs.root, gameId, "nations"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var list []Nation
+	for _, entry := range entries {
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		if entry.IsDir() || id == entry.Name() {
+			continue
+		}
+		data, err := fs.read(gameId, "nations", entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		var n Nation
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("%s: nation %q: %w", gameId, id, err)
+		}
+		list = append(list, n)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Id < list[j].Id
+	})
+	return list, nil
+}
+
+// PutNation implements the NationStore interface.
+func (fs *FileStore) PutNation(gameId string, n Nation) error {
+	if !safeName.MatchString(n.Id) {
+		return fmt.Errorf("invalid nation id %q", n.Id)
+	}
+	data, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fs.write(data, gameId, "nations", n.Id+".json")
+}
+
+// PutOrders implements the OrderStore interface.
+func (fs *FileStore) PutOrders(gameId string, turn int, nation string, data []byte) (int, error) {
+	if !safeName.MatchString(nation) {
+		return 0, fmt.Errorf("invalid nation id %q", nation)
+	}
+	fs.private.Lock()
+	defer fs.private.Unlock()
+
+	version, err := fs.latestOrders(gameId, turn, nation)
+	if err != nil {
+		return 0, err
+	}
+	version++
+	if err := fs.write(data, gameId, "orders", turnName(turn), ordersName(nation, version)); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// GetOrders implements the OrderStore interface.
+func (fs *FileStore) GetOrders(gameId string, turn int, nation string) ([]byte, int, error) {
+	if !safeName.MatchString(nation) {
+		return nil, 0, fmt.Errorf("invalid nation id %q", nation)
+	}
+	fs.private.Lock()
+	defer fs.private.Unlock()
+
+	version, err := fs.latestOrders(gameId, turn, nation)
+	if err != nil {
+		return nil, 0, err
+	} else if version == 0 {
+		return nil, 0, ErrNotFound
+	}
+	data, err := fs.read(gameId, "orders", turnName(turn), ordersName(nation, version))
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, version, nil
+}
+
+// GetReport implements the ReportStore interface.
+func (fs *FileStore) GetReport(gameId string, turn int, nation string) ([]byte, error) {
+	if !safeName.MatchString(nation) {
+		return nil, fmt.Errorf("invalid nation id %q", nation)
+	}
+	return fs.read(gameId, "reports", turnName(turn), nation+".txt")
+}
+
+// PutReport implements the ReportStore interface.
+func (fs *FileStore) PutReport(gameId string, turn int, nation string, data []byte) error {
+	if !safeName.MatchString(nation) {
+		return fmt.Errorf("invalid nation id %q", nation)
+	}
+	return fs.write(data, gameId, "reports", turnName(turn), nation+".txt")
+}
+
+// ListIdentities implements the IdentityStore interface.
+func (fs *FileStore) ListIdentities() ([]Identity, error) {
+	fs.private.Lock()
+	defer fs.private.Unlock()
+	return fs.identities()
+}
+
+// PutIdentity implements the IdentityStore interface.
+func (fs *FileStore) PutIdentity(id Identity) error {
+	fs.private.Lock()
+	defer fs.private.Unlock()
+
+	list, err := fs.identities()
+	if err != nil {
+		return err
+	}
+	found := false
+	for i := range list {
+		if list[i].Id == id.Id {
+			list[i], found = id, true
+		}
+	}
+	if !found {
+		list = append(list, id)
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Id < list[j].Id
+		})
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(fs.root, "identities.json"), data, 0666)
+}
+
+// identities returns the identities sorted by id, like the SQLite store.
+func (fs *FileStore) identities() ([]Identity, error) {
+	data, err := os.ReadFile(filepath.Join(fs.root, "identities.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var list []Identity
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("identities: %w", err)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Id < list[j].Id
+	})
+	return list, nil
+}
+
+// latestOrders returns the highest version of the nation's orders for the turn.
+// It returns zero if there are none. The caller must check the nation id.
+func (fs *FileStore) latestOrders(gameId string, turn int, nation string) (int, error) {
+	if !safeName.MatchString(gameId) {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(filepath.Join(fs.root, gameId, "orders", turnName(turn)))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	latest := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, nation+".") || !strings.HasSuffix(name, ".txt") {
+			continue
+		}
+		version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, nation+"."), ".txt"))
+		if err == nil && version > latest {
+			latest = version
+		}
+	}
+	return latest, nil
+}
+
+// read returns the contents of the file in the game's directory.
+func (fs *FileStore) read(gameId string, path ...string) ([]byte, error) {
+	if !safeName.MatchString(gameId) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(append([]string{fs.root, gameId}, path...)...))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// write saves the data to the file in the game's directory,
+// creating any missing directories.
+func (fs *FileStore) write(data []byte, gameId string, path ...string) error {
+	if !safeName.MatchString(gameId) {
+		return fmt.Errorf("invalid game id %q", gameId)
+	}
+	filename := filepath.Join(append([]string{fs.root, gameId}, path...)...)
+	if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0666)
+}
+
+func turnName(turn int) string {
+	return fmt.Sprintf("%04d", turn)
+}
+
+func ordersName(nation string, version int) string {
+	return fmt.Sprintf("%s.%03d.txt", nation, version)
+}
@@ -0,0 +1,310 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// SQLiteStore keeps everything in a single SQLite database.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// migrations is the ordered list of schema changes.
+// Never change a migration once it has been released; add a new one instead.
+var migrations = []string{
+	// 1: initial schema
+	`create table games (
+		id   text primary key,
+		data blob not null
+	);
+	create table clusters (
+		game_id text primary key references games(id),
+		data    blob not null
+	);
+	create table turns (
+		game_id text not null references games(id),
+		turn    integer not null,
+		data    blob not null,
+		primary key (game_id, turn)
+	);
+	create table nations (
+		game_id text not null references games(id),
+		id      text not null,
+		user_id text not null,
+		primary key (game_id, id)
+	);
+	create table orders (
+		game_id text not null references games(id),
+		turn    integer not null,
+		nation  text not null,
+		version integer not null,
+		data    blob not null,
+		primary key (game_id, turn, nation, version)
+	);
+	create table reports (
+		game_id text not null references games(id),
+		turn    integer not null,
+		nation  text not null,
+		data    blob not null,
+		primary key (game_id, turn, nation)
+	);
+	create table identities (
+		id            text primary key,
+		user_id       text not null,
+		hashed_secret blob
+	);`,
+	// 2: play-by-email
+	`alter table nations add column email text not null default '';
+	alter table nations add column hashed_secret blob;`,
+}
+
+// NewSQLiteStore opens the database, creating it if it doesn't exist,
+// and applies any outstanding migrations.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// the driver doesn't share in-memory databases between connections
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`pragma foreign_keys = on`); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	s := &SQLiteStore{db: db}
+	if err := s.migrate(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate applies every migration newer than the schema version.
+// Each migration runs in its own transaction.
+func (s *SQLiteStore) migrate() error {
+	if _, err := s.db.Exec(`create table if not exists schema_migrations (version integer primary key)`); err != nil {
+		return err
+	}
+	var version int
+	if err := s.db.QueryRow(`select coalesce(max(version), 0) from schema_migrations`).Scan(&version); err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("store: schema version %d is newer than this program (%d)", version, len(migrations))
+	}
+	for v := version + 1; v <= len(migrations); v++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[v-1]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("store: migration %d: %w", v, err)
+		}
+		if _, err := tx.Exec(`insert into schema_migrations (version) values (?)`, v); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("store: migration %d: %w", v, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("[store] applied migration %d\n", v)
+	}
+	return nil
+}
+
+// Close implements the Store interface.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// ListGames implements the GameStore interface.
+func (s *SQLiteStore) ListGames() ([]string, error) {
+	rows, err := s.db.Query(`select id from games order by id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetGame implements the GameStore interface.
+func (s *SQLiteStore) GetGame(id string) ([]byte, error) {
+	return s.blob(`select data from games where id = ?`, id)
+}
+
+// PutGame implements the GameStore interface.
+func (s *SQLiteStore) PutGame(id string, data []byte) error {
+	_, err := s.db.Exec(`insert into games (id, data) values (?, ?)
+		on conflict (id) do update set data = excluded.data`, id, data)
+	return err
+}
+
+// GetCluster implements the GameStore interface.
+func (s *SQLiteStore) GetCluster(gameId string) ([]byte, error) {
+	return s.blob(`select data from clusters where game_id = ?`, gameId)
+}
+
+// PutCluster implements the GameStore interface.
+func (s *SQLiteStore) PutCluster(gameId string, data []byte) error {
+	_, err := s.db.Exec(`insert into clusters (game_id, data) values (?, ?)
+		on conflict (game_id) do update set data = excluded.data`, gameId, data)
+	return err
+}
+
+// GetTurn implements the TurnStore interface.
+func (s *SQLiteStore) GetTurn(gameId string, turn int) ([]byte, error) {
+	return s.blob(`select data from turns where game_id = ? and turn = ?`, gameId, turn)
+}
+
+// PutTurn implements the TurnStore interface.
+func (s *SQLiteStore) PutTurn(gameId string, turn int, data []byte) error {
+	result, err := s.db.Exec(`insert into turns (game_id, turn, data) values (?, ?, ?)
+		on conflict (game_id, turn) do nothing`, gameId, turn, data)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrExists
+	}
+	return nil
+}
+
+// ListNations implements the NationStore interface.
+func (s *SQLiteStore) ListNations(gameId string) ([]Nation, error) {
+	rows, err := s.db.Query(`select id, user_id, email, hashed_secret from nations where game_id = ? order by id`, gameId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []Nation
+	for rows.Next() {
+		var n Nation
+		if err := rows.Scan(&n.Id, &n.UserId, &n.Email, &n.HashedSecret); err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
+
+// PutNation implements the NationStore interface.
+func (s *SQLiteStore) PutNation(gameId string, n Nation) error {
+	_, err := s.db.Exec(`insert into nations (game_id, id, user_id, email, hashed_secret) values (?, ?, ?, ?, ?)
+		on conflict (game_id, id) do update set user_id = excluded.user_id, email = excluded.email, hashed_secret = excluded.hashed_secret`,
+		gameId, n.Id, n.UserId, n.Email, n.HashedSecret)
+	return err
+}
+
+// PutOrders implements the OrderStore interface.
+func (s *SQLiteStore) PutOrders(gameId string, turn int, nation string, data []byte) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	var version int
+	if err := tx.QueryRow(`select coalesce(max(version), 0) + 1 from orders where game_id = ? and turn = ? and nation = ?`, gameId, turn, nation).Scan(&version); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`insert into orders (game_id, turn, nation, version, data) values (?, ?, ?, ?, ?)`, gameId, turn, nation, version, data); err != nil {
+		return 0, err
+	}
+	return version, tx.Commit()
+}
+
+// GetOrders implements the OrderStore interface.
+func (s *SQLiteStore) GetOrders(gameId string, turn int, nation string) ([]byte, int, error) {
+	var data []byte
+	var version int
+	err := s.db.QueryRow(`select data, version from orders where game_id = ? and turn = ? and nation = ?
+		order by version desc limit 1`, gameId, turn, nation).Scan(&data, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, ErrNotFound
+	} else if err != nil {
+		return nil, 0, err
+	}
+	return data, version, nil
+}
+
+// GetReport implements the ReportStore interface.
+func (s *SQLiteStore) GetReport(gameId string, turn int, nation string) ([]byte, error) {
+	return s.blob(`select data from reports where game_id = ? and turn = ? and nation = ?`, gameId, turn, nation)
+}
+
+// PutReport implements the ReportStore interface.
+func (s *SQLiteStore) PutReport(gameId string, turn int, nation string, data []byte) error {
+	_, err := s.db.Exec(`insert into reports (game_id, turn, nation, data) values (?, ?, ?, ?)
+		on conflict (game_id, turn, nation) do update set data = excluded.data`, gameId, turn, nation, data)
+	return err
+}
+
+// ListIdentities implements the IdentityStore interface.
+func (s *SQLiteStore) ListIdentities() ([]Identity, error) {
+	rows, err := s.db.Query(`select id, user_id, hashed_secret from identities order by id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []Identity
+	for rows.Next() {
+		var id Identity
+		if err := rows.Scan(&id.Id, &id.UserId, &id.HashedSecret); err != nil {
+			return nil, err
+		}
+		list = append(list, id)
+	}
+	return list, rows.Err()
+}
+
+// PutIdentity implements the IdentityStore interface.
+func (s *SQLiteStore) PutIdentity(id Identity) error {
+	_, err := s.db.Exec(`insert into identities (id, user_id, hashed_secret) values (?, ?, ?)
+		on conflict (id) do update set user_id = excluded.user_id, hashed_secret = excluded.hashed_secret`,
+		id.Id, id.UserId, id.HashedSecret)
+	return err
+}
+
+// blob returns the single value selected by the query.
+func (s *SQLiteStore) blob(query string, args ...any) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRow(query, args...).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
@@ -0,0 +1,129 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package store implements the persistence layer for the server.
+//
+// Games, clusters, and turn snapshots are stored as JSON documents.
+// The store doesn't look inside them; the games and wraith packages
+// own their formats. Nations, orders, reports, and identities are
+// stored as records so that they can be queried.
+//
+// There are two implementations. The file store keeps everything in a
+// directory tree, which is easy to inspect and edit by hand. The SQLite
+// store keeps everything in a single database file.
+package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned when a record doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrExists is returned when an immutable record already exists.
+var ErrExists = errors.New("already exists")
+
+// Store is the persistence layer shared by the games, the turn engine,
+// the handlers, and authentication.
+type Store interface {
+	GameStore
+	TurnStore
+	NationStore
+	OrderStore
+	ReportStore
+	IdentityStore
+	// Close releases any resources held by the store.
+	Close() error
+}
+
+// GameStore stores game metadata and clusters.
+type GameStore interface {
+	// ListGames returns the ids of all the games, sorted.
+	ListGames() ([]string, error)
+	GetGame(id string) ([]byte, error)
+	PutGame(id string, data []byte) error
+	GetCluster(gameId string) ([]byte, error)
+	PutCluster(gameId string, data []byte) error
+}
+
+// TurnStore stores the snapshot of a game's state at the end of each turn.
+// Turn zero is the state at the start of the game.
+// Snapshots are immutable.
+type TurnStore interface {
+	GetTurn(gameId string, turn int) ([]byte, error)
+	// PutTurn saves the snapshot for the turn.
+	// It returns ErrExists if the turn already has a snapshot.
+	PutTurn(gameId string, turn int, data []byte) error
+}
+
+// Nation links a nation in a game to the user that plays it.
+type Nation struct {
+	Id     string `json:"id"`
+	UserId string `json:"user-id"`
+	// Email and HashedSecret identify the player for play-by-email.
+	Email        string `json:"email,omitempty"`
+	HashedSecret []byte `json:"hashed-secret,omitempty"`
+}
+
+// NationStore stores the nations in each game.
+type NationStore interface {
+	// ListNations returns the nations in the game, sorted by id.
+	ListNations(gameId string) ([]Nation, error)
+	PutNation(gameId string, n Nation) error
+}
+
+// OrderStore stores every version of the orders submitted by a nation.
+type OrderStore interface {
+	// PutOrders saves a new version of the orders and returns its version number.
+	PutOrders(gameId string, turn int, nation string, data []byte) (int, error)
+	// GetOrders returns the latest version of the orders.
+	GetOrders(gameId string, turn int, nation string) (data []byte, version int, err error)
+}
+
+// ReportStore stores the turn reports for each nation.
+type ReportStore interface {
+	GetReport(gameId string, turn int, nation string) ([]byte, error)
+	PutReport(gameId string, turn int, nation string, data []byte) error
+}
+
+// Identity is the stored form of an authentication identity.
+type Identity struct {
+	Id           string `json:"id"`
+	UserId       string `json:"user-id"`
+	HashedSecret []byte `json:"hashed-secret,omitempty"`
+}
+
+// IdentityStore stores authentication identities.
+type IdentityStore interface {
+	ListIdentities() ([]Identity, error)
+	PutIdentity(id Identity) error
+}
+
+// Open returns a store using the named driver.
+// The file driver uses path as the root directory.
+// The sqlite driver uses path as the database file.
+func Open(driver, path string) (Store, error) {
+	switch driver {
+	case "file":
+		return NewFileStore(path)
+	case "sqlite":
+		return NewSQLiteStore(path)
+	}
+	return nil, fmt.Errorf("store: unknown driver %q", driver)
+}
@@ -0,0 +1,149 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestStores(t *testing.T) {
+	for _, tc := range []struct {
+		id     int
+		driver string
+		path   string
+	}{
+		{1, "file", t.TempDir()},
+		{2, "sqlite", filepath.Join(t.TempDir(), "wraith.db")},
+	} {
+		st, err := Open(tc.driver, tc.path)
+		if err != nil {
+			t.Fatalf("%d: open: %v\n", tc.id, err)
+		}
+		testStore(t, tc.id, st)
+		if err := st.Close(); err != nil {
+			t.Errorf("%d: close: %v\n", tc.id, err)
+		}
+
+		// reopening must find the data and must not re-run migrations
+		st, err = Open(tc.driver, tc.path)
+		if err != nil {
+			t.Fatalf("%d: reopen: %v\n", tc.id, err)
+		}
+		if ids, err := st.ListGames(); err != nil || len(ids) != 2 {
+			t.Errorf("%d: reopen: expected 2 games: got %v %v\n", tc.id, ids, err)
+		}
+		_ = st.Close()
+	}
+}
+
+func testStore(t *testing.T, id int, st Store) {
+	for _, game := range []string{"g2", "g1"} {
+		if err := st.PutGame(game, []byte(`{"id":"`+game+`"}`)); err != nil {
+			t.Fatalf("%d: put game: %v\n", id, err)
+		}
+	}
+	if ids, err := st.ListGames(); err != nil || len(ids) != 2 || ids[0] != "g1" || ids[1] != "g2" {
+		t.Errorf("%d: list games: expected [g1 g2]: got %v %v\n", id, ids, err)
+	}
+	if _, err := st.GetGame("g3"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("%d: get missing game: expected ErrNotFound: got %v\n", id, err)
+	}
+	if err := st.PutCluster("g1", []byte("cluster")); err != nil {
+		t.Fatalf("%d: put cluster: %v\n", id, err)
+	} else if data, err := st.GetCluster("g1"); err != nil || string(data) != "cluster" {
+		t.Errorf("%d: get cluster: expected %q: got %q %v\n", id, "cluster", data, err)
+	}
+	if err := st.PutTurn("g1", 0, []byte("turn 0")); err != nil {
+		t.Fatalf("%d: put turn: %v\n", id, err)
+	} else if data, err := st.GetTurn("g1", 0); err != nil || string(data) != "turn 0" {
+		t.Errorf("%d: get turn: expected %q: got %q %v\n", id, "turn 0", data, err)
+	}
+	if err := st.PutTurn("g1", 0, []byte("changed")); !errors.Is(err, ErrExists) {
+		t.Errorf("%d: replace turn: expected ErrExists: got %v\n", id, err)
+	} else if data, _ := st.GetTurn("g1", 0); string(data) != "turn 0" {
+		t.Errorf("%d: replace turn: expected %q: got %q\n", id, "turn 0", data)
+	}
+	if _, err := st.GetTurn("g1", 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("%d: get missing turn: expected ErrNotFound: got %v\n", id, err)
+	}
+
+	for _, n := range []Nation{{Id: "n2", UserId: "u2"}, {Id: "n1", UserId: "u1"}, {Id: "n1", UserId: "u3", Email: "u3@example.com"}} {
+		if err := st.PutNation("g1", n); err != nil {
+			t.Fatalf("%d: put nation: %v\n", id, err)
+		}
+	}
+	if list, err := st.ListNations("g1"); err != nil || len(list) != 2 || list[0].UserId != "u3" || list[0].Email != "u3@example.com" || list[1].Id != "n2" {
+		t.Errorf("%d: list nations: expected [n1 n2] with n1 played by u3: got %v %v\n", id, list, err)
+	}
+
+	if _, _, err := st.GetOrders("g1", 1, "n1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("%d: get missing orders: expected ErrNotFound: got %v\n", id, err)
+	}
+	for want, data := range []string{"first", "second"} {
+		if version, err := st.PutOrders("g1", 1, "n1", []byte(data)); err != nil || version != want+1 {
+			t.Errorf("%d: put orders: expected version %d: got %d %v\n", id, want+1, version, err)
+		}
+	}
+	if data, version, err := st.GetOrders("g1", 1, "n1"); err != nil || version != 2 || string(data) != "second" {
+		t.Errorf("%d: get orders: expected 2 %q: got %d %q %v\n", id, "second", version, data, err)
+	}
+
+	if err := st.PutReport("g1", 1, "n1", []byte("report")); err != nil {
+		t.Fatalf("%d: put report: %v\n", id, err)
+	} else if data, err := st.GetReport("g1", 1, "n1"); err != nil || string(data) != "report" {
+		t.Errorf("%d: get report: expected %q: got %q %v\n", id, "report", data, err)
+	}
+	if _, err := st.GetReport("g1", 1, "n2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("%d: get missing report: expected ErrNotFound: got %v\n", id, err)
+	}
+
+	for _, ident := range []Identity{{"i2", "u2", nil}, {"i1", "u1", []byte("a")}, {"i1", "u1", []byte("b")}} {
+		if err := st.PutIdentity(ident); err != nil {
+			t.Fatalf("%d: put identity: %v\n", id, err)
+		}
+	}
+	if list, err := st.ListIdentities(); err != nil || len(list) != 2 || list[0].Id != "i1" || string(list[0].HashedSecret) != "b" {
+		t.Errorf("%d: list identities: expected [i1 i2] with secret %q: got %v %v\n", id, "b", list, err)
+	}
+}
+
+func TestFileStoreNames(t *testing.T) {
+	fs, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	} else if err = fs.PutGame("g1", []byte(`{"id":"g1"}`)); err != nil {
+		t.Fatal(err)
+	}
+	for _, nation := range []string{"", "../g2", "n1/x", ".n1"} {
+		if _, err := fs.PutOrders("g1", 1, nation, []byte("orders")); err == nil {
+			t.Errorf("put orders %q: expected error: got nil\n", nation)
+		}
+		if _, _, err := fs.GetOrders("g1", 1, nation); err == nil {
+			t.Errorf("get orders %q: expected error: got nil\n", nation)
+		}
+		if err := fs.PutReport("g1", 1, nation, []byte("report")); err == nil {
+			t.Errorf("put report %q: expected error: got nil\n", nation)
+		}
+		if _, err := fs.GetReport("g1", 1, nation); err == nil {
+			t.Errorf("get report %q: expected error: got nil\n", nation)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Analysis collects the distributions of a set of generated clusters.
+// It is used to tune the generators and to judge whether maps are fair.
+//
+// Every metric counts something in buckets, once per cluster:
+//
+//	systems-per-ring       systems in each ring
+//	stars-per-system       systems with each number of stars
+//	nearest-neighbor       systems whose nearest neighbor is in each 1 ly band
+//	habitable-by-distance  habitable planets in each ring around the home system
+//	stars-by-type          stars of each spectral type (0 is O through 6 is M)
+//	anomalies              systems without stars with each anomaly (0 is dead,
+//	                       1 nebula, 2 dust, 3 rogue planet)
+//
+// The report gives the mean, spread, and range of each count across clusters.
+type Analysis struct {
+	clusters int
+	// samples holds the counts for every metric.
+	// The first key is the metric name, the second is the bucket.
+	// Each slice has one count per cluster.
+	samples map[string]map[int][]float64
+}
+
+// analysisMetrics is the report order of the metrics.
+var analysisMetrics = []string{"systems-per-ring", "stars-per-system", "nearest-neighbor", "habitable-by-distance", "stars-by-type", "anomalies"}
+
+// NewAnalysis returns an empty analysis.
+func NewAnalysis() *Analysis {
+	a := &Analysis{samples: make(map[string]map[int][]float64)}
+	for _, metric := range analysisMetrics {
+		a.samples[metric] = make(map[int][]float64)
+	}
+	return a
+}
+
+// Clusters returns the number of clusters that have been added.
+func (a *Analysis) Clusters() int {
+	return a.clusters
+}
+
+// Add counts the cluster.
+func (a *Analysis) Add(c *Cluster) {
+	counts := make(map[string]map[int]float64)
+	for _, metric := range analysisMetrics {
+		counts[metric] = make(map[int]float64)
+	}
+	index := c.index()
+	for _, sys := range c.systems {
+		counts["systems-per-ring"][sys.ring]++
+		counts["stars-per-system"][len(sys.stars)]++
+		if len(sys.stars) == 0 {
+			counts["anomalies"][int(sys.anomaly)]++
+		}
+		if n, ok := index.nearest(sys.coords, func(other *system) bool { return other == sys }); ok {
+			counts["nearest-neighbor"][int(n.distance)]++
+		}
+		for _, s := range sys.stars {
+			counts["stars-by-type"][int(s.spectral)]++
+			for _, p := range s.planets {
+				if p.habitability > 0 {
+					counts["habitable-by-distance"][sys.ring]++
+				}
+			}
+		}
+	}
+
+	// buckets that this cluster doesn't have get a zero count, and
+	// buckets that earlier clusters didn't have are back-filled with zeroes.
+	for metric, buckets := range a.samples {
+		for bucket := range counts[metric] {
+			if _, ok := buckets[bucket]; !ok {
+				buckets[bucket] = make([]float64, a.clusters)
+			}
+		}
+		for bucket := range buckets {
+			buckets[bucket] = append(buckets[bucket], counts[metric][bucket])
+		}
+	}
+	a.clusters++
+}
+
+// Summary is the distribution of a count across clusters.
+type Summary struct {
+	Metric string
+	Bucket int
+	Mean   float64
+	StdDev float64
+	Min    float64
+	Max    float64
+}
+
+// Summaries returns the summary of every bucket of every metric,
+// sorted by metric and then bucket.
+func (a *Analysis) Summaries() []Summary {
+	var list []Summary
+	for _, metric := range analysisMetrics {
+		var buckets []int
+		for bucket := range a.samples[metric] {
+			buckets = append(buckets, bucket)
+		}
+		sort.Ints(buckets)
+		for _, bucket := range buckets {
+			list = append(list, summarize(metric, bucket, a.samples[metric][bucket]))
+		}
+	}
+	return list
+}
+
+func summarize(metric string, bucket int, samples []float64) Summary {
+	s := Summary{Metric: metric, Bucket: bucket}
+	if len(samples) == 0 {
+		return s
+	}
+	s.Min, s.Max = samples[0], samples[0]
+	for _, v := range samples {
+		s.Mean += v
+		s.Min, s.Max = math.Min(s.Min, v), math.Max(s.Max, v)
+	}
+	s.Mean /= float64(len(samples))
+	for _, v := range samples {
+		s.StdDev += (v - s.Mean) * (v - s.Mean)
+	}
+	s.StdDev = math.Sqrt(s.StdDev / float64(len(samples)))
+	return s
+}
+
+// WriteText writes the analysis as a set of tables.
+func (a *Analysis) WriteText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "clusters analyzed: %d\n", a.clusters); err != nil {
+		return err
+	}
+	metric := ""
+	for _, s := range a.Summaries() {
+		if s.Metric != metric {
+			metric = s.Metric
+			if _, err := fmt.Fprintf(w, "\n%s\n  %6s  %9s  %9s  %9s  %9s\n", metric, "bucket", "mean", "stddev", "min", "max"); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "  %6d  %9.2f  %9.2f  %9.0f  %9.0f\n", s.Bucket, s.Mean, s.StdDev, s.Min, s.Max); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes the analysis as CSV with a header row.
+func (a *Analysis) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"metric", "bucket", "mean", "stddev", "min", "max", "clusters"}); err != nil {
+		return err
+	}
+	f := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 4, 64)
+	}
+	for _, s := range a.Summaries() {
+		if err := cw.Write([]string{s.Metric, strconv.Itoa(s.Bucket), f(s.Mean), f(s.StdDev), f(s.Min), f(s.Max), strconv.Itoa(a.clusters)}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
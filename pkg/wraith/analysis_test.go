@@ -0,0 +1,82 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+func TestAnalysis(t *testing.T) {
+	// two tiny clusters: the second has a ring the first doesn't
+	a := NewAnalysis()
+	for _, c := range []*Cluster{
+		{systems: []*system{
+			{ring: 0, stars: []*star{{planets: []*planet{{habitability: 25}}}}},
+			{ring: 2, coords: coords{x: 2}, stars: []*star{{}, {}}},
+		}},
+		{systems: []*system{
+			{ring: 0, stars: []*star{{planets: []*planet{{habitability: 25}, {habitability: 3}}}}},
+			{ring: 3, coords: coords{y: 3}},
+		}},
+	} {
+		a.Add(c)
+	}
+
+	got := make(map[string]map[int]Summary)
+	for _, s := range a.Summaries() {
+		if got[s.Metric] == nil {
+			got[s.Metric] = make(map[int]Summary)
+		}
+		got[s.Metric][s.Bucket] = s
+	}
+	for _, tc := range []struct {
+		id       int
+		metric   string
+		bucket   int
+		mean     float64
+		min, max float64
+	}{
+		{1, "systems-per-ring", 0, 1, 1, 1},
+		{2, "systems-per-ring", 2, 0.5, 0, 1},
+		{3, "systems-per-ring", 3, 0.5, 0, 1},
+		{4, "stars-per-system", 2, 0.5, 0, 1},
+		{5, "nearest-neighbor", 2, 1, 0, 2},
+		{6, "habitable-by-distance", 0, 1.5, 1, 2},
+	} {
+		s, ok := got[tc.metric][tc.bucket]
+		if !ok {
+			t.Errorf("%d: %s %d: missing\n", tc.id, tc.metric, tc.bucket)
+		} else if s.Mean != tc.mean || s.Min != tc.min || s.Max != tc.max {
+			t.Errorf("%d: %s %d: expected %v %v %v: got %v %v %v\n", tc.id, tc.metric, tc.bucket, tc.mean, tc.min, tc.max, s.Mean, s.Min, s.Max)
+		}
+	}
+
+	b := &bytes.Buffer{}
+	if err := a.WriteCSV(b); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(rows) != len(a.Summaries())+1 || rows[0][0] != "metric" {
+		t.Errorf("csv: expected %d rows with a header: got %d\n", len(a.Summaries())+1, len(rows))
+	}
+}
@@ -0,0 +1,199 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"math"
+	"math/rand"
+)
+
+const (
+	// nebulaSpeed is the fraction of its speed that a ship keeps on a leg
+	// that starts or ends in a nebula.
+	nebulaSpeed = 0.5
+	// dustRadius is the distance, in light years, within which a dust
+	// cloud blocks sensors. Lines of sight that pass this close to a
+	// cloud are blocked, so clouds hide themselves and what lies behind them.
+	dustRadius = 0.75
+	// minRogueResources and maxRogueResources bound the resources on a rogue planet.
+	minRogueResources, maxRogueResources = 10, 100
+)
+
+// anomaly is what fills a system without stars.
+type anomaly int
+
+const (
+	deadSystem  anomaly = iota // nothing of interest
+	nebula                     // slows ships
+	dustCloud                  // blocks sensors
+	roguePlanet                // a planet without a star, with resources
+)
+
+// anomalies is every anomaly, in the order used by the weights.
+var anomalies = []anomaly{deadSystem, nebula, dustCloud, roguePlanet}
+
+// String implements the Stringer interface.
+func (a anomaly) String() string {
+	switch a {
+	case deadSystem:
+		return "dead"
+	case nebula:
+		return "nebula"
+	case dustCloud:
+		return "dust"
+	case roguePlanet:
+		return "rogue"
+	}
+	return "unknown"
+}
+
+// describe returns the name of the anomaly for reports.
+func (a anomaly) describe() string {
+	switch a {
+	case nebula:
+		return "nebula"
+	case dustCloud:
+		return "dust cloud"
+	case roguePlanet:
+		return "rogue planet"
+	}
+	return "dead system"
+}
+
+// color returns the color used to draw systems with the anomaly.
+func (a anomaly) color() string {
+	switch a {
+	case nebula:
+		return "#c080ff"
+	case dustCloud:
+		return "#a07850"
+	case roguePlanet:
+		return "#40c080"
+	}
+	return "#808080"
+}
+
+// symbol returns the character used for the anomaly on text maps.
+func (a anomaly) symbol() byte {
+	switch a {
+	case nebula:
+		return 'n'
+	case dustCloud:
+		return 'd'
+	case roguePlanet:
+		return 'r'
+	}
+	return 'o'
+}
+
+// parseAnomaly returns the anomaly with the given name.
+func parseAnomaly(s string) (anomaly, bool) {
+	for _, a := range anomalies {
+		if a.String() == s {
+			return a, true
+		}
+	}
+	return deadSystem, false
+}
+
+// DefaultAnomalies returns the relative frequency of each anomaly used
+// when the parameters don't have any. The keys are "dead", "nebula",
+// "dust", and "rogue".
+func DefaultAnomalies() map[string]int {
+	return map[string]int{"dead": 40, "nebula": 25, "dust": 15, "rogue": 20}
+}
+
+// validateAnomalies returns an error if the weights can't be used.
+func validateAnomalies(weights map[string]int) error {
+	return validateWeights("anomaly", weights, func(s string) bool { _, ok := parseAnomaly(s); return ok })
+}
+
+// is returns true if the system is a starless system with the anomaly.
+func (sys *system) is(a anomaly) bool {
+	return len(sys.stars) == 0 && sys.anomaly == a
+}
+
+// generateAnomalies fills every system without stars with an anomaly
+// chosen with the weights. A nil map uses DefaultAnomalies.
+func (c *Cluster) generateAnomalies(r *rand.Rand, weights map[string]int) {
+	if weights == nil {
+		weights = DefaultAnomalies()
+	}
+	var list []int
+	for _, a := range anomalies {
+		list = append(list, weights[a.String()])
+	}
+	for _, sys := range c.systems {
+		sys.anomaly, sys.resources = deadSystem, 0
+		if len(sys.stars) != 0 {
+			continue
+		}
+		sys.anomaly = anomalies[pick(r, list)]
+		if sys.anomaly == roguePlanet {
+			sys.resources = minRogueResources + r.Intn(maxRogueResources-minRogueResources+1)
+		}
+	}
+}
+
+// hasAnomalies returns true if any system in the cluster has an anomaly.
+func (c *Cluster) hasAnomalies() bool {
+	for _, sys := range c.systems {
+		if len(sys.stars) == 0 && sys.anomaly != deadSystem {
+			return true
+		}
+	}
+	return false
+}
+
+// legSpeed returns the fraction of its speed that a ship keeps while
+// travelling between the systems.
+func (c *Cluster) legSpeed(from, to int) float64 {
+	if c.systems[from].is(nebula) || c.systems[to].is(nebula) {
+		return nebulaSpeed
+	}
+	return 1
+}
+
+// legCost returns the effective length of the leg between the systems:
+// the distance a ship would cover in the same time outside of nebulae.
+func (c *Cluster) legCost(from, to int) float64 {
+	return c.systems[from].coords.distance(c.systems[to].coords) / c.legSpeed(from, to)
+}
+
+// obscured returns true if a dust cloud blocks the line of sight between
+// the points. Sensors inside a dust cloud are blind.
+func (c *Cluster) obscured(from, to coords) bool {
+	mid := coords{x: (from.x + to.x) / 2, y: (from.y + to.y) / 2, z: (from.z + to.z) / 2}
+	radius := from.distance(to)/2 + dustRadius
+	return !c.index().visit(mid, radius, func(nb neighbor) bool {
+		return !nb.sys.is(dustCloud) || segmentDistance(nb.sys.coords, from, to) > dustRadius
+	})
+}
+
+// segmentDistance returns the distance from the point to the line segment from a to b.
+func segmentDistance(pt, a, b coords) float64 {
+	dx, dy, dz := b.x-a.x, b.y-a.y, b.z-a.z
+	length := dx*dx + dy*dy + dz*dz
+	if length == 0 {
+		return pt.distance(a)
+	}
+	f := ((pt.x-a.x)*dx + (pt.y-a.y)*dy + (pt.z-a.z)*dz) / length
+	f = math.Max(0, math.Min(1, f))
+	return pt.distance(coords{x: a.x + f*dx, y: a.y + f*dy, z: a.z + f*dz})
+}
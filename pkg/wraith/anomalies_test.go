@@ -0,0 +1,148 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGenerateAnomalies(t *testing.T) {
+	p := Params{Seed: 5, Systems: 96, MinStars: 40, Radius: 12, Clearance: 4.5, Anomalies: map[string]int{"rogue": 1}}
+	c, err := Generate("sphere", p)
+	if err != nil {
+		t.Fatalf("generate: %v\n", err)
+	}
+	rogues := 0
+	for _, sys := range c.systems {
+		if len(sys.stars) != 0 {
+			if sys.anomaly != deadSystem || sys.resources != 0 {
+				t.Errorf("system %d: expected no anomaly with stars: got %s %d\n", sys.id, sys.anomaly, sys.resources)
+			}
+			continue
+		}
+		rogues++
+		if !sys.is(roguePlanet) {
+			t.Errorf("system %d: expected rogue: got %s\n", sys.id, sys.anomaly)
+		} else if sys.resources < minRogueResources || sys.resources > maxRogueResources {
+			t.Errorf("system %d: expected %d to %d resources: got %d\n", sys.id, minRogueResources, maxRogueResources, sys.resources)
+		}
+	}
+	if rogues == 0 {
+		t.Fatalf("generate: expected systems without stars: got none\n")
+	}
+
+	// anomalies survive a trip through JSON
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again Cluster
+	if err = json.Unmarshal(data, &again); err != nil {
+		t.Fatal(err)
+	}
+	for i, sys := range c.systems {
+		if other := again.systems[i]; other.anomaly != sys.anomaly || other.resources != sys.resources {
+			t.Errorf("json: system %d: expected %s %d: got %s %d\n", i, sys.anomaly, sys.resources, other.anomaly, other.resources)
+		}
+	}
+
+	if err := (Params{Systems: 1, MinStars: 1, Radius: 5, Anomalies: map[string]int{"wormhole": 1}}).Validate(); err == nil {
+		t.Errorf("validate: expected an error for an unknown anomaly: got none\n")
+	}
+	if err := (Params{Systems: 1, MinStars: 1, Radius: 5, Anomalies: map[string]int{"dead": 0}}).Validate(); err == nil {
+		t.Errorf("validate: expected an error for zero weights: got none\n")
+	}
+}
+
+func TestNebulaMovement(t *testing.T) {
+	// home -- nebula -- beta, in a line
+	c := &Cluster{systems: []*system{
+		{name: "Home", home: true, stars: []*star{{}}},
+		{name: "Murk", coords: coords{x: 2}, anomaly: nebula},
+		{name: "Beta", coords: coords{x: 6}, stars: []*star{{}}},
+	}}
+	c.link()
+	c.addLane(c.systems[0], c.systems[1])
+	c.addLane(c.systems[1], c.systems[2])
+
+	// both legs touch the nebula, so the 6 ly trip costs 12
+	if _, cost, _ := c.route(0, 2, All); cost != 12 {
+		t.Errorf("route: expected cost 12: got %v\n", cost)
+	}
+	r := c.reachable(0, []int{2}, All, c.hasLanes())[0]
+	if r.distance != 6 || r.cost != 12 {
+		t.Errorf("reachable: expected 6 ly costing 12: got %+v\n", r)
+	}
+	scout := &Design{Name: "scout", Speed: 3}
+	if turns := travelTurns(scout, r); turns != 4 {
+		t.Errorf("travel turns: expected 4: got %d\n", turns)
+	}
+
+	sh := &Ship{Id: "S1"}
+	path, _, _ := c.route(0, 2, All)
+	sh.follow(path)
+	sh.advance(c, 3) // half speed toward the nebula
+	if sh.System != 0 || sh.Traveled != 1.5 {
+		t.Errorf("advance: expected 1.5 ly from home: got %d %v\n", sh.System, sh.Traveled)
+	}
+	sh.advance(c, 3) // the last 0.5 ly takes a third of the turn, then 1 ly out of the nebula
+	if sh.System != 1 || sh.Destination != 2 || sh.Traveled != 1 {
+		t.Errorf("advance: expected 1 ly past the nebula: got %d %d %v\n", sh.System, sh.Destination, sh.Traveled)
+	}
+	for turn := 0; turn < 2; turn++ {
+		sh.advance(c, 3)
+	}
+	if sh.InTransit() || sh.System != 2 {
+		t.Errorf("advance: expected to arrive at Beta in 4 turns: got %+v\n", sh)
+	}
+}
+
+func TestDustBlocksSensors(t *testing.T) {
+	c := &Cluster{systems: []*system{
+		{name: "Home", home: true, stars: []*star{{}}},
+		{name: "Haze", coords: coords{x: 3}, anomaly: dustCloud},
+		{name: "Behind", coords: coords{x: 6}, stars: []*star{{}}},
+		{name: "Beside", coords: coords{y: 4}, stars: []*star{{}}},
+		{name: "Grazed", coords: coords{x: 6, y: 1.2}, stars: []*star{{}}},
+	}}
+	c.link()
+	for _, tc := range []struct {
+		id       int
+		to       int
+		obscured bool
+	}{
+		{1, 1, true},
+		{2, 2, true},
+		{3, 3, false},
+		{4, 4, true}, // passes 0.6 ly from the cloud
+	} {
+		if got := c.obscured(c.systems[0].coords, c.systems[tc.to].coords); got != tc.obscured {
+			t.Errorf("%d: obscured %s: expected %v: got %v\n", tc.id, c.systems[tc.to].name, tc.obscured, got)
+		}
+	}
+
+	s, err := NewState(c, "alpha")
+	if err != nil {
+		t.Fatalf("state: %v\n", err)
+	}
+	if n := s.Nations["alpha"]; len(n.Known) != 2 || !n.Knows(0) || !n.Knows(3) {
+		t.Errorf("survey: expected Home and Beside: got %v\n", n.Known)
+	}
+}
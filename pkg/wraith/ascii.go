@@ -0,0 +1,140 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// asciiMapCells is the most cells across a text map. Each cell is two
+// characters wide so that the map fits in 80 columns and looks square.
+const asciiMapCells = 36
+
+// asciiMap returns a map of the systems known to the nation for plain
+// text reports. The cluster is projected onto the XY plane with +y at
+// the top; the system table gives the z coordinates.
+//
+// The first character of a cell is the system: @ for the home system,
+// a digit for the number of stars, n for a nebula, d for a dust cloud,
+// r for a rogue planet, o for a dead system, and + for a cell with more
+// than one system. Cells inside the cluster are
+// dotted. The second character is * if the nation has ships there.
+func (c *Cluster) asciiMap(n *Nation) []string {
+	radius := 1
+	for _, sys := range c.systems {
+		if sys.ring > radius {
+			radius = sys.ring
+		}
+	}
+	scale := 2*radius/asciiMapCells + 1 // light years per cell
+	half := int(math.Ceil(float64(radius) / float64(scale)))
+	size := 2*half + 1
+	cell := func(pt coords) (row, col int) {
+		clamp := func(i int) int {
+			if i < 0 {
+				return 0
+			} else if i >= size {
+				return size - 1
+			}
+			return i
+		}
+		return clamp(half - int(math.Round(pt.y/float64(scale)))), clamp(half + int(math.Round(pt.x/float64(scale))))
+	}
+
+	grid := make([][]byte, size)
+	for row := range grid {
+		grid[row] = []byte(strings.Repeat(" ", 2*size))
+		for col := 0; col < size; col++ {
+			x, y := float64((col-half)*scale), float64((half-row)*scale)
+			if math.Hypot(x, y) <= float64(radius) {
+				grid[row][2*col] = '.'
+			}
+		}
+	}
+	for _, id := range n.Known {
+		sys := c.systems[id]
+		row, col := cell(sys.coords)
+		symbol := sys.anomaly.symbol()
+		if id == n.HomeSystem {
+			symbol = '@'
+		} else if len(sys.stars) != 0 {
+			symbol = byte('0' + len(sys.stars))
+		}
+		if prev := grid[row][2*col]; prev != '.' && prev != ' ' && prev != '@' {
+			symbol = '+'
+		} else if prev == '@' {
+			symbol = '@'
+		}
+		grid[row][2*col] = symbol
+	}
+	for _, sh := range n.Ships {
+		row, col := cell(sh.position(c))
+		grid[row][2*col+1] = '*'
+	}
+
+	lines := []string{
+		"@ home  1-5 stars  n nebula  d dust  r rogue planet  o dead  + several  * ships",
+		fmt.Sprintf("x across, y up, %d ly per cell; see the system table for z", scale),
+	}
+	// label the x axis at the edges and the center
+	axis := []byte(strings.Repeat(" ", 2*size))
+	for _, col := range []int{0, half, size - 1} {
+		label := strconv.Itoa((col - half) * scale)
+		start := 2 * col
+		if start+len(label) > len(axis) {
+			start = len(axis) - len(label)
+		}
+		copy(axis[start:], label)
+	}
+	lines = append(lines, "     "+strings.TrimRight(string(axis), " "))
+	for row := range grid {
+		label := ""
+		if y := (half - row) * scale; y%5 == 0 {
+			label = strconv.Itoa(y)
+		}
+		lines = append(lines, fmt.Sprintf("%4s %s", label, strings.TrimRight(string(grid[row]), " ")))
+	}
+	return lines
+}
+
+// systemTable returns the systems known to the nation for plain text
+// reports, sorted by distance from the nation's home system.
+func (c *Cluster) systemTable(n *Nation) []string {
+	home := c.systems[n.HomeSystem].coords
+	known := append([]int(nil), n.Known...)
+	sort.SliceStable(known, func(i, j int) bool {
+		return c.systems[known[i]].coords.distance(home) < c.systems[known[j]].coords.distance(home)
+	})
+	lines := []string{fmt.Sprintf("%-12s %-9s  %5s %4s %5s  %s", "System", "  X  Y  Z", "Dist", "Ring", "Stars", "Notes")}
+	for _, id := range known {
+		sys := c.systems[id]
+		notes := ""
+		if sys.is(roguePlanet) {
+			notes = fmt.Sprintf("rogue planet, %d resources", sys.resources)
+		} else if len(sys.stars) == 0 {
+			notes = sys.anomaly.describe()
+		}
+		lines = append(lines, strings.TrimRight(fmt.Sprintf("%-12s %s  %5.1f %4d %5d  %s", sys.label(), sys.coords.xyz(), sys.coords.distance(home), sys.ring, len(sys.stars), notes), " "))
+	}
+	return lines
+}
@@ -0,0 +1,72 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestASCIIMap(t *testing.T) {
+	c := &Cluster{systems: []*system{
+		{name: "Home", home: true, stars: []*star{{}}},
+		{name: "Near", ring: 3, coords: coords{x: 3}, stars: []*star{{}, {}}},
+		{name: "Far", ring: 10, coords: coords{y: -10}},
+		{name: "Hidden", ring: 5, coords: coords{x: -5}, stars: []*star{{}}},
+	}}
+	c.link()
+	n := &Nation{Id: "alpha"}
+	for _, id := range []int{2, 0, 1} {
+		n.Learn(id)
+	}
+	n.Ships = []*Ship{{Id: "S1", System: 1, Destination: 1}}
+
+	lines := c.asciiMap(n)
+	grid := make(map[int]string) // y to the row of the grid
+	for _, line := range lines[3:] {
+		grid[len(grid)-10] = line[5:] + strings.Repeat(" ", 50)
+	}
+	// the center column is at 2*half, and half is the radius
+	for _, tc := range []struct {
+		id     int
+		x, y   int
+		expect string
+	}{
+		{1, 0, 0, "@"},
+		{2, 3, 0, "2*"},
+		{3, 0, -10, "o"},
+		{4, -5, 0, "."},
+	} {
+		row := grid[-tc.y]
+		col := 2 * (tc.x + 10)
+		if got := strings.TrimRight(row[col:col+2], " "); got != tc.expect {
+			t.Errorf("%d: cell (%d, %d): expected %q: got %q\n", tc.id, tc.x, tc.y, tc.expect, got)
+		}
+	}
+
+	table := c.systemTable(n)
+	if len(table) != 4 {
+		t.Fatalf("table: expected 4 lines: got %d\n", len(table))
+	}
+	for i, name := range []string{"Home", "Near", "Far"} {
+		if !strings.HasPrefix(table[i+1], name+" ") {
+			t.Errorf("table: row %d: expected %q: got %q\n", i+1, name, table[i+1])
+		}
+	}
+}
@@ -0,0 +1,289 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/orders"
+	"strconv"
+	"strings"
+)
+
+// colonySensors is the range of the sensors at a colony founded by
+// unloading people in a system where the nation has no colony.
+const colonySensors = 2.0
+
+// Supply is a shipment that a ship repeats until it is given a move order.
+// The ship loads the cargo at From, carries it to To, unloads all of it
+// there, and returns to From for the next load.
+type Supply struct {
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	Cargo    string `json:"cargo"`    // "people" or "goods"
+	Quantity int    `json:"quantity"` // loaded each trip, if there is room
+}
+
+// hold returns the amount of the cargo aboard the ship.
+func (sh *Ship) hold(cargo string) *int {
+	if cargo == "people" {
+		return &sh.People
+	}
+	return &sh.Goods
+}
+
+// stock returns the amount of the cargo in the colony.
+func (col *Colony) stock(cargo string) *int {
+	if cargo == "people" {
+		return &col.Population
+	}
+	return &col.Goods
+}
+
+// room limits the quantity of the cargo to what the ship can take on.
+// People are limited by both the holds and the life support. It returns
+// the quantity and, if it is less than asked for, the reason.
+func (sh *Ship) room(d *Design, cargo string, qty int) (int, string) {
+	why := ""
+	if free := d.Cargo - sh.People - sh.Goods; free < qty {
+		qty, why = free, "not enough room"
+	}
+	if free := d.LifeSupport - sh.People; cargo == "people" && free < qty {
+		qty, why = free, "not enough life support"
+	}
+	if qty < 0 {
+		qty = 0
+	}
+	return qty, why
+}
+
+// manifest returns the ship's cargo for reports, or an empty string if
+// its holds are empty.
+func (sh *Ship) manifest() string {
+	var list []string
+	if sh.People != 0 {
+		list = append(list, fmt.Sprintf("%d people", sh.People))
+	}
+	if sh.Goods != 0 {
+		list = append(list, fmt.Sprintf("%d goods", sh.Goods))
+	}
+	if len(list) == 0 {
+		return ""
+	}
+	return "carrying " + strings.Join(list, " and ")
+}
+
+// cargoShip returns the nation's ship for a cargo order and its design.
+// It returns a message if the ship can't handle cargo now.
+func (t *turn) cargoShip(n *Nation, id string) (*Ship, *Design, string) {
+	sh := n.ship(id)
+	if sh == nil {
+		return nil, nil, "no such ship"
+	}
+	d, ok := t.state.Designs[sh.Design]
+	if !ok || d.Cargo <= 0 {
+		return nil, nil, "ship has no cargo space"
+	} else if sh.InTransit() {
+		return nil, nil, "ship is in transit"
+	}
+	return sh, d, ""
+}
+
+// loadCargo moves up to qty of the cargo from the colony to the ship.
+// It returns the quantity moved and, if it is less than qty, the reason.
+func loadCargo(sh *Ship, d *Design, col *Colony, cargo string, qty int) (int, string) {
+	qty, why := sh.room(d, cargo, qty)
+	if stock := *col.stock(cargo); stock < qty {
+		qty, why = stock, "not enough "+cargo
+	}
+	*col.stock(cargo) -= qty
+	*sh.hold(cargo) += qty
+	return qty, why
+}
+
+// unloadCargo moves up to qty of the cargo from the ship to the nation's
+// colony in the ship's system. Unloading people where the nation has no
+// colony founds one. It returns the quantity moved and, if it is less
+// than qty, the reason.
+func unloadCargo(n *Nation, sh *Ship, cargo string, qty int) (int, string) {
+	col := n.colony(sh.System)
+	if col == nil && cargo == "goods" {
+		return 0, "no colony in the system"
+	}
+	why := ""
+	if aboard := *sh.hold(cargo); aboard < qty {
+		qty, why = aboard, "not enough "+cargo+" aboard"
+	}
+	if qty == 0 {
+		return 0, why
+	}
+	if col == nil {
+		col = &Colony{System: sh.System, Sensors: colonySensors}
+		n.Colonies = append(n.Colonies, col)
+	}
+	*sh.hold(cargo) -= qty
+	*col.stock(cargo) += qty
+	return qty, why
+}
+
+// load implements the "load SHIP CARGO QUANTITY" order.
+// Cargo is loaded from the nation's colony in the ship's system.
+// If there isn't enough cargo or room, the ship loads as much as it can.
+func (t *turn) load(n *Nation, o *orders.Order) string {
+	sh, d, msg := t.cargoShip(n, o.Args[0])
+	if msg != "" {
+		return msg
+	}
+	col := n.colony(sh.System)
+	if col == nil {
+		return "no colony in the system"
+	}
+	qty, _ := strconv.Atoi(o.Args[2])
+	if moved, why := loadCargo(sh, d, col, strings.ToLower(o.Args[1]), qty); why != "" {
+		return why + ": loaded " + strconv.Itoa(moved)
+	}
+	return ""
+}
+
+// unload implements the "unload SHIP CARGO QUANTITY" order.
+// Cargo is unloaded into the nation's colony in the ship's system.
+// People may be unloaded anywhere; they found a colony if there isn't one.
+func (t *turn) unload(n *Nation, o *orders.Order) string {
+	sh, _, msg := t.cargoShip(n, o.Args[0])
+	if msg != "" {
+		return msg
+	}
+	cargo := strings.ToLower(o.Args[1])
+	if cargo == "goods" && n.colony(sh.System) == nil {
+		return "no colony in the system"
+	}
+	qty, _ := strconv.Atoi(o.Args[2])
+	if moved, why := unloadCargo(n, sh, cargo, qty); why != "" {
+		return why + ": unloaded " + strconv.Itoa(moved)
+	}
+	return ""
+}
+
+// transfer implements the "transfer SHIP SHIP CARGO QUANTITY" order.
+// Cargo moves from the first ship to the second, which must be in the
+// same system. If there isn't enough cargo or room, as much as possible moves.
+func (t *turn) transfer(n *Nation, o *orders.Order) string {
+	from, _, msg := t.cargoShip(n, o.Args[0])
+	if msg != "" {
+		return msg
+	}
+	to, d, msg := t.cargoShip(n, o.Args[1])
+	if msg != "" {
+		return msg
+	} else if from == to {
+		return "can't transfer cargo to the same ship"
+	} else if from.System != to.System {
+		return "ships are not in the same system"
+	}
+	cargo := strings.ToLower(o.Args[2])
+	qty, _ := strconv.Atoi(o.Args[3])
+	qty, why := to.room(d, cargo, qty)
+	if aboard := *from.hold(cargo); aboard < qty {
+		qty, why = aboard, "not enough "+cargo+" aboard"
+	}
+	*from.hold(cargo) -= qty
+	*to.hold(cargo) += qty
+	if why != "" {
+		return why + ": transferred " + strconv.Itoa(qty)
+	}
+	return ""
+}
+
+// supply implements the "supply SHIP FROM TO CARGO QUANTITY" order.
+// The ship starts the route this turn (see logistics).
+func (t *turn) supply(n *Nation, o *orders.Order) string {
+	sh, d, msg := t.cargoShip(n, o.Args[0])
+	if msg != "" {
+		return msg
+	}
+	from, ok := t.cluster.Lookup(o.Args[1])
+	if !ok || !n.Knows(from) {
+		return "no such system: " + o.Args[1]
+	}
+	to, ok := t.cluster.Lookup(o.Args[2])
+	if !ok || !n.Knows(to) {
+		return "no such system: " + o.Args[2]
+	} else if from == to {
+		return "the route must join two systems"
+	}
+	cargo := strings.ToLower(o.Args[3])
+	if cargo == "people" && d.LifeSupport <= 0 {
+		return "ship has no life support"
+	}
+	qty, _ := strconv.Atoi(o.Args[4])
+	sh.Supply = &Supply{From: from, To: to, Cargo: cargo, Quantity: qty}
+	return ""
+}
+
+// logistics runs the supply routes of the ships that aren't in transit.
+// A ship at the end of its route unloads everything and heads back, a
+// ship at the start loads and sets out, and a ship anywhere else heads
+// for the start. Problems stop the route.
+func (t *turn) logistics() {
+	for _, id := range t.nations {
+		n, sect := t.state.Nations[id], t.shipments[id]
+		for _, sh := range n.Ships {
+			sup := sh.Supply
+			if sup == nil || sh.InTransit() {
+				continue
+			}
+			d, ok := t.state.Designs[sh.Design]
+			if !ok {
+				continue
+			}
+			dest := sup.From
+			switch sh.System {
+			case sup.To:
+				if moved, why := unloadCargo(n, sh, sup.Cargo, *sh.hold(sup.Cargo)); why != "" {
+					sect.Printf("%s: %s in %s: supply route stopped", sh, why, t.cluster.SystemName(sh.System))
+					sh.Supply = nil
+					continue
+				} else if moved != 0 {
+					sect.Printf("%s: unloaded %d %s in %s", sh, moved, sup.Cargo, t.cluster.SystemName(sh.System))
+				}
+			case sup.From:
+				col := n.colony(sh.System)
+				if col == nil {
+					sect.Printf("%s: no colony in %s: supply route stopped", sh, t.cluster.SystemName(sh.System))
+					sh.Supply = nil
+					continue
+				}
+				if want := sup.Quantity - *sh.hold(sup.Cargo); want > 0 {
+					moved, why := loadCargo(sh, d, col, sup.Cargo, want)
+					if why != "" {
+						why = " (" + why + ")"
+					}
+					sect.Printf("%s: loaded %d %s in %s%s", sh, moved, sup.Cargo, t.cluster.SystemName(sh.System), why)
+				}
+				dest = sup.To
+			}
+			path, _, ok := t.cluster.route(sh.System, dest, n.Knows)
+			if !ok {
+				sect.Printf("%s: no known lanes lead to %s: supply route stopped", sh, t.cluster.SystemName(dest))
+				sh.Supply = nil
+				continue
+			}
+			sh.follow(path)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"github.com/mdhender/wraithe/pkg/orders"
+	"testing"
+)
+
+// section returns the lines of the report section with the title.
+func section(r *Report, title string) []string {
+	for _, s := range r.Sections {
+		if s.Title == title {
+			return s.Lines
+		}
+	}
+	return nil
+}
+
+func TestCargo(t *testing.T) {
+	c := &Cluster{systems: []*system{
+		{name: "Home", home: true, stars: []*star{{}}},
+		{name: "Kessa", coords: coords{x: 3}, stars: []*star{{}}},
+	}}
+	c.link()
+	s, err := NewState(c, "alpha")
+	if err != nil {
+		t.Fatalf("state: %v\n", err)
+	}
+
+	play := func(src string) *Report {
+		list, errs := orders.Parse([]byte(src))
+		if len(errs) != 0 {
+			t.Fatalf("turn %d: orders: %v\n", s.Turn+1, errs)
+		}
+		var reports map[string]*Report
+		s, reports = Process(c, s, map[string][]*orders.Order{"alpha": list}, 1)
+		return reports["alpha"]
+	}
+	expectLines := func(r *Report, title string, expect ...string) {
+		got := section(r, title)
+		if len(got) != len(expect) {
+			t.Fatalf("turn %d: %s: expected %q: got %q\n", r.Turn, title, expect, got)
+		}
+		for i := range expect {
+			if got[i] != expect[i] {
+				t.Errorf("turn %d: %s: expected %q: got %q\n", r.Turn, title, expect[i], got[i])
+			}
+		}
+	}
+
+	// a nation can afford one transport a turn
+	r := play("build transport 1") // S3
+	expectLines(r, "Order Errors")
+	r = play("build transport 2") // S4
+	expectLines(r, "Order Errors", `line 1: build transport 2: not enough industry: built 1`)
+
+	r = play(`load S3 people 30
+load S3 goods 40
+load S4 goods 25
+transfer S4 S3 goods 5
+transfer S3 S1 goods 5
+move S3 Kessa`)
+	expectLines(r, "Order Errors",
+		`line 1: load S3 people 30: not enough life support: loaded 20`,
+		`line 2: load S3 goods 40: not enough room: loaded 30`,
+		`line 3: load S4 goods 25: not enough goods: loaded 20`,
+		`line 4: transfer S4 S3 goods 5: not enough goods aboard: transferred 0`, // transfers run before loads, so S4 is still empty
+		`line 5: transfer S3 S1 goods 5: ship has no cargo space`,
+	)
+	if lines := section(r, "Ships"); len(lines) != 4 || lines[2] != "S3 (transport): in transit from Home to Kessa, at   2  0  0, carrying 20 people and 30 goods" {
+		t.Errorf("ships: expected S3 with its cargo: got %q\n", lines)
+	}
+	home := s.Nations["alpha"].colony(0)
+	if home.Population != 80 || home.Goods != 0 {
+		t.Errorf("load: expected 80 people and no goods at home: got %d %d\n", home.Population, home.Goods)
+	}
+
+	play("")
+	r = play(`unload S3 goods 30
+unload S3 people 20
+unload S3 goods 30`)
+	expectLines(r, "Order Errors", `line 1: unload S3 goods 30: no colony in the system`)
+	kessa := s.Nations["alpha"].colony(1)
+	if kessa == nil || kessa.Population != 20 || kessa.Goods != 30 {
+		t.Fatalf("unload: expected a colony in Kessa with 20 people and 30 goods: got %+v\n", kessa)
+	}
+
+	// S4 carries goods to Kessa until it is moved
+	r = play("supply S4 Home Kessa goods 10")
+	expectLines(r, "Shipments") // S4 already has 20 goods aboard
+	for turn := 0; turn < 2; turn++ {
+		r = play("")
+	}
+	expectLines(r, "Shipments", "S4 (transport): unloaded 20 goods in Kessa")
+	if kessa = s.Nations["alpha"].colony(1); kessa.Goods != 50 {
+		t.Errorf("supply: expected 50 goods in Kessa: got %d\n", kessa.Goods)
+	}
+	for turn := 0; turn < 2; turn++ {
+		r = play("")
+	}
+	expectLines(r, "Shipments", "S4 (transport): loaded 0 goods in Home (not enough goods)")
+	if sh := s.Nations["alpha"].ship("S4"); !sh.InTransit() || sh.Supply == nil {
+		t.Errorf("supply: expected S4 to be on its way to Kessa: got %+v\n", sh)
+	}
+	play("") // arrives in Kessa
+	r = play("move S4 Home")
+	expectLines(r, "Shipments")
+	if sh := s.Nations["alpha"].ship("S4"); sh.Supply != nil {
+		t.Errorf("supply: expected the move order to stop the route: got %+v\n", sh.Supply)
+	}
+}
@@ -24,16 +24,27 @@ import (
 	"html/template"
 	"log"
 	"path/filepath"
+	"sync"
 )
 
-// cluster is a container for the systems
-type cluster struct {
+// Cluster is a container for the systems
+type Cluster struct {
 	prng    *prng.PRNG
 	systems []*system
+	// spatial indexes the systems by location.
+	spatial struct {
+		sync.Once
+		tree *kdTree
+	}
+	// names maps lower case system names to ids for Lookup.
+	names struct {
+		sync.Once
+		ids map[string]int
+	}
 }
 
 // ToHTML returns a pretty picture of the cluster.
-func (c *cluster) ToHTML(templates string, tname string, tfm template.FuncMap) ([]byte, error) {
+func (c *Cluster) ToHTML(templates string, tname string, tfm template.FuncMap) ([]byte, error) {
 	type System struct {
 		Ring    int
 		Size    int
@@ -45,22 +56,7 @@ func (c *cluster) ToHTML(templates string, tname string, tfm template.FuncMap) (
 	}
 
 	for _, sys := range c.systems {
-		var color string
-		switch len(sys.stars) {
-		case 5:
-			color = "red"
-		case 4:
-			color = "teal"
-		case 3:
-			color = "blue"
-		case 2:
-			color = "green"
-		case 1:
-			color = "silver"
-		default:
-			color = "grey"
-		}
-		data.Systems = append(data.Systems, &System{Ring: sys.ring, X: sys.coords.x, Y: sys.coords.y, Z: sys.coords.z, Size: len(sys.stars), Color: color})
+		data.Systems = append(data.Systems, &System{Ring: sys.ring, X: sys.coords.x, Y: sys.coords.y, Z: sys.coords.z, Size: len(sys.stars), Color: sys.color()})
 	}
 	log.Printf("[cluster] len(data.Systems) is %d\n", len(data.Systems))
 
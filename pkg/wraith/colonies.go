@@ -16,27 +16,23 @@
  * along with this program.  If not, see <https://www.gnu.org/licenses/>.
  */
 
-package main
+package wraith
 
-import (
-	"fmt"
-	"net/http"
-)
-
-func (s *server) routes() *http.ServeMux {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", s.handleHome)
-
-	return mux
+// Colony is a settlement of a nation in a system.
+// A nation has at most one colony in a system.
+type Colony struct {
+	System     int     `json:"system"`
+	Sensors    float64 `json:"sensors,omitempty"` // range of the colony's sensors in light years
+	Population int     `json:"population,omitempty"`
+	Goods      int     `json:"goods,omitempty"`
 }
 
-func (s *server) handleHome(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path != "/" {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
-		return
-	} else if r.Method != "GET" {
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-		return
+// colony returns the nation's colony in the system.
+func (n *Nation) colony(system int) *Colony {
+	for _, col := range n.Colonies {
+		if col.System == system {
+			return col
+		}
 	}
-	_, _ = fmt.Fprintf(w, "<title>Home</title><body><h1>Welcome")
+	return nil
 }
@@ -0,0 +1,200 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Sensors detect foreign ships and colonies within their range.
+// The chance of detecting a target, in percent, is
+//
+//	100 - stealth - 50 * distance / range
+//
+// using the sensor with the best chance. Dust clouds block sensors.
+// A target within half the range of a sensor is identified: its owner
+// is known and its size is exact. Otherwise the size is an estimate.
+// Sensors also chart every system in range.
+
+// Contact is a foreign fleet or colony that a nation detected during a turn.
+type Contact struct {
+	Kind   string  `json:"kind"`   // "ships" or "colony"
+	System int     `json:"system"` // id of the system, or -1 between systems
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Z      float64 `json:"z"`
+	Size   int     `json:"size,omitempty"`  // estimated number of ships
+	Owner  string  `json:"owner,omitempty"` // set if the owner was identified
+}
+
+// sensor is a ship or colony that can detect things.
+type sensor struct {
+	pos    coords
+	radius float64 // range in light years
+}
+
+// target is something that can be detected: a colony, or ships that
+// travel together.
+type target struct {
+	owner   *Nation
+	kind    string
+	system  int // -1 between systems
+	pos     coords
+	size    int
+	stealth int
+}
+
+// sensors returns the nation's sensors.
+func (s *State) sensors(c *Cluster, n *Nation) []sensor {
+	var list []sensor
+	for _, col := range n.Colonies {
+		if col.Sensors > 0 {
+			list = append(list, sensor{pos: c.systems[col.System].coords, radius: col.Sensors})
+		}
+	}
+	for _, sh := range n.Ships {
+		if d, ok := s.Designs[sh.Design]; ok && d.Sensors > 0 {
+			list = append(list, sensor{pos: sh.position(c), radius: d.Sensors})
+		}
+	}
+	return list
+}
+
+// targets returns the nation's colonies and ships as targets.
+// Ships in the same place going to the same system are one target,
+// which is as stealthy as its least stealthy ship.
+func (s *State) targets(c *Cluster, n *Nation) []*target {
+	var list []*target
+	for _, col := range n.Colonies {
+		list = append(list, &target{owner: n, kind: "colony", system: col.System, pos: c.systems[col.System].coords})
+	}
+	type key struct {
+		from, to int
+		traveled float64
+	}
+	groups := make(map[key]*target)
+	for _, sh := range n.Ships {
+		stealth := 0
+		if d, ok := s.Designs[sh.Design]; ok {
+			stealth = d.Stealth
+		}
+		k := key{from: sh.System, to: sh.Destination, traveled: sh.Traveled}
+		if tg, ok := groups[k]; ok {
+			tg.size++
+			if stealth < tg.stealth {
+				tg.stealth = stealth
+			}
+			continue
+		}
+		tg := &target{owner: n, kind: "ships", system: sh.System, pos: sh.position(c), size: 1, stealth: stealth}
+		if sh.InTransit() {
+			tg.system = -1
+		}
+		groups[k] = tg
+		list = append(list, tg)
+	}
+	return list
+}
+
+// detection runs every nation's sensors. It charts the systems in range
+// and replaces the nation's contacts with the targets it detects.
+func (t *turn) detection() {
+	for _, id := range t.nations {
+		n := t.state.Nations[id]
+		n.Contacts = nil
+		sensors := t.state.sensors(t.cluster, n)
+		for _, sn := range sensors {
+			for _, nb := range t.cluster.index().within(sn.pos, sn.radius) {
+				if !t.cluster.obscured(sn.pos, nb.sys.coords) {
+					n.Learn(nb.sys.id)
+				}
+			}
+		}
+		for _, other := range t.state.nationIds() {
+			if other == id {
+				continue
+			}
+			for _, tg := range t.state.targets(t.cluster, t.state.Nations[other]) {
+				if ct, ok := t.cluster.detect(t.rng, sensors, tg); ok {
+					n.Contacts = append(n.Contacts, ct)
+				}
+			}
+		}
+	}
+}
+
+// detect rolls to detect the target with the sensors.
+// It returns false if the target isn't detected.
+func (c *Cluster) detect(r *rand.Rand, sensors []sensor, tg *target) (*Contact, bool) {
+	chance, identified := 0.0, false
+	for _, sn := range sensors {
+		d := sn.pos.distance(tg.pos)
+		if d > sn.radius || c.obscured(sn.pos, tg.pos) {
+			continue
+		}
+		chance = math.Max(chance, 100-float64(tg.stealth)-50*d/sn.radius)
+		identified = identified || d <= sn.radius/2
+	}
+	if chance <= 0 || float64(r.Intn(100)) >= chance {
+		return nil, false
+	}
+	ct := &Contact{
+		Kind:   tg.kind,
+		System: tg.system,
+		X:      math.Round(tg.pos.x*10) / 10,
+		Y:      math.Round(tg.pos.y*10) / 10,
+		Z:      math.Round(tg.pos.z*10) / 10,
+		Size:   tg.size,
+	}
+	if identified {
+		ct.Owner = tg.owner.Id
+	} else if tg.size != 0 {
+		// off by up to half either way
+		ct.Size = tg.size + r.Intn(tg.size+1) - tg.size/2
+		if ct.Size < 1 {
+			ct.Size = 1
+		}
+	}
+	return ct, true
+}
+
+// describe returns the contact for a text report.
+func (ct *Contact) describe(c *Cluster) string {
+	where := fmt.Sprintf("between systems at %s", coords{x: ct.X, y: ct.Y, z: ct.Z}.xyz())
+	if ct.System >= 0 {
+		where = "in " + c.SystemName(ct.System)
+	}
+	owner := "owner unknown"
+	if ct.Owner != "" {
+		owner = ct.Owner
+	}
+	if ct.Kind == "colony" {
+		return fmt.Sprintf("colony (%s) %s", owner, where)
+	}
+	ships := fmt.Sprintf("%d ships", ct.Size)
+	if ct.Size == 1 {
+		ships = "1 ship"
+	}
+	if ct.Owner == "" {
+		ships = "about " + ships
+	}
+	return fmt.Sprintf("%s (%s) %s", ships, owner, where)
+}
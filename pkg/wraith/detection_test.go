@@ -0,0 +1,148 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestDetection(t *testing.T) {
+	c := &Cluster{systems: []*system{
+		{name: "Home", home: true, stars: []*star{{}}},
+		{name: "Near", coords: coords{x: 1}, stars: []*star{{}}},
+		{name: "Edge", coords: coords{x: 4}, stars: []*star{{}}},
+		{name: "Far", coords: coords{x: 12}, stars: []*star{{}}},
+		{name: "Haze", coords: coords{y: -3}, anomaly: dustCloud},
+		{name: "Hidden", coords: coords{y: -6}, stars: []*star{{}}},
+	}}
+	c.link()
+	s := &State{Designs: map[string]*Design{
+		"scout": {Name: "scout", Speed: 3, Sensors: 2},
+		"ghost": {Name: "ghost", Speed: 3, Stealth: 100},
+		"barge": {Name: "barge", Speed: 1},
+	}, Nations: map[string]*Nation{
+		"alpha": {Id: "alpha", Colonies: []*Colony{{System: 0, Sensors: 10}}},
+		"beta":  {Id: "beta"},
+	}}
+	beta := s.Nations["beta"]
+	for i, sh := range []*Ship{
+		{Design: "barge", System: 1, Destination: 1},
+		{Design: "barge", System: 1, Destination: 1},
+		{Design: "ghost", System: 1, Destination: 1},
+		{Design: "barge", System: 3, Destination: 3},
+		{Design: "barge", System: 5, Destination: 5},
+		{Design: "barge", System: 2, Destination: 3, Traveled: 4},
+	} {
+		sh.Id = string(rune('A' + i))
+		beta.Ships = append(beta.Ships, sh)
+	}
+	beta.Colonies = []*Colony{{System: 2}}
+
+	// the rolls depend on the seed, so run the pass with many seeds.
+	// Far is out of range and Hidden is behind the dust cloud. The ghost
+	// travels with barges, so the fleet in Near is seen and identified.
+	// The ship between systems is beyond half the range of the sensor.
+	alpha := s.Nations["alpha"]
+	seen := make(map[string]int)
+	for seed := int64(1); seed <= 20; seed++ {
+		tr := &turn{cluster: c, state: s, nations: s.nationIds(), rng: rand.New(rand.NewSource(seed))}
+		tr.detection()
+		for _, ct := range alpha.Contacts {
+			switch line := ct.describe(c); line {
+			case "colony (beta) in Edge", "3 ships (beta) in Near":
+				seen[line]++
+			case "about 1 ship (owner unknown) between systems at   8  0  0", "about 2 ships (owner unknown) between systems at   8  0  0":
+				seen["between systems"]++
+			default:
+				t.Errorf("seed %d: contacts: unexpected %q\n", seed, line)
+			}
+		}
+	}
+	for _, key := range []string{"colony (beta) in Edge", "3 ships (beta) in Near", "between systems"} {
+		if seen[key] == 0 {
+			t.Errorf("contacts: expected %q: got none\n", key)
+		}
+	}
+
+	// sensors chart systems in range, but not through dust
+	for _, tc := range []struct {
+		id     int
+		system int
+		known  bool
+	}{
+		{1, 2, true},
+		{2, 3, false},
+		{3, 4, false},
+		{4, 5, false},
+	} {
+		if got := alpha.Knows(tc.system); got != tc.known {
+			t.Errorf("%d: knows %s: expected %v: got %v\n", tc.id, c.systems[tc.system].name, tc.known, got)
+		}
+	}
+
+	// beta has no sensors
+	if len(beta.Contacts) != 0 {
+		t.Errorf("contacts: expected none without sensors: got %d\n", len(beta.Contacts))
+	}
+}
+
+func TestDetectStealth(t *testing.T) {
+	c := &Cluster{systems: []*system{{name: "Home", home: true, stars: []*star{{}}}}}
+	c.link()
+	sensors := []sensor{{radius: 4}}
+	r := rand.New(rand.NewSource(7))
+	for i := 0; i < 100; i++ {
+		if _, ok := c.detect(r, sensors, &target{owner: &Nation{}, kind: "ships", size: 1, stealth: 100}); ok {
+			t.Fatalf("detect: expected full stealth to hide the ship: got a contact\n")
+		}
+		if ct, ok := c.detect(r, sensors, &target{owner: &Nation{Id: "beta"}, kind: "ships", size: 3}); !ok {
+			t.Fatalf("detect: expected to see a ship next to the sensor: got nothing\n")
+		} else if ct.Owner != "beta" || ct.Size != 3 {
+			t.Fatalf("detect: expected 3 ships of beta: got %d of %q\n", ct.Size, ct.Owner)
+		}
+	}
+	detected := 0
+	for i := 0; i < 1000; i++ {
+		if _, ok := c.detect(r, sensors, &target{owner: &Nation{}, kind: "ships", pos: coords{x: 4}, size: 1, stealth: 20}); ok {
+			detected++
+		}
+	}
+	// 100 - 20 - 50 at the edge of the range
+	if detected < 250 || detected > 350 {
+		t.Errorf("detect: expected about 300 of 1000 at the edge: got %d\n", detected)
+	}
+}
+
+func TestContactDescribe(t *testing.T) {
+	c := &Cluster{systems: []*system{{name: "Home", home: true, stars: []*star{{}}}, {name: "Kessa", coords: coords{x: 3}}}}
+	for _, tc := range []struct {
+		id      int
+		contact Contact
+		expect  string
+	}{
+		{1, Contact{Kind: "ships", System: 1, Size: 3, Owner: "beta"}, "3 ships (beta) in Kessa"},
+		{2, Contact{Kind: "ships", System: -1, X: 1.4, Y: 2, Z: -1, Size: 1}, "about 1 ship (owner unknown) between systems at   1  2 -1"},
+		{3, Contact{Kind: "colony", System: 0}, "colony (owner unknown) in Home"},
+	} {
+		if got := tc.contact.describe(c); got != tc.expect {
+			t.Errorf("%d: describe: expected %q: got %q\n", tc.id, tc.expect, got)
+		}
+	}
+}
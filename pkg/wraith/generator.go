@@ -19,184 +19,627 @@
 package wraith
 
 import (
-	"log"
+	"fmt"
 	"math"
 	"math/rand"
+	"sort"
 )
 
 // note: we use the term "ring" to include the stars and systems that are
 //       a given distance from the origin of the cluster. ring zero is the
 //       origin, ring 1 is 1 light year from it, etc.
 
-var (
-	// systems are not uniformly distributed in the cluster.
-	// instead they are generated based on the ring they're in.
-	//expectedSystemsPerRing [16]int = [16]int{1, 4, 6, 8, 9, 11, 13, 11, 10, 9, 8, 8, 7, 6, 5, 5}
-	systemsPerRing = [16]int{1, 6, 8, 8, 9, 9, 8, 6, 6, 6, 6, 6, 4, 4, 3, 3}
-)
+// Generator creates new clusters.
+//
+// Every generator must honor the same invariants, which Generate checks:
+//   - system 0 is a home system at the origin
+//   - there are at least Params.Homes home systems, each with one star
+//   - no other system is closer to a home system than Params.Clearance
+//   - no system is farther from the origin than Params.Radius
+//   - systems are at least 1 light year apart, and systems with stars
+//     are at least 2 light years per star (plus 2) from their neighbors
+//     (see separation)
+type Generator interface {
+	// Generate returns a new cluster.
+	// The generator must use only the seed in the parameters for
+	// random numbers so that clusters can be re-created.
+	Generate(p Params) (*Cluster, error)
+}
 
-// a cluster has a radius of about 15.
+// Params are the parameters shared by every generator.
+// Generators that need more have their own parameters in their structs.
+type Params struct {
+	// Seed is the seed for the generator's random numbers.
+	Seed int64
+	// Systems is the number of systems in the cluster, including the home systems.
+	Systems int
+	// Homes is the number of home systems, one for each nation.
+	// Zero means one.
+	Homes int
+	// MinStars is the minimum number of stars in the cluster.
+	MinStars int
+	// Radius is the radius of the cluster in light years.
+	Radius float64
+	// Clearance is the minimum distance from a home system to any other system.
+	Clearance float64
+	// File is the map read by the file generator.
+	File string
+	// Lanes is the longest jump lane in light years.
+	// Zero means that the cluster has no lanes and ships fly anywhere.
+	Lanes float64
+	// Stars is the distribution of star types and sizes.
+	// The zero value uses DefaultStarDistribution.
+	Stars StarDistribution
+	// Anomalies is the relative frequency of each anomaly in systems
+	// without stars. Nil uses DefaultAnomalies.
+	Anomalies map[string]int
+}
 
-// F generates a new cluster.
-// `n` is the number of players (nations) to populate.
-func F(minStars int) *cluster {
-	c := cluster{}
+// DefaultParams returns the parameters used by the create command.
+func DefaultParams() Params {
+	return Params{Systems: 512, Homes: 1, MinStars: 128, Radius: 15, Clearance: 4.5}
+}
 
-	totalSystems := 0
-	for _, n := range systemsPerRing {
-		totalSystems += n
+// homes returns the number of home systems, treating zero as one.
+func (p Params) homes() int {
+	if p.Homes == 0 {
+		return 1
 	}
-	log.Printf("[f] generating cluster with %3d systems and %3d stars\n", totalSystems, minStars)
-
-	// the "home world" is always in the "home system" which is always at (0,0,0).
-	// it always contains 1 star and 10 planets.
-	// the resources on the home world are determined by the number of players in the game.
-	c.systems = append(c.systems, &system{ring: 0, coords: coords{0, 0, 0}})
+	return p.Homes
+}
 
-	// the first ring always contains six systems, one at each cardinal point.
-	c.systems = append(c.systems, &system{ring: 1, coords: coords{1, 0, 0}})
-	c.systems = append(c.systems, &system{ring: 1, coords: coords{-1, 0, 0}})
-	c.systems = append(c.systems, &system{ring: 1, coords: coords{0, 1, 0}})
-	c.systems = append(c.systems, &system{ring: 1, coords: coords{0, -1, 0}})
-	c.systems = append(c.systems, &system{ring: 1, coords: coords{0, 0, 1}})
-	c.systems = append(c.systems, &system{ring: 1, coords: coords{0, 0, -1}})
+// maxStars returns the most stars the generators can place in the systems:
+// one in every home system and as many in the others as nextStars allows.
+func (p Params) maxStars() int {
+	stars := p.homes()
+	for n := 1; n <= p.Systems-p.homes(); n++ {
+		stars += starsFor(n)
+	}
+	return stars
+}
 
-	for ring, expectedSystems := range systemsPerRing {
-		if ring == 0 || ring == 1 {
-			continue
+// Validate returns an error if the parameters are not usable.
+func (p Params) Validate() error {
+	if p.Systems < 1 {
+		return fmt.Errorf("systems must be at least 1")
+	} else if p.Homes < 0 || p.Homes > p.Systems {
+		return fmt.Errorf("homes must be between 1 and %d", p.Systems)
+	} else if p.MinStars < p.homes() || p.MinStars > p.maxStars() {
+		return fmt.Errorf("min-stars must be between %d and %d", p.homes(), p.maxStars())
+	} else if p.Clearance < 0 {
+		return fmt.Errorf("clearance must not be negative")
+	} else if p.Lanes < 0 {
+		return fmt.Errorf("lanes must not be negative")
+	} else if p.Radius <= p.Clearance {
+		return fmt.Errorf("radius must be greater than the clearance (%g)", p.Clearance)
+	} else if p.Anomalies != nil {
+		if err := validateAnomalies(p.Anomalies); err != nil {
+			return err
 		}
+	}
+	return p.Stars.Validate()
+}
 
-		log.Printf("[f] generating ring %2d with %2d systems\n", ring, expectedSystems)
+// generators are the registered generators.
+// The map key is the name used to select the generator.
+var generators = map[string]Generator{
+	"rings":     &Rings{},
+	"sphere":    &Sphere{},
+	"dense":     &Sphere{Dense: true},
+	"spiral":    &Spiral{Arms: 2, Twist: 1.25 * math.Pi, Spread: 0.12, Thickness: 0.2},
+	"clustered": &Clustered{Clumps: 6, Spread: 0.2},
+	"file":      &File{},
+}
 
-		for i := 0; i < expectedSystems; i++ {
-			sys := &system{ring: ring}
+// Register adds a generator, replacing any with the same name.
+func Register(name string, g Generator) {
+	generators[name] = g
+}
 
-			// create a location for the system by generating 15 random
-			// points in the ring and using the one that is the furthest
-			// from all existing systems
-			maxDistance := 0.0 // maximum distance of points so far
-			for pn := 0; pn < 15; pn++ {
-				if pt := randomPoint(ring); pn == 0 || maxDistance < minDistance(pt, c.systems) {
-					sys.coords = pt
-				}
-			}
-			sys.coords.x = math.Round(sys.coords.x)
-			sys.coords.y = math.Round(sys.coords.y)
-			sys.coords.z = math.Round(sys.coords.z)
-			log.Println(ring, sys.coords)
+// Generators returns the names of the registered generators, sorted.
+func Generators() []string {
+	var names []string
+	for name := range generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
-			c.systems = append(c.systems, sys)
-		}
+// Generate creates a cluster with the named generator and
+// verifies that it honors the invariants.
+func Generate(name string, p Params) (*Cluster, error) {
+	g, ok := generators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown generator %q", name)
+	}
+	c, err := g.Generate(p)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	} else if err = c.checkInvariants(p); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
 	}
+	return c, nil
+}
 
-	// create one star in every system
-	for _, sys := range c.systems {
-		sys.stars = append(sys.stars, &star{system: sys})
+// G generates a new cluster with the sphere generator.
+// It takes its seed from the default source and panics if the cluster
+// can't be generated, so it is meant for tests and tools.
+func G(homes, minSystems, minStars int, scale float64) *Cluster {
+	p := DefaultParams()
+	p.Seed, p.Homes, p.Systems, p.MinStars, p.Radius = rand.Int63(), homes, minSystems, minStars, scale
+	c, err := Generate("sphere", p)
+	if err != nil {
+		panic(err)
 	}
+	return c
+}
 
-	// add any remaining stars to random systems.
-	for remaining := minStars - len(c.systems); remaining > 0; remaining-- {
-		// pick a system at random. well, sort of random. we don't want binary
-		// systems in rings 0 or 1, so if the pick lands there, pick again.
-		sys := c.systems[rand.Intn(len(c.systems))]
-		for sys.ring == 0 || sys.ring == 1 {
-			sys = c.systems[rand.Intn(len(c.systems))]
-		}
-		// and add a star to it
-		sys.stars = append(sys.stars, &star{system: sys})
+// separation returns the minimum distance from the system to its neighbors.
+// Systems without stars need 1 light year. Systems with stars need 2 plus 2
+// per star. Two systems must be at least the smaller of their separations apart.
+func (sys *system) separation() float64 {
+	if len(sys.stars) == 0 {
+		return 1
 	}
+	return float64(2 + 2*len(sys.stars))
+}
+
+// checkInvariants returns an error if the cluster breaks any of the rules
+// that every generator must honor.
+func (c *Cluster) checkInvariants(p Params) error {
+	if problems := c.violations(p, nil); len(problems) != 0 {
+		return fmt.Errorf("%s", problems[0])
+	}
+	return nil
+}
 
-	totalStars := 0
+// violations returns every way the cluster breaks the invariants.
+// The label function names a system in the messages; if it is nil,
+// systems are named by id.
+func (c *Cluster) violations(p Params, label func(*system) string) (problems []string) {
+	if label == nil {
+		label = func(sys *system) string {
+			return fmt.Sprintf("system %d", sys.id)
+		}
+	}
+	if len(c.systems) == 0 {
+		return []string{"cluster has no systems"}
+	}
+	if origin := c.systems[0]; origin.coords != (coords{}) {
+		problems = append(problems, fmt.Sprintf("%s: home system is at %s, not the origin", label(origin), origin.coords.xyz()))
+	} else if !origin.home {
+		problems = append(problems, fmt.Sprintf("%s: the system at the origin is not a home system", label(origin)))
+	}
+	homes := c.homes()
+	if len(homes) < p.homes() {
+		problems = append(problems, fmt.Sprintf("cluster has %d home systems, not %d", len(homes), p.homes()))
+	}
+	for _, home := range homes {
+		if len(home.stars) != 1 {
+			problems = append(problems, fmt.Sprintf("%s: home system has %d stars, not 1", label(home), len(home.stars)))
+		}
+	}
 	for _, sys := range c.systems {
-		totalStars += len(sys.stars)
-		log.Printf("[ring] %2d: %5.0f,%5.0f,%5.0f %2d stars %4d\n", sys.ring, sys.coords.x, sys.coords.y, sys.coords.z, len(sys.stars), totalStars)
-	}
-
-	return &c
-}
-
-// G generates a new cluster.
-// The cluster will have 128 stars in 256 systems.
-func G(minSystems, minStars int, scale float64) *cluster {
-	const probes = 15
-
-	starsLeft := minStars
-
-	c := cluster{
-		systems: []*system{
-			//{coords: coords{x: 0, y: -15, z: 0}}, // north pole
-			{coords: coords{x: 0, y: 0, z: 0}}, // center
-			//{coords: coords{x: 0, y: 15, z: 0}},  // south pole
-		},
-	}
-
-	// create the systems.
-	// we will add stars to systems until the number left is zero.
-	// the remaining systems will have no stars.
-	// systems with stars can't be closer than 4 light years to another system with stars.
-	// systems without stars can't be closer than 1 light year to another system.
-	for len(c.systems) < minSystems {
-		sys := &system{}
-		if starsLeft > 0 {
-			sys.stars, starsLeft = append(sys.stars, &star{}), starsLeft-1
-			if starsLeft > 0 && len(c.systems) < 28 {
-				sys.stars, starsLeft = append(sys.stars, &star{}), starsLeft-1
-				if starsLeft > 0 && len(c.systems) < 12 {
-					sys.stars, starsLeft = append(sys.stars, &star{}), starsLeft-1
-					if starsLeft > 0 && len(c.systems) < 6 {
-						sys.stars, starsLeft = append(sys.stars, &star{}), starsLeft-1
-						if starsLeft > 0 && len(c.systems) < 3 {
-							sys.stars, starsLeft = append(sys.stars, &star{}), starsLeft-1
-						}
-					}
-				}
+		for _, home := range homes {
+			if home == sys {
+				continue
+			} else if d := sys.coords.distance(home.coords); d < p.Clearance {
+				problems = append(problems, fmt.Sprintf("%s: %s is %.2f ly from %s, less than %g", label(sys), sys.coords.xyz(), d, homeLabel(home, label), p.Clearance))
 			}
 		}
+		if d := sys.coords.distance(coords{}); d > p.Radius {
+			problems = append(problems, fmt.Sprintf("%s: %s is %.2f ly from home, more than %g", label(sys), sys.coords.xyz(), d, p.Radius))
+		}
+	}
+	index := c.index()
+	for _, sys := range c.systems {
+		index.visit(sys.coords, sys.separation(), func(n neighbor) bool {
+			// report each pair once, from the system that comes first
+			if n.sys.id <= sys.id {
+				return true
+			} else if n.distance == 0 {
+				problems = append(problems, fmt.Sprintf("%s: %s: same coordinates as %s", label(n.sys), n.sys.coords.xyz(), label(sys)))
+			} else if limit := math.Min(sys.separation(), n.sys.separation()); n.distance < limit {
+				problems = append(problems, fmt.Sprintf("%s: %s is %.2f ly from %s, less than %g", label(n.sys), n.sys.coords.xyz(), n.distance, label(sys), limit))
+			}
+			return true
+		})
+	}
+	return problems
+}
+
+// homeLabel names a home system in the messages from violations.
+// The home system at the origin is just "home".
+func homeLabel(home *system, label func(*system) string) string {
+	if home.coords == (coords{}) {
+		return "home"
+	}
+	return "home " + label(home)
+}
+
+// placer places systems for the generators.
+// It owns the random numbers, the stars that are left to place,
+// the home systems, and the spatial index used to enforce the
+// separation rules.
+type placer struct {
+	p         Params
+	r         *rand.Rand
+	c         *Cluster
+	index     *kdTree
+	homes     []*system
+	starsLeft int
+}
+
+const (
+	// probes is the number of acceptable points tried for every system.
+	// the one furthest from all the other systems is used.
+	probes = 15
+	// maxSamples limits the search for acceptable points for a system.
+	maxSamples = 10_000
+)
+
+// newPlacer returns a placer with the home systems already placed.
+// The first is at the origin. The others are spread out, as far from each
+// other as possible, close enough to the origin that the space their
+// nations have surveyed is inside the cluster (see homeSurveyRange).
+// Every home system has a single star.
+func newPlacer(p Params) (*placer, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	origin := &system{home: true, coords: coords{}, stars: []*star{{}}}
+	pl := &placer{
+		p:         p,
+		r:         rand.New(rand.NewSource(p.Seed)),
+		c:         &Cluster{systems: []*system{origin}},
+		homes:     []*system{origin},
+		starsLeft: p.MinStars - p.homes(),
+	}
+	pl.index = buildKDTree(pl.c.systems)
+	inner := p.Radius - homeSurveyRange
+	if inner < p.Clearance {
+		inner = p.Radius
+	}
+	near := func() coords {
+		return getPoint(pl.r, inner)
+	}
+	for len(pl.homes) < p.homes() {
+		sys := &system{home: true, stars: []*star{{}}}
+		if err := pl.add(sys, near, pl.anywhere); err != nil {
+			return nil, err
+		}
+		pl.homes = append(pl.homes, sys)
+	}
+	return pl, nil
+}
 
-		// loop until we get a point that isn't near the center or too close to an existing system
-		var ring int
-		var spt coords
-		maxDistance, closestNeighbor := 0.0, float64(2+len(sys.stars)*2)
-		if len(sys.stars) == 0 {
-			closestNeighbor = 1.0
+// nextStars returns the stars for the next system.
+// Systems get stars until the number left is zero, so the remaining systems
+// have no stars (see starsFor).
+func (pl *placer) nextStars() (stars []*star) {
+	for n := starsFor(len(pl.c.systems) - len(pl.homes) + 1); len(stars) < n && pl.starsLeft != 0; {
+		stars, pl.starsLeft = append(stars, &star{}), pl.starsLeft-1
+	}
+	return stars
+}
+
+// starsFor returns the most stars for the nth system after the home systems.
+// The first few systems get up to five stars and the rest get one.
+func starsFor(n int) int {
+	stars := 1
+	for _, limit := range []int{28, 12, 6, 3} {
+		if n < limit {
+			stars++
 		}
-		for probe := 0; probe < probes; {
-			pt := getPoint(scale)
-			if ring = int(math.Round(pt.distance(coords{}))); ring < 5 || minDistance(pt, c.systems) < closestNeighbor {
+	}
+	return stars
+}
+
+// place adds a system at a point returned by a sampler.
+// Points are rounded to whole light years before they are checked.
+// Of the acceptable points, the one furthest from the other systems is used.
+// If a sampler can't find an acceptable point, the next one is tried.
+func (pl *placer) place(samplers ...func() coords) error {
+	return pl.add(&system{stars: pl.nextStars()}, samplers...)
+}
+
+// add places the system at a point returned by a sampler (see place).
+// No point closer to a home system than the clearance is acceptable.
+func (pl *placer) add(sys *system, samplers ...func() coords) error {
+	var best coords
+	found, maxDistance := 0, 0.0
+	for _, sample := range samplers {
+		for n := 0; n < maxSamples && found < probes; n++ {
+			pt := sample().roundToInt()
+			if pt.distance(coords{}) > pl.p.Radius || pl.nearHome(pt) {
+				continue
+			}
+			if !pl.index.visit(pt, sys.separation(), func(n neighbor) bool {
+				return n.distance >= math.Min(sys.separation(), n.sys.separation())
+			}) {
 				continue
 			}
-			if d := minDistance(pt, c.systems); d > maxDistance {
-				spt, maxDistance = pt, d
+			d := pl.index.nearestDistance(pt)
+			if found == 0 || d > maxDistance {
+				best, maxDistance = pt, d
 			}
-			probe++
+			found++
+		}
+		if found != 0 {
+			break
+		}
+	}
+	if found == 0 {
+		return fmt.Errorf("no room for system %d: the cluster is too crowded", len(pl.c.systems))
+	}
+	sys.coords, sys.ring = best, int(math.Round(best.distance(coords{})))
+	pl.c.systems = append(pl.c.systems, sys)
+	pl.index.insert(sys)
+	return nil
+}
+
+// nearHome returns true if the point is closer to a home system than the clearance.
+func (pl *placer) nearHome(pt coords) bool {
+	for _, home := range pl.homes {
+		if pt.distance(home.coords) < pl.p.Clearance {
+			return true
 		}
-		sys.ring = int(math.Round(spt.distance(coords{})))
-		sys.coords = spt.roundToInt()
-		//if starsLeft > 0 {
-		//	log.Printf("[ring] %2d: %7.2f,%7.2f,%7.2f stars: %5d / %5d\n", sys.ring, sys.coords.x, sys.coords.y, sys.coords.z, len(sys.stars), starsLeft)
-		//}
-		c.systems = append(c.systems, sys)
 	}
+	return false
+}
 
-	return &c
+// anywhere returns a random point in the cluster.
+// Generators use it when their own shapes are too crowded.
+func (pl *placer) anywhere() coords {
+	return getPoint(pl.r, pl.p.Radius)
 }
 
-func getPoint(scale float64) coords {
-	u, v, d := rand.Float64(), rand.Float64(), rand.Float64()
-	theta, phi, r := u*2.0*math.Pi, math.Acos(2.0*v-1.0), d //math.Cbrt(d)
-	sinTheta, cosTheta := math.Sin(theta), math.Cos(theta)
-	sinPhi, cosPhi := math.Sin(phi), math.Cos(phi)
-	return coords{x: scale * r * sinPhi * cosTheta, y: scale * r * sinPhi * sinTheta, z: scale * r * cosPhi}
+// finish creates the planets, names, anomalies, and lanes and returns the cluster.
+// It returns an error if the systems couldn't hold all the stars.
+func (pl *placer) finish() (*Cluster, error) {
+	if pl.starsLeft != 0 {
+		return nil, fmt.Errorf("%d systems hold only %d of %d stars", len(pl.c.systems), pl.p.MinStars-pl.starsLeft, pl.p.MinStars)
+	}
+	pl.c.generatePlanets(pl.r, pl.p.Stars)
+	pl.c.generateNames(pl.r)
+	pl.c.generateAnomalies(pl.r, pl.p.Anomalies)
+	if pl.p.Lanes > 0 {
+		pl.c.generateLanes(pl.p.Lanes)
+	}
+	return pl.c, nil
+}
+
+// Sphere places systems randomly in a sphere.
+// Points are spread evenly through the volume of the sphere.
+type Sphere struct {
+	// Dense draws points with a uniform distance from the center instead,
+	// so the cluster is denser toward the middle. This is the original
+	// G generator.
+	Dense bool
 }
 
-// minDistance returns the distance from the coordinates to the nearest system.
-func minDistance(c coords, systems []*system) (d float64) {
-	for i := range systems {
-		if ds := c.distance(systems[i].coords); i == 0 || ds < d {
-			d = ds
+// Generate implements the Generator interface.
+func (g *Sphere) Generate(p Params) (*Cluster, error) {
+	pl, err := newPlacer(p)
+	if err != nil {
+		return nil, err
+	}
+	sample := pl.anywhere
+	if g.Dense {
+		sample = func() coords {
+			return getDensePoint(pl.r, p.Radius)
 		}
 	}
-	return d
+	for len(pl.c.systems) < p.Systems {
+		if err := pl.place(sample); err != nil {
+			return nil, err
+		}
+	}
+	return pl.finish()
+}
+
+// Rings places systems on shells one light year apart, from the clearance
+// out to the radius. The number of systems on each shell follows the
+// profile in systemsPerRing. This is the original F generator.
+type Rings struct{}
+
+// systemsPerRing is the profile for the rings generator.
+// systems are not uniformly distributed in the cluster.
+// instead they are generated based on the ring they're in.
+var systemsPerRing = [16]int{1, 6, 8, 8, 9, 9, 8, 6, 6, 6, 6, 6, 4, 4, 3, 3}
+
+// Generate implements the Generator interface.
+func (g *Rings) Generate(p Params) (*Cluster, error) {
+	pl, err := newPlacer(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// spread the systems over the rings by the profile,
+	// scaling the profile to the radius of the cluster
+	first, last := int(math.Ceil(p.Clearance)), int(math.Floor(p.Radius))
+	if first < 1 {
+		first = 1
+	}
+	if first > last {
+		return nil, fmt.Errorf("no rings between the clearance and the radius")
+	}
+	var rings []int
+	weights, total := make(map[int]float64), 0.0
+	for ring := first; ring <= last; ring++ {
+		weights[ring] = float64(systemsPerRing[ring*(len(systemsPerRing)-1)/last])
+		total += weights[ring]
+	}
+	for ring := first; ring <= last; ring++ {
+		for i := 0; i < int(math.Round(weights[ring]*float64(p.Systems-len(pl.homes))/total)); i++ {
+			rings = append(rings, ring)
+		}
+	}
+	for len(rings) < p.Systems-len(pl.homes) {
+		rings = append(rings, last)
+	}
+	rings = rings[:p.Systems-len(pl.homes)]
+	// place them in random order so that the multi-star systems,
+	// which are placed first, aren't all on the inner rings
+	pl.r.Shuffle(len(rings), func(i, j int) {
+		rings[i], rings[j] = rings[j], rings[i]
+	})
+
+	for _, ring := range rings {
+		// if the ring is full, try the nearest rings that aren't
+		var samplers []func() coords
+		for _, alt := range nearbyRings(ring, first, last) {
+			alt := alt
+			samplers = append(samplers, func() coords { return randomPoint(pl.r, alt) })
+		}
+		if err := pl.place(samplers...); err != nil {
+			return nil, err
+		}
+	}
+	return pl.finish()
+}
+
+// nearbyRings returns the rings from first to last, ordered by their
+// distance from the ring. Outer rings come before inner rings at the same
+// distance because they have more room.
+func nearbyRings(ring, first, last int) []int {
+	list := []int{ring}
+	for delta := 1; ring+delta <= last || ring-delta >= first; delta++ {
+		if ring+delta <= last {
+			list = append(list, ring+delta)
+		}
+		if ring-delta >= first {
+			list = append(list, ring-delta)
+		}
+	}
+	return list
+}
+
+// Spiral places systems in a disc with spiral arms.
+// Most systems are scattered around the arms and the rest fill the disc.
+type Spiral struct {
+	// Arms is the number of spiral arms.
+	Arms int
+	// Twist is how far, in radians, each arm winds from the center to the edge.
+	Twist float64
+	// Spread is the scatter around an arm as a fraction of the radius.
+	Spread float64
+	// Thickness is the thickness of the disc as a fraction of the radius.
+	Thickness float64
+}
+
+// Generate implements the Generator interface.
+func (g *Spiral) Generate(p Params) (*Cluster, error) {
+	if g.Arms < 1 {
+		return nil, fmt.Errorf("arms must be at least 1")
+	}
+	pl, err := newPlacer(p)
+	if err != nil {
+		return nil, err
+	}
+	sample := func() coords {
+		t := pl.r.Float64()
+		radius := p.Clearance + t*(p.Radius-p.Clearance)
+		var theta float64
+		if pl.r.Intn(4) == 0 {
+			// a quarter of the systems are spread around the disc
+			theta = pl.r.Float64() * 2 * math.Pi
+		} else {
+			arm := pl.r.Intn(g.Arms)
+			theta = float64(arm)*2*math.Pi/float64(g.Arms) + g.Twist*t
+			theta += pl.r.NormFloat64() * g.Spread * p.Radius / radius
+		}
+		return coords{
+			x: radius * math.Cos(theta),
+			y: radius * math.Sin(theta),
+			z: pl.r.NormFloat64() * g.Thickness * p.Radius / 2,
+		}
+	}
+	for len(pl.c.systems) < p.Systems {
+		if err := pl.place(sample, pl.anywhere); err != nil {
+			return nil, err
+		}
+	}
+	return pl.finish()
+}
+
+// Clustered places systems in clumps around a few randomly placed centers,
+// leaving empty space between them.
+type Clustered struct {
+	// Clumps is the number of clumps.
+	Clumps int
+	// Spread is the size of a clump as a fraction of the radius.
+	Spread float64
+}
+
+// Generate implements the Generator interface.
+func (g *Clustered) Generate(p Params) (*Cluster, error) {
+	if g.Clumps < 1 {
+		return nil, fmt.Errorf("clumps must be at least 1")
+	}
+	pl, err := newPlacer(p)
+	if err != nil {
+		return nil, err
+	}
+	var centers []coords
+	for len(centers) < g.Clumps {
+		pt := getPoint(pl.r, p.Radius)
+		if d := pt.distance(coords{}); d >= p.Clearance {
+			centers = append(centers, pt)
+		}
+	}
+	sample := func() coords {
+		center, sigma := centers[pl.r.Intn(len(centers))], g.Spread*p.Radius
+		return coords{
+			x: center.x + pl.r.NormFloat64()*sigma,
+			y: center.y + pl.r.NormFloat64()*sigma,
+			z: center.z + pl.r.NormFloat64()*sigma,
+		}
+	}
+	for len(pl.c.systems) < p.Systems {
+		if err := pl.place(sample, pl.anywhere); err != nil {
+			return nil, err
+		}
+	}
+	return pl.finish()
+}
+
+// File loads a hand-authored map (see ImportMap).
+type File struct{}
+
+// Generate implements the Generator interface.
+func (g *File) Generate(p Params) (*Cluster, error) {
+	if p.File == "" {
+		return nil, fmt.Errorf("no map file")
+	}
+	return ImportMap(p.File, p)
+}
+
+// getPoint returns a random point in a sphere of the given radius.
+// The points are spread evenly through the volume of the sphere: the
+// cube root keeps the outer shells, which have more room, from being
+// under-filled.
+func getPoint(r *rand.Rand, scale float64) coords {
+	u, v, d := r.Float64(), r.Float64(), r.Float64()
+	return spherical(u, v, scale*math.Cbrt(d))
+}
+
+// getDensePoint returns a random point in a sphere of the given radius.
+// The distance from the center is uniform, so the points are denser
+// toward the center.
+func getDensePoint(r *rand.Rand, scale float64) coords {
+	u, v, d := r.Float64(), r.Float64(), r.Float64()
+	return spherical(u, v, scale*d)
+}
+
+// spherical returns the point at the radius in the direction given by
+// u and v, both in the range [0, 1).
+func spherical(u, v, radius float64) coords {
+	theta, phi := u*2.0*math.Pi, math.Acos(2.0*v-1.0)
+	sinTheta, cosTheta := math.Sin(theta), math.Cos(theta)
+	sinPhi, cosPhi := math.Sin(phi), math.Cos(phi)
+	return coords{x: radius * sinPhi * cosTheta, y: radius * sinPhi * sinTheta, z: radius * cosPhi}
 }
 
 // randomPoint returns a random location that is the given distance from the origin.
@@ -207,13 +650,13 @@ func minDistance(c coords, systems []*system) (d float64) {
 //	theta is sin-1(z/R)
 //	    x is R cos(theta) cos(phi)
 //	    y is R cos(theta) sin(phi)
-func randomPoint(distance int) (c coords) {
+func randomPoint(r *rand.Rand, distance int) (c coords) {
 	R := float64(distance)
-	c = coords{z: R * rand.Float64()}
-	if rand.Int()%2 == 0 {
+	c = coords{z: R * r.Float64()}
+	if r.Int()%2 == 0 {
 		c.z = -c.z
 	}
-	phi := 2 * math.Pi * rand.Float64()
+	phi := 2 * math.Pi * r.Float64()
 	rCosTheta := R * math.Cos(math.Asin(c.z/R))
 	c.x, c.y = rCosTheta*math.Cos(phi), rCosTheta*math.Sin(phi)
 	return c
@@ -0,0 +1,179 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerators(t *testing.T) {
+	p := Params{Seed: 3, Systems: 128, MinStars: 40, Radius: 12, Clearance: 4.5}
+	for _, name := range Generators() {
+		if name == "file" {
+			continue
+		}
+		c, err := Generate(name, p)
+		if err != nil {
+			t.Errorf("%s: %v\n", name, err)
+			continue
+		}
+		stars := 0
+		for _, sys := range c.systems {
+			stars += len(sys.stars)
+		}
+		if len(c.systems) != p.Systems || stars != p.MinStars {
+			t.Errorf("%s: expected %d systems and %d stars: got %d %d\n", name, p.Systems, p.MinStars, len(c.systems), stars)
+		}
+
+		// the same seed must give the same cluster
+		again, err := Generate(name, p)
+		if err != nil {
+			t.Fatalf("%s: again: %v\n", name, err)
+		}
+		a, _ := json.Marshal(c)
+		b, _ := json.Marshal(again)
+		if !bytes.Equal(a, b) {
+			t.Errorf("%s: expected the same cluster from the same seed\n", name)
+		}
+
+		// and the file generator must load it unchanged
+		filename := filepath.Join(t.TempDir(), name+".json")
+		if err = c.Write(filename); err != nil {
+			t.Fatal(err)
+		}
+		fp := p
+		fp.File = filename
+		loaded, err := Generate("file", fp)
+		if err != nil {
+			t.Errorf("%s: file: %v\n", name, err)
+		} else if b, _ = json.Marshal(loaded); !bytes.Equal(a, b) {
+			t.Errorf("%s: file: expected the same cluster\n", name)
+		}
+	}
+
+	if _, err := Generate("nonesuch", p); err == nil {
+		t.Errorf("unknown generator: expected error: got nil\n")
+	}
+}
+
+func TestMaxStars(t *testing.T) {
+	for _, tc := range []struct {
+		id      int
+		systems int
+		homes   int
+		expect  int
+	}{
+		{1, 1, 1, 1},
+		{2, 3, 1, 11},
+		{3, 3, 2, 7},
+		{4, 40, 1, 1 + 2*5 + 3*4 + 6*3 + 16*2 + 12*1},
+	} {
+		p := Params{Systems: tc.systems, Homes: tc.homes, Radius: 40, Clearance: 4.5}
+		if got := p.maxStars(); got != tc.expect {
+			t.Errorf("%d: expected %d: got %d\n", tc.id, tc.expect, got)
+			continue
+		}
+		p.MinStars = tc.expect + 1
+		if err := p.Validate(); err == nil {
+			t.Errorf("%d: %d stars: expected error: got nil\n", tc.id, p.MinStars)
+		}
+		p.MinStars = tc.expect
+		c, err := Generate("sphere", p)
+		if err != nil {
+			t.Errorf("%d: %d stars: expected nil: got %v\n", tc.id, p.MinStars, err)
+			continue
+		}
+		stars := 0
+		for _, sys := range c.systems {
+			stars += len(sys.stars)
+		}
+		if stars != tc.expect {
+			t.Errorf("%d: expected %d stars: got %d\n", tc.id, tc.expect, stars)
+		}
+	}
+}
+
+func TestSpherePoints(t *testing.T) {
+	// the inner half of the radius holds an eighth of the volume
+	r := rand.New(rand.NewSource(5))
+	for _, tc := range []struct {
+		id     int
+		point  func(*rand.Rand, float64) coords
+		expect float64
+	}{
+		{1, getPoint, 0.125},
+		{2, getDensePoint, 0.5},
+	} {
+		const n = 20000
+		inner := 0
+		for i := 0; i < n; i++ {
+			if d := tc.point(r, 10).distance(coords{}); d > 10 {
+				t.Fatalf("%d: expected a point in the sphere: got distance %g\n", tc.id, d)
+			} else if d < 5 {
+				inner++
+			}
+		}
+		if got := float64(inner) / n; math.Abs(got-tc.expect) > 0.02 {
+			t.Errorf("%d: inner half: expected %g: got %g\n", tc.id, tc.expect, got)
+		}
+	}
+}
+
+func TestInvariants(t *testing.T) {
+	p := Params{Systems: 3, MinStars: 1, Radius: 10, Clearance: 4.5}
+	home := func() *system {
+		return &system{home: true, stars: []*star{{}}}
+	}
+	for _, tc := range []struct {
+		id      int
+		systems []*system
+		ok      bool
+	}{
+		{1, []*system{home(), {coords: coords{x: 5}}, {coords: coords{x: -5}}}, true},
+		{2, []*system{{coords: coords{x: 1}, stars: []*star{{}}}}, false},                                                     // home not at origin
+		{3, []*system{home(), {coords: coords{x: 4}}}, false},                                                                 // inside the clearance
+		{4, []*system{home(), {coords: coords{x: 11}}}, false},                                                                // outside the radius
+		{5, []*system{home(), {coords: coords{x: 5}}, {coords: coords{x: 5}}}, false},                                         // on top of each other
+		{6, []*system{home(), {coords: coords{x: 6}, stars: []*star{{}}}, {coords: coords{x: 9}, stars: []*star{{}}}}, false}, // stars too close
+		{7, []*system{home(), {coords: coords{x: 6}, stars: []*star{{}}}, {coords: coords{x: 7}}}, true},                      // starless neighbor is fine
+		{8, []*system{{coords: coords{}, stars: []*star{{}}}, {coords: coords{x: 5}}}, false},                                 // origin isn't a home
+		{9, []*system{home(), {home: true, coords: coords{x: 5}, stars: []*star{{}}}, {coords: coords{x: -5}}}, true},         // two homes
+		{10, []*system{home(), {home: true, coords: coords{x: 5}, stars: []*star{{}}}, {coords: coords{x: 8}}}, false},        // inside another home's clearance
+		{11, []*system{home(), {home: true, coords: coords{x: 8}, stars: []*star{{}, {}}}}, false},                            // home with two stars
+	} {
+		c := &Cluster{systems: tc.systems}
+		c.link()
+		if err := c.checkInvariants(p); (err == nil) != tc.ok {
+			t.Errorf("%d: expected ok %v: got %v\n", tc.id, tc.ok, err)
+		}
+	}
+
+	// every nation needs a home system
+	c := &Cluster{systems: []*system{home(), {coords: coords{x: 5}}}}
+	c.link()
+	p.Homes = 2
+	if err := c.checkInvariants(p); err == nil {
+		t.Errorf("homes: expected error: got nil\n")
+	}
+}
@@ -0,0 +1,180 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// jsonCluster is the on-disk format for a cluster.
+type jsonCluster struct {
+	Systems []jsonSystem `json:"systems"`
+	Lanes   [][2]int     `json:"lanes,omitempty"` // pairs of system ids
+}
+
+type jsonSystem struct {
+	Name   string     `json:"name,omitempty"`
+	Home   bool       `json:"home,omitempty"`
+	Ring   int        `json:"ring"`
+	Coords jsonCoords `json:"coords"`
+	Stars  []jsonStar `json:"stars,omitempty"`
+	// Anomaly and Resources are set only for systems without stars.
+	Anomaly   string `json:"anomaly,omitempty"`
+	Resources int    `json:"resources,omitempty"`
+}
+
+type jsonCoords struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+type jsonStar struct {
+	Type       string       `json:"type,omitempty"`
+	Size       string       `json:"size,omitempty"`
+	Luminosity float64      `json:"luminosity,omitempty"`
+	Planets    []jsonPlanet `json:"planets,omitempty"`
+}
+
+type jsonPlanet struct {
+	Orbit        int    `json:"orbit"`
+	Kind         string `json:"kind"`
+	Habitability int    `json:"habitability,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (c *Cluster) MarshalJSON() ([]byte, error) {
+	var jc jsonCluster
+	for _, sys := range c.systems {
+		js := jsonSystem{
+			Name:   sys.name,
+			Home:   sys.home,
+			Ring:   sys.ring,
+			Coords: jsonCoords{X: sys.coords.x, Y: sys.coords.y, Z: sys.coords.z},
+		}
+		if len(sys.stars) == 0 {
+			js.Anomaly, js.Resources = sys.anomaly.String(), sys.resources
+		}
+		for _, s := range sys.stars {
+			jst := jsonStar{Type: s.spectral.String(), Size: s.size.String(), Luminosity: s.luminosity}
+			for _, p := range s.planets {
+				jst.Planets = append(jst.Planets, jsonPlanet{Orbit: p.orbit, Kind: p.kind.String(), Habitability: p.habitability})
+			}
+			js.Stars = append(js.Stars, jst)
+		}
+		jc.Systems = append(jc.Systems, js)
+	}
+	jc.Lanes = c.lanes()
+	return json.Marshal(jc)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (c *Cluster) UnmarshalJSON(data []byte) error {
+	var jc jsonCluster
+	if err := json.Unmarshal(data, &jc); err != nil {
+		return err
+	}
+	c.systems = nil
+	for _, js := range jc.Systems {
+		sys := &system{
+			name:   js.Name,
+			home:   js.Home,
+			ring:   js.Ring,
+			coords: coords{x: js.Coords.X, y: js.Coords.Y, z: js.Coords.Z},
+		}
+		if js.Anomaly != "" {
+			a, ok := parseAnomaly(js.Anomaly)
+			if !ok {
+				return fmt.Errorf("system %d: unknown anomaly %q", len(c.systems), js.Anomaly)
+			} else if len(js.Stars) == 0 {
+				sys.anomaly, sys.resources = a, js.Resources
+			}
+		}
+		for _, jst := range js.Stars {
+			// clusters written before stars were classified have sun-like stars
+			s := &star{spectral: typeG, size: mainSequence, luminosity: 1}
+			if jst.Type != "" {
+				t, ok := parseSpectralType(jst.Type)
+				if !ok {
+					return fmt.Errorf("system %d: unknown spectral type %q", len(c.systems), jst.Type)
+				}
+				s.spectral = t
+			}
+			if jst.Size != "" {
+				sc, ok := parseSizeClass(jst.Size)
+				if !ok {
+					return fmt.Errorf("system %d: unknown size class %q", len(c.systems), jst.Size)
+				}
+				s.size = sc
+			}
+			if jst.Luminosity != 0 {
+				s.luminosity = jst.Luminosity
+			}
+			for _, jp := range jst.Planets {
+				kind, ok := parsePlanetKind(jp.Kind)
+				if !ok {
+					return fmt.Errorf("system %d: unknown planet kind %q", len(c.systems), jp.Kind)
+				}
+				s.planets = append(s.planets, &planet{orbit: jp.Orbit, kind: kind, habitability: jp.Habitability})
+			}
+			sys.stars = append(sys.stars, s)
+		}
+		c.systems = append(c.systems, sys)
+	}
+	if len(c.systems) == 0 {
+		return fmt.Errorf("cluster: no systems")
+	} else if len(c.homes()) == 0 {
+		// clusters written before there were several home systems
+		// have just the one at the origin
+		c.systems[0].home = true
+	}
+	c.link()
+	for _, lane := range jc.Lanes {
+		a, b := lane[0], lane[1]
+		if a < 0 || a >= len(c.systems) || b < 0 || b >= len(c.systems) || a == b {
+			return fmt.Errorf("cluster: invalid lane %d-%d", a, b)
+		}
+		c.addLane(c.systems[a], c.systems[b])
+	}
+	return nil
+}
+
+// ReadCluster loads a cluster from a JSON file.
+func ReadCluster(filename string) (*Cluster, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var c Cluster
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &c, nil
+}
+
+// Write saves the cluster to a JSON file.
+func (c *Cluster) Write(filename string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0666)
+}
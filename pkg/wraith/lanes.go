@@ -0,0 +1,289 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// Clusters may have jump lanes. When they do, ships travel only along
+// the lanes, and a move order sends the ship along the shortest path
+// through the systems the nation knows about. Clusters without lanes
+// let ships fly straight to any system.
+
+// generateLanes connects the systems with a relative neighborhood graph:
+// two systems are joined if no third system is closer to both of them
+// than they are to each other. Lanes longer than maxLength are dropped,
+// and then the shortest lanes that join separate groups of systems are
+// added back so that every system can be reached.
+func (c *Cluster) generateLanes(maxLength float64) {
+	index := c.index()
+	for _, sys := range c.systems {
+		sys.lanes = nil
+	}
+	uf := newUnionFind(len(c.systems))
+	for _, a := range c.systems {
+		index.visit(a.coords, maxLength, func(nb neighbor) bool {
+			b := nb.sys
+			if b.id <= a.id {
+				return true
+			}
+			// look for a system closer to both a and b
+			blocked := !index.visit(a.coords, nb.distance, func(w neighbor) bool {
+				return w.sys == a || w.sys == b || !(w.distance < nb.distance && w.sys.coords.distance(b.coords) < nb.distance)
+			})
+			if !blocked {
+				c.addLane(a, b)
+				uf.union(a.id, b.id)
+			}
+			return true
+		})
+	}
+
+	if uf.groups == 1 {
+		return
+	}
+	type pair struct {
+		a, b     *system
+		distance float64
+	}
+	var pairs []pair
+	for i, a := range c.systems {
+		for _, b := range c.systems[i+1:] {
+			pairs = append(pairs, pair{a: a, b: b, distance: a.coords.distance(b.coords)})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].distance < pairs[j].distance
+	})
+	for _, p := range pairs {
+		if uf.union(p.a.id, p.b.id) {
+			c.addLane(p.a, p.b)
+			if uf.groups == 1 {
+				return
+			}
+		}
+	}
+}
+
+// addLane joins the systems. The lanes of every system are sorted by id.
+func (c *Cluster) addLane(a, b *system) {
+	insert := func(sys *system, id int) {
+		i := sort.SearchInts(sys.lanes, id)
+		if i < len(sys.lanes) && sys.lanes[i] == id {
+			return
+		}
+		sys.lanes = append(sys.lanes, 0)
+		copy(sys.lanes[i+1:], sys.lanes[i:])
+		sys.lanes[i] = id
+	}
+	insert(a, b.id)
+	insert(b, a.id)
+}
+
+// hasLanes returns true if the cluster has jump lanes.
+func (c *Cluster) hasLanes() bool {
+	for _, sys := range c.systems {
+		if len(sys.lanes) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// lanes returns every lane once, as pairs of system ids with the lower id first.
+func (c *Cluster) lanes() [][2]int {
+	var list [][2]int
+	for _, sys := range c.systems {
+		for _, id := range sys.lanes {
+			if sys.id < id {
+				list = append(list, [2]int{sys.id, id})
+			}
+		}
+	}
+	return list
+}
+
+// route returns the fastest path from one system to another, starting
+// with from and ending with to, and its cost. The cost is the length of
+// the path with legs through nebulae stretched (see legCost), so a ship
+// needs cost/speed turns to fly it. The path goes only through systems
+// that pass (the end points don't need to). Without lanes, the path is
+// a straight line. It returns false if there is no path.
+//
+// The search is A*, with the straight line distance as the heuristic.
+// Nebulae only make legs more expensive, so the heuristic still holds.
+func (c *Cluster) route(from, to int, pass func(id int) bool) ([]int, float64, bool) {
+	if from == to {
+		return []int{from}, 0, true
+	} else if !c.hasLanes() {
+		return []int{from, to}, c.legCost(from, to), true
+	}
+	goal := c.systems[to].coords
+	cost := map[int]float64{from: 0}
+	prev := make(map[int]int)
+	open := &routeQueue{{id: from, estimate: c.systems[from].coords.distance(goal)}}
+	closed := make(map[int]bool)
+	for open.Len() != 0 {
+		cur := heap.Pop(open).(routeItem)
+		if cur.id == to {
+			path := []int{to}
+			for id := to; id != from; {
+				id = prev[id]
+				path = append([]int{id}, path...)
+			}
+			return path, cost[to], true
+		} else if closed[cur.id] {
+			continue
+		}
+		closed[cur.id] = true
+		sys := c.systems[cur.id]
+		for _, id := range sys.lanes {
+			if closed[id] || (id != to && !pass(id)) {
+				continue
+			}
+			g := cost[cur.id] + c.legCost(cur.id, id)
+			if old, ok := cost[id]; ok && old <= g {
+				continue
+			}
+			cost[id], prev[id] = g, cur.id
+			heap.Push(open, routeItem{id: id, estimate: g + c.systems[id].coords.distance(goal)})
+		}
+	}
+	return nil, 0, false
+}
+
+// reach is how far a ship travels from an origin to a system (see reachable).
+type reach struct {
+	ok       bool    // false if there is no path
+	distance float64 // light years along the path
+	cost     float64 // the distance with legs through nebulae stretched
+}
+
+// reachable returns how far a ship travels from the origin to each of the
+// systems on the fastest path, going only through systems that pass (the
+// end points don't need to). It answers what route would for every one of
+// the systems with a single search, so it is the one to use for tables.
+// Lanes must be c.hasLanes(); callers with many origins check it once.
+//
+// The search is Dijkstra's, ordered by cost like route.
+func (c *Cluster) reachable(from int, to []int, pass func(id int) bool, lanes bool) []reach {
+	list := make([]reach, len(to))
+	if !lanes {
+		for i, id := range to {
+			if id == from {
+				list[i] = reach{ok: true}
+			} else {
+				list[i] = reach{ok: true, distance: c.systems[from].coords.distance(c.systems[id].coords), cost: c.legCost(from, id)}
+			}
+		}
+		return list
+	}
+	best := map[int]reach{from: {ok: true}}
+	open := &routeQueue{{id: from}}
+	closed := make(map[int]bool)
+	for open.Len() != 0 {
+		cur := heap.Pop(open).(routeItem)
+		if closed[cur.id] {
+			continue
+		}
+		closed[cur.id] = true
+		if cur.id != from && !pass(cur.id) {
+			// an end point, but not a way through
+			continue
+		}
+		sys, r := c.systems[cur.id], best[cur.id]
+		for _, id := range sys.lanes {
+			if closed[id] {
+				continue
+			}
+			g := r.cost + c.legCost(cur.id, id)
+			if old, ok := best[id]; ok && old.cost <= g {
+				continue
+			}
+			best[id] = reach{ok: true, distance: r.distance + sys.coords.distance(c.systems[id].coords), cost: g}
+			heap.Push(open, routeItem{id: id, estimate: g})
+		}
+	}
+	for i, id := range to {
+		list[i] = best[id]
+	}
+	return list
+}
+
+// routeItem is a system waiting to be searched.
+type routeItem struct {
+	id       int
+	estimate float64 // cost so far plus the distance to the goal, if there is one
+}
+
+// routeQueue orders the items by estimate and then id, so that
+// searches are deterministic.
+type routeQueue []routeItem
+
+func (q routeQueue) Len() int { return len(q) }
+func (q routeQueue) Less(i, j int) bool {
+	if q[i].estimate != q[j].estimate {
+		return q[i].estimate < q[j].estimate
+	}
+	return q[i].id < q[j].id
+}
+func (q routeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *routeQueue) Push(x interface{}) { *q = append(*q, x.(routeItem)) }
+func (q *routeQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// unionFind tracks groups of connected systems.
+type unionFind struct {
+	parent []int
+	groups int
+}
+
+func newUnionFind(n int) *unionFind {
+	uf := &unionFind{parent: make([]int, n), groups: n}
+	for i := range uf.parent {
+		uf.parent[i] = i
+	}
+	return uf
+}
+
+func (uf *unionFind) find(i int) int {
+	for uf.parent[i] != i {
+		uf.parent[i] = uf.parent[uf.parent[i]]
+		i = uf.parent[i]
+	}
+	return i
+}
+
+// union joins the groups of a and b. It returns false if they were
+// already in the same group.
+func (uf *unionFind) union(a, b int) bool {
+	ra, rb := uf.find(a), uf.find(b)
+	if ra == rb {
+		return false
+	}
+	uf.parent[rb] = ra
+	uf.groups--
+	return true
+}
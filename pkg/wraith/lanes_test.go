@@ -0,0 +1,157 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestLanes(t *testing.T) {
+	p := Params{Seed: 8, Systems: 96, MinStars: 40, Radius: 12, Clearance: 4.5, Lanes: 4}
+	c, err := Generate("sphere", p)
+	if err != nil {
+		t.Fatalf("generate: %v\n", err)
+	}
+
+	// every system can be reached from home
+	seen := map[int]bool{0: true}
+	queue := []int{0}
+	for len(queue) != 0 {
+		sys := c.systems[queue[0]]
+		queue = queue[1:]
+		for _, id := range sys.lanes {
+			if !seen[id] {
+				seen[id] = true
+				queue = append(queue, id)
+			}
+		}
+	}
+	if len(seen) != len(c.systems) {
+		t.Errorf("lanes: expected %d systems reachable: got %d\n", len(c.systems), len(seen))
+	}
+
+	// A* must find the same costs as Floyd-Warshall
+	n := len(c.systems)
+	dist := make([][]float64, n)
+	for i := range dist {
+		dist[i] = make([]float64, n)
+		for j := range dist[i] {
+			dist[i][j] = math.Inf(1)
+		}
+		dist[i][i] = 0
+		for _, j := range c.systems[i].lanes {
+			dist[i][j] = c.legCost(i, j)
+		}
+	}
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if d := dist[i][k] + dist[k][j]; d < dist[i][j] {
+					dist[i][j] = d
+				}
+			}
+		}
+	}
+	every := make([]int, n)
+	for i := range every {
+		every[i] = i
+	}
+	for i := 0; i < n; i += 7 {
+		reaches := c.reachable(i, every, All, true)
+		for j, r := range reaches {
+			if !r.ok || math.Abs(r.cost-dist[i][j]) > 1e-9 {
+				t.Errorf("reachable %d %d: expected %v: got %+v\n", i, j, dist[i][j], r)
+			}
+		}
+		for j := 0; j < n; j += 5 {
+			path, length, ok := c.route(i, j, All)
+			if !ok {
+				t.Errorf("route %d %d: expected a path: got none\n", i, j)
+				continue
+			}
+			if math.Abs(length-dist[i][j]) > 1e-9 {
+				t.Errorf("route %d %d: expected %v: got %v\n", i, j, dist[i][j], length)
+			}
+			if path[0] != i || path[len(path)-1] != j {
+				t.Errorf("route %d %d: expected end points: got %v\n", i, j, path)
+			}
+		}
+	}
+
+	// lanes survive a trip through JSON
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again Cluster
+	if err = json.Unmarshal(data, &again); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := c.lanes(), again.lanes(); len(a) != len(b) {
+		t.Errorf("json: expected %d lanes: got %d\n", len(a), len(b))
+	}
+}
+
+func TestRouteMovement(t *testing.T) {
+	// home -- a -- b, with a shortcut home -- b that isn't a lane
+	c := &Cluster{systems: []*system{
+		{name: "Home"},
+		{name: "Alpha", coords: coords{x: 2}},
+		{name: "Beta", coords: coords{x: 2, y: 2}},
+		{name: "Gamma", coords: coords{y: 5}},
+	}}
+	c.link()
+	c.addLane(c.systems[0], c.systems[1])
+	c.addLane(c.systems[1], c.systems[2])
+	c.addLane(c.systems[0], c.systems[3])
+	c.addLane(c.systems[3], c.systems[2])
+
+	known := func(id int) bool { return id != 1 }
+	if _, _, ok := c.route(0, 2, known); !ok {
+		t.Fatalf("route: expected the long way through Gamma: got none\n")
+	} else if path, _, _ := c.route(0, 2, All); len(path) != 3 || path[1] != 1 {
+		t.Errorf("route: expected Home Alpha Beta: got %v\n", path)
+	}
+	if _, _, ok := c.route(0, 2, func(id int) bool { return false }); ok {
+		t.Errorf("route: expected no path through unknown systems: got one\n")
+	}
+	if r := c.reachable(0, []int{2}, known, true)[0]; !r.ok || math.Abs(r.distance-(5+math.Sqrt(13))) > 1e-9 {
+		t.Errorf("reachable: expected the long way through Gamma: got %+v\n", r)
+	}
+	if r := c.reachable(0, []int{1, 2}, func(id int) bool { return false }, true); !r[0].ok || r[1].ok {
+		t.Errorf("reachable: expected only Alpha without passing through: got %+v\n", r)
+	}
+
+	sh := &Ship{Id: "S1"}
+	path, _, _ := c.route(0, 2, All)
+	sh.follow(path)
+	if sh.Destination != 1 || len(sh.Route) != 1 || sh.FinalDestination() != 2 {
+		t.Fatalf("follow: expected Alpha then Beta: got %d %v\n", sh.Destination, sh.Route)
+	}
+	sh.advance(c, 3) // reaches Alpha and carries 1 ly toward Beta
+	if sh.System != 1 || sh.Destination != 2 || sh.Traveled != 1 {
+		t.Errorf("advance: expected 1 ly past Alpha: got %d %d %v\n", sh.System, sh.Destination, sh.Traveled)
+	}
+	sh.advance(c, 3)
+	if sh.InTransit() || sh.System != 2 || sh.Traveled != 0 || sh.Route != nil {
+		t.Errorf("advance: expected to arrive at Beta: got %+v\n", sh)
+	}
+}
@@ -0,0 +1,336 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxStarsPerSystem is the most stars a system may have.
+const maxStarsPerSystem = 5
+
+// MapError lists every problem found in a map file.
+type MapError struct {
+	File     string
+	Problems []string
+}
+
+// Error implements the error interface.
+func (e *MapError) Error() string {
+	if len(e.Problems) == 1 {
+		return fmt.Sprintf("%s: %s", e.File, e.Problems[0])
+	}
+	return fmt.Sprintf("%s: %d problems:\n\t%s", e.File, len(e.Problems), strings.Join(e.Problems, "\n\t"))
+}
+
+// ImportMap reads a hand-authored map from a CSV or JSON file.
+//
+// CSV files need a header row that names the x, y, z, and stars columns.
+// An optional name column names the system; systems without names are
+// given generated ones.
+// The columns may be in any order and other columns are ignored.
+// Lines starting with # are comments.
+//
+//	x,y,z,stars
+//	0,0,0,1
+//	5,2,-1,2
+//
+// JSON files hold a list of systems:
+//
+//	{"systems": [{"name": "Sol", "x": 0, "y": 0, "z": 0, "stars": 1}, ...]}
+//
+// Cluster files written by the server are accepted too and keep their planets.
+//
+// The system at the origin becomes a home system, as do the home systems
+// in cluster files. Rings are computed
+// from the coordinates. If the map has no planets, they are generated
+// from the seed. The map must honor the generator invariants (duplicate
+// coordinates, systems outside the radius, and systems that are too
+// close together are all errors). Every problem is reported, not just
+// the first; the error is a *MapError.
+func ImportMap(filename string, p Params) (*Cluster, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var entries []mapEntry
+	var c *Cluster
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		entries, err = readMapCSV(data)
+	case ".json":
+		if c, err = readClusterJSON(data); c == nil && err == nil {
+			entries, err = readMapJSON(data)
+		}
+	default:
+		return nil, fmt.Errorf("%s: map files must be .csv or .json", filename)
+	}
+	var me *MapError
+	if errors.As(err, &me) {
+		me.File = filename
+		return nil, me
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	labels := make(map[*system]string)
+	if c == nil {
+		c = &Cluster{}
+		for _, e := range entries {
+			sys := &system{name: e.name, coords: e.coords}
+			for i := 0; i < e.stars; i++ {
+				sys.stars = append(sys.stars, &star{})
+			}
+			labels[sys] = e.label
+			c.systems = append(c.systems, sys)
+		}
+	} else {
+		for i, sys := range c.systems {
+			labels[sys] = mapLabel(fmt.Sprintf("system %d", i+1), sys.name)
+		}
+	}
+
+	// the system at the origin is the home system.
+	// moving it changes the ids, so remember the old ones for the lanes.
+	oldIds := make(map[int]*system)
+	for _, sys := range c.systems {
+		oldIds[sys.id] = sys
+	}
+	for i, sys := range c.systems {
+		if sys.coords == (coords{}) {
+			copy(c.systems[1:i+1], c.systems[:i])
+			c.systems[0], sys.home = sys, true
+			break
+		}
+	}
+	for _, sys := range c.systems {
+		sys.ring = int(math.Round(sys.coords.distance(coords{})))
+	}
+	c.link()
+	for _, sys := range c.systems {
+		for i, id := range sys.lanes {
+			sys.lanes[i] = oldIds[id].id
+		}
+		sort.Ints(sys.lanes)
+	}
+
+	var problems []string
+	if len(c.systems) != 0 && c.systems[0].coords != (coords{}) {
+		problems = append(problems, "no system at the origin for the home system")
+	} else {
+		problems = c.violations(p, func(sys *system) string { return labels[sys] })
+	}
+	named := make(map[string]*system)
+	for _, sys := range c.systems {
+		if sys.name == "" {
+			continue
+		} else if err := validName(sys.name); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", labels[sys], err))
+		} else if other, ok := named[strings.ToLower(sys.name)]; ok {
+			problems = append(problems, fmt.Sprintf("%s: same name as %s", labels[sys], labels[other]))
+		} else {
+			named[strings.ToLower(sys.name)] = sys
+		}
+	}
+	if len(problems) != 0 {
+		return nil, &MapError{File: filename, Problems: problems}
+	}
+
+	r := rand.New(rand.NewSource(p.Seed))
+	if !c.hasPlanets() {
+		c.generatePlanets(r, p.Stars)
+	}
+	c.generateNames(r)
+	if !c.hasAnomalies() {
+		c.generateAnomalies(r, p.Anomalies)
+	}
+	if p.Lanes > 0 && !c.hasLanes() {
+		c.generateLanes(p.Lanes)
+	}
+	return c, nil
+}
+
+// hasPlanets returns true if any star in the cluster has planets.
+func (c *Cluster) hasPlanets() bool {
+	for _, sys := range c.systems {
+		for _, s := range sys.stars {
+			if len(s.planets) != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mapEntry is a system read from a map file.
+type mapEntry struct {
+	label  string // where the system came from, for error messages
+	name   string
+	coords coords
+	stars  int
+}
+
+// mapLabel returns the label for a system, adding the name if there is one.
+func mapLabel(where, name string) string {
+	if name == "" {
+		return where
+	}
+	return fmt.Sprintf("%s (%s)", where, name)
+}
+
+// readMapCSV returns the systems in a CSV map.
+func readMapCSV(data []byte) ([]mapEntry, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"x", "y", "z", "stars"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("header: missing column %q", name)
+		}
+	}
+
+	var entries []mapEntry
+	var problems []string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		e := mapEntry{label: mapLabel(fmt.Sprintf("line %d", line), field("name")), name: field("name")}
+		var bad []string
+		for _, axis := range []struct {
+			name  string
+			value *float64
+		}{{"x", &e.coords.x}, {"y", &e.coords.y}, {"z", &e.coords.z}} {
+			v, err := strconv.ParseFloat(field(axis.name), 64)
+			if err != nil {
+				bad = append(bad, fmt.Sprintf("%s %q", axis.name, field(axis.name)))
+			}
+			*axis.value = v
+		}
+		stars, err := strconv.Atoi(field("stars"))
+		if err != nil || stars < 0 || stars > maxStarsPerSystem {
+			bad = append(bad, fmt.Sprintf("stars %q (must be 0 to %d)", field("stars"), maxStarsPerSystem))
+		}
+		e.stars = stars
+		if len(bad) != 0 {
+			problems = append(problems, fmt.Sprintf("%s: invalid %s", e.label, strings.Join(bad, ", ")))
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if len(problems) != 0 {
+		return nil, &MapError{Problems: problems}
+	} else if len(entries) == 0 {
+		return nil, fmt.Errorf("no systems")
+	}
+	return entries, nil
+}
+
+// readMapJSON returns the systems in a JSON map.
+func readMapJSON(data []byte) ([]mapEntry, error) {
+	var jm struct {
+		Systems []struct {
+			Name  string   `json:"name"`
+			X     *float64 `json:"x"`
+			Y     *float64 `json:"y"`
+			Z     *float64 `json:"z"`
+			Stars int      `json:"stars"`
+		} `json:"systems"`
+	}
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return nil, err
+	}
+	var entries []mapEntry
+	var problems []string
+	for i, js := range jm.Systems {
+		e := mapEntry{label: mapLabel(fmt.Sprintf("system %d", i+1), js.Name), name: js.Name, stars: js.Stars}
+		if js.X == nil || js.Y == nil || js.Z == nil {
+			problems = append(problems, fmt.Sprintf("%s: x, y, and z are required", e.label))
+			continue
+		} else if js.Stars < 0 || js.Stars > maxStarsPerSystem {
+			problems = append(problems, fmt.Sprintf("%s: invalid stars %d (must be 0 to %d)", e.label, js.Stars, maxStarsPerSystem))
+			continue
+		}
+		e.coords = coords{x: *js.X, y: *js.Y, z: *js.Z}
+		entries = append(entries, e)
+	}
+	if len(problems) != 0 {
+		return nil, &MapError{Problems: problems}
+	} else if len(entries) == 0 {
+		return nil, fmt.Errorf("no systems")
+	}
+	return entries, nil
+}
+
+// readClusterJSON returns the cluster if the data is in the cluster file format.
+// It returns nil if the data is in some other format.
+func readClusterJSON(data []byte) (*Cluster, error) {
+	var probe struct {
+		Systems []map[string]json.RawMessage `json:"systems"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	} else if len(probe.Systems) == 0 {
+		return nil, nil
+	} else if _, ok := probe.Systems[0]["coords"]; !ok {
+		return nil, nil
+	}
+	var c Cluster
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	for _, sys := range c.systems {
+		if len(sys.stars) > maxStarsPerSystem {
+			return nil, fmt.Errorf("system %d: %d stars (must be 0 to %d)", sys.id, len(sys.stars), maxStarsPerSystem)
+		}
+	}
+	return &c, nil
+}
@@ -0,0 +1,89 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportMap(t *testing.T) {
+	p := Params{Seed: 7, Radius: 15, Clearance: 4.5}
+	for _, tc := range []struct {
+		id       int
+		name     string
+		data     string
+		systems  int
+		problems []string
+	}{
+		{id: 1, name: "map.csv", data: "# a comment\nx,y,z,stars\n0,0,0,1\n8,0,0,1\n0,9,0,2\n0,0,-10,0\n", systems: 4},
+		{id: 2, name: "map.csv", data: "stars,z,y,x\n2,0,9,0\n1,0,0,0\n", systems: 2},
+		{id: 3, name: "map.json", data: `{"systems":[{"x":8,"y":0,"z":0,"stars":1},{"x":0,"y":0,"z":0,"stars":1}]}`, systems: 2},
+		{id: 4, name: "map.csv", data: "x,y,z,stars\n0,0,0,1\n8,0,0,1\n8,0,0,1\n", problems: []string{"same coordinates"}},
+		{id: 5, name: "map.csv", data: "x,y,z,stars\n0,0,0,1\n16,0,0,1\n", problems: []string{"line 3", "from home"}},
+		{id: 6, name: "map.json", data: `{"systems":[{"x":0,"y":0,"z":0,"stars":1},{"x":6,"y":0,"z":0,"stars":1},{"x":9,"y":0,"z":0,"stars":3}]}`, problems: []string{"system 2", "system 3"}},
+		{id: 7, name: "map.csv", data: "name,x,y,z,stars\nSol,0,0,0,1\nVega,8,zero,0,9\n", problems: []string{"line 3 (Vega)", "y \"zero\"", "stars \"9\""}},
+		{id: 8, name: "map.csv", data: "x,y,z,stars\n8,0,0,1\n", problems: []string{"origin"}},
+		{id: 9, name: "map.csv", data: "x,y,stars\n0,0,1\n", problems: []string{"missing column \"z\""}},
+	} {
+		filename := filepath.Join(t.TempDir(), tc.name)
+		if err := os.WriteFile(filename, []byte(tc.data), 0666); err != nil {
+			t.Fatal(err)
+		}
+		c, err := ImportMap(filename, p)
+		if tc.problems == nil {
+			if err != nil {
+				t.Errorf("%d: import: expected nil: got %v\n", tc.id, err)
+				continue
+			}
+			if len(c.systems) != tc.systems {
+				t.Errorf("%d: systems: expected %d: got %d\n", tc.id, tc.systems, len(c.systems))
+			}
+			if c.systems[0].coords != (coords{}) {
+				t.Errorf("%d: home: expected origin: got %v\n", tc.id, c.systems[0].coords)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%d: import: expected error: got nil\n", tc.id)
+			continue
+		}
+		for _, want := range tc.problems {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%d: error: expected %q: got %v\n", tc.id, want, err)
+			}
+		}
+	}
+
+	// every problem is reported, not just the first
+	filename := filepath.Join(t.TempDir(), "map.csv")
+	if err := os.WriteFile(filename, []byte("x,y,z,stars\n0,0,0,1\n20,0,0,1\n0,20,0,1\n0,0,1,1\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ImportMap(filename, p)
+	var me *MapError
+	if !errors.As(err, &me) {
+		t.Fatalf("problems: expected *MapError: got %v\n", err)
+	} else if len(me.Problems) < 3 {
+		t.Errorf("problems: expected at least 3: got %d\n", len(me.Problems))
+	}
+}
@@ -0,0 +1,237 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Every system has a unique name, like "Kessa". Names are a single word
+// of letters so that they can be used in orders without quotes, and they
+// are unique regardless of case.
+//
+// Stars and planets are named after their system. The short id of a star
+// is the system name if the system has one star, and the system name
+// followed by a letter if it has more ("Kessa-B"). The short id of a planet
+// is the star's id followed by the orbit in Roman numerals ("Kessa-IV" or
+// "Kessa-B-IV"). Reports print the ids with spaces ("Kessa B IV"), and
+// orders accept either form.
+//
+// Names are saved with the cluster, so ids never change once a game starts.
+
+// nameSeeds trains the name generator. The generated names sound like
+// these without repeating them.
+var nameSeeds = []string{
+	"achernar", "aldara", "alnitak", "altair", "antares", "arcturus", "avior", "bellatrix",
+	"benetnash", "caliban", "canopus", "capella", "castor", "corvala", "deneb", "dorado",
+	"elnath", "eridani", "esker", "fomalhaut", "gacrux", "garnet", "halcyon", "hadar",
+	"izar", "kessa", "kochab", "korvan", "lesath", "maia", "markab", "menkar",
+	"merope", "mirach", "mizar", "naos", "nashira", "nembus", "okaro", "orvane",
+	"peacock", "phaet", "polaris", "pollux", "procyon", "rigel", "rukbat", "sabik",
+	"sadira", "saiph", "scheat", "sirius", "solenne", "spica", "sualocin", "talitha",
+	"tarazed", "thuban", "torvel", "unukal", "vashti", "vega", "velora", "wezen",
+	"yildun", "zaniah", "zavijava", "zosma",
+}
+
+// nameOrder is the number of letters the generator looks back.
+const nameOrder = 2
+
+// nameGenerator is a Markov chain over letters.
+type nameGenerator struct {
+	// next holds the letters that follow each prefix in the seeds,
+	// one entry per occurrence so that common letters are picked more often.
+	// A prefix is padded with '^' at the start of a name and
+	// '$' marks the end of a name.
+	next map[string][]byte
+	used map[string]bool // lower case names already assigned
+}
+
+func newNameGenerator() *nameGenerator {
+	g := &nameGenerator{next: make(map[string][]byte), used: make(map[string]bool)}
+	for _, seed := range nameSeeds {
+		g.used[seed] = true // don't hand out the seeds themselves
+		word := strings.Repeat("^", nameOrder) + seed + "$"
+		for i := nameOrder; i < len(word); i++ {
+			prefix := word[i-nameOrder : i]
+			g.next[prefix] = append(g.next[prefix], word[i])
+		}
+	}
+	return g
+}
+
+// reserve marks the name as used.
+func (g *nameGenerator) reserve(name string) {
+	g.used[strings.ToLower(name)] = true
+}
+
+// generate returns a new, unused name.
+func (g *nameGenerator) generate(r *rand.Rand) string {
+	for attempt := 0; ; attempt++ {
+		name := g.word(r)
+		if attempt >= 100 {
+			// the chain is worn out; add letters that count the attempts
+			// instead of looping forever. Names must stay letters only.
+			if len(name) > 6 {
+				name = name[:6]
+			}
+			name += suffix(attempt - 100)
+		}
+		if len(name) < 4 || len(name) > 9 || g.used[name] {
+			continue
+		}
+		g.used[name] = true
+		return strings.ToUpper(name[:1]) + name[1:]
+	}
+}
+
+// suffix returns the letters that count n: "a" to "z", then "aa",
+// "ab", and so on.
+func suffix(n int) string {
+	s := ""
+	for n++; n > 0; n = (n - 1) / 26 {
+		s = string(rune('a'+(n-1)%26)) + s
+	}
+	return s
+}
+
+// word runs the chain once.
+func (g *nameGenerator) word(r *rand.Rand) string {
+	prefix := strings.Repeat("^", nameOrder)
+	var sb strings.Builder
+	for sb.Len() <= 9 {
+		choices := g.next[prefix]
+		ch := choices[r.Intn(len(choices))]
+		if ch == '$' {
+			break
+		}
+		sb.WriteByte(ch)
+		prefix = prefix[1:] + string(ch)
+	}
+	return sb.String()
+}
+
+// generateNames gives a name to every system that doesn't have one.
+// Names already in the cluster are kept.
+func (c *Cluster) generateNames(r *rand.Rand) {
+	g := newNameGenerator()
+	for _, sys := range c.systems {
+		if sys.name != "" {
+			g.reserve(sys.name)
+		}
+	}
+	for _, sys := range c.systems {
+		if sys.name == "" {
+			sys.name = g.generate(r)
+		}
+	}
+}
+
+// validName returns an error if the name can't be used for a system.
+func validName(name string) error {
+	if name == "" {
+		return fmt.Errorf("name is empty")
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) {
+			return fmt.Errorf("name %q must contain only letters", name)
+		}
+	}
+	return nil
+}
+
+// label returns the name of the system, or its number if it doesn't have a name.
+func (sys *system) label() string {
+	if sys.name == "" {
+		return strconv.Itoa(sys.id)
+	}
+	return sys.name
+}
+
+// shortId returns the short id of the star, like "Kessa" or "Kessa-B".
+func (s *star) shortId() string {
+	if len(s.system.stars) == 1 {
+		return s.system.label()
+	}
+	for i, o := range s.system.stars {
+		if o == s {
+			return s.system.label() + "-" + string(rune('A'+i))
+		}
+	}
+	panic("assert(star is in its system)")
+}
+
+// shortId returns the short id of the planet, like "Kessa-IV" or "Kessa-B-IV".
+func (p *planet) shortId() string {
+	return p.star.shortId() + "-" + roman(p.orbit)
+}
+
+// displayName returns the short id with spaces instead of dashes.
+func displayName(id string) string {
+	return strings.ReplaceAll(id, "-", " ")
+}
+
+// roman returns the number in Roman numerals.
+func roman(n int) string {
+	var sb strings.Builder
+	for _, r := range []struct {
+		value  int
+		digits string
+	}{{10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"}} {
+		for ; n >= r.value; n -= r.value {
+			sb.WriteString(r.digits)
+		}
+	}
+	return sb.String()
+}
+
+// Lookup returns the id of the system with the given name or number.
+// Names are matched regardless of case. Star and planet ids match
+// their system, so "Kessa-B-IV" and "Kessa B IV" both find Kessa.
+func (c *Cluster) Lookup(name string) (int, bool) {
+	name = strings.TrimSpace(name)
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, 0 <= id && id < len(c.systems)
+	}
+	if i := strings.IndexAny(name, "- "); i != -1 {
+		name = name[:i]
+	}
+	c.names.Do(func() {
+		c.names.ids = make(map[string]int)
+		for _, sys := range c.systems {
+			if sys.name != "" {
+				c.names.ids[strings.ToLower(sys.name)] = sys.id
+			}
+		}
+	})
+	id, ok := c.names.ids[strings.ToLower(name)]
+	return id, ok
+}
+
+// SystemName returns the name of the system with the given id,
+// or its number if it doesn't have a name.
+func (c *Cluster) SystemName(id int) string {
+	if id < 0 || id >= len(c.systems) {
+		return strconv.Itoa(id)
+	}
+	return c.systems[id].label()
+}
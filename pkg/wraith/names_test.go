@@ -0,0 +1,143 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"github.com/mdhender/wraithe/pkg/orders"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestNames(t *testing.T) {
+	p := DefaultParams()
+	p.Seed = 11
+	c, err := Generate("sphere", p)
+	if err != nil {
+		t.Fatalf("generate: %v\n", err)
+	}
+	seen := make(map[string]int)
+	for _, sys := range c.systems {
+		if err := validName(sys.name); err != nil {
+			t.Errorf("system %d: %v\n", sys.id, err)
+		}
+		if other, ok := seen[strings.ToLower(sys.name)]; ok {
+			t.Errorf("system %d: expected unique name: got %q, same as %d\n", sys.id, sys.name, other)
+		}
+		seen[strings.ToLower(sys.name)] = sys.id
+		if id, ok := c.Lookup(strings.ToUpper(sys.name)); !ok || id != sys.id {
+			t.Errorf("system %d: lookup %q: expected %d: got %d %v\n", sys.id, sys.name, sys.id, id, ok)
+		}
+	}
+
+	// the same seed must give the same names
+	again, err := Generate("sphere", p)
+	if err != nil {
+		t.Fatalf("generate: %v\n", err)
+	}
+	for i, sys := range c.systems {
+		if sys.name != again.systems[i].name {
+			t.Errorf("system %d: expected %q: got %q\n", i, sys.name, again.systems[i].name)
+		}
+	}
+}
+
+func TestNameCollisions(t *testing.T) {
+	// a chain that only knows one word
+	g := &nameGenerator{next: make(map[string][]byte), used: make(map[string]bool)}
+	word := "^^kessa$"
+	for i := nameOrder; i < len(word); i++ {
+		g.next[word[i-nameOrder:i]] = []byte{word[i]}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		name := g.generate(r)
+		if err := validName(name); err != nil {
+			t.Errorf("%d: %v\n", i, err)
+		} else if _, errs := orders.Parse([]byte("move S1 " + name)); len(errs) != 0 {
+			t.Errorf("%d: orders: expected %q to be a system: got %v\n", i, name, errs)
+		} else if seen[name] {
+			t.Errorf("%d: expected unique name: got %q again\n", i, name)
+		}
+		seen[name] = true
+	}
+	if !seen["Kessa"] || !seen["Kessab"] {
+		t.Errorf("collisions: expected Kessa and Kessab: got %v\n", seen)
+	}
+
+	for _, tc := range []struct {
+		id     int
+		n      int
+		expect string
+	}{
+		{1, 0, "a"},
+		{2, 25, "z"},
+		{3, 26, "aa"},
+		{4, 27, "ab"},
+		{5, 26 + 26*26, "aaa"},
+	} {
+		if got := suffix(tc.n); got != tc.expect {
+			t.Errorf("%d: suffix %d: expected %q: got %q\n", tc.id, tc.n, tc.expect, got)
+		}
+	}
+}
+
+func TestShortIds(t *testing.T) {
+	c := &Cluster{systems: []*system{{name: "Kessa"}, {name: "Orvo"}, {}}}
+	c.systems[0].stars = []*star{{planets: []*planet{{orbit: 4}}}}
+	c.systems[1].stars = []*star{{}, {planets: []*planet{{orbit: 9}}}}
+	c.link()
+
+	for _, tc := range []struct {
+		id     int
+		got    string
+		expect string
+	}{
+		{1, c.systems[0].stars[0].shortId(), "Kessa"},
+		{2, c.systems[0].stars[0].planets[0].shortId(), "Kessa-IV"},
+		{3, c.systems[1].stars[1].shortId(), "Orvo-B"},
+		{4, c.systems[1].stars[1].planets[0].shortId(), "Orvo-B-IX"},
+		{5, displayName("Orvo-B-IX"), "Orvo B IX"},
+		{6, c.SystemName(2), "2"},
+	} {
+		if tc.got != tc.expect {
+			t.Errorf("%d: expected %q: got %q\n", tc.id, tc.expect, tc.got)
+		}
+	}
+
+	for _, tc := range []struct {
+		id     int
+		name   string
+		expect int
+		ok     bool
+	}{
+		{1, "kessa", 0, true},
+		{2, "Kessa-IV", 0, true},
+		{3, "Orvo B IX", 1, true},
+		{4, "2", 2, true},
+		{5, "3", 3, false},
+		{6, "Nowhere", 0, false},
+	} {
+		if id, ok := c.Lookup(tc.name); ok != tc.ok || (ok && id != tc.expect) {
+			t.Errorf("%d: lookup %q: expected %d %v: got %d %v\n", tc.id, tc.name, tc.expect, tc.ok, id, ok)
+		}
+	}
+}
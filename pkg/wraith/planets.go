@@ -0,0 +1,119 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"math"
+	"math/rand"
+)
+
+const (
+	// maxOrbits is the number of orbits around every star.
+	maxOrbits = 10
+	// homeOrbit is the orbit of the home world in the home system.
+	homeOrbit = 3
+)
+
+// link connects the systems, stars, and planets to their parents
+// and assigns the system ids.
+func (c *Cluster) link() {
+	for id, sys := range c.systems {
+		sys.id = id
+		for _, s := range sys.stars {
+			s.system = sys
+			for _, p := range s.planets {
+				p.star = s
+			}
+		}
+	}
+}
+
+// homes returns the home systems, in order.
+func (c *Cluster) homes() (homes []*system) {
+	for _, sys := range c.systems {
+		if sys.home {
+			homes = append(homes, sys)
+		}
+	}
+	return homes
+}
+
+// generatePlanets classifies every star in the cluster and creates its planets.
+// It must be called after the generator has placed all the stars.
+func (c *Cluster) generatePlanets(r *rand.Rand, d StarDistribution) {
+	c.link()
+	c.classify(r, d)
+	for _, sys := range c.systems {
+		for _, s := range sys.stars {
+			if sys.home {
+				s.planets = homePlanets(s)
+			} else {
+				s.planets = randomPlanets(r, s)
+			}
+		}
+	}
+}
+
+// homePlanets returns the planets for the home system.
+// It always contains 10 planets, and the home world is always ideal.
+func homePlanets(s *star) (planets []*planet) {
+	for orbit := 1; orbit <= maxOrbits; orbit++ {
+		p := &planet{star: s, orbit: orbit, kind: orbitKind(orbit, s.habitableOrbit())}
+		if orbit == homeOrbit {
+			p.habitability = 25
+		}
+		planets = append(planets, p)
+	}
+	return planets
+}
+
+// randomPlanets returns a random set of planets for the star.
+// Orbits inside the habitable zone are rocky and orbits near the center
+// of the zone may be habitable. The outer orbits hold gas giants and
+// asteroid belts. Bright stars push the zone out and dim stars pull it in.
+func randomPlanets(r *rand.Rand, s *star) (planets []*planet) {
+	hz := s.habitableOrbit()
+	for orbit := 1; orbit <= maxOrbits; orbit++ {
+		if r.Intn(100) >= 60 {
+			continue
+		}
+		p := &planet{star: s, orbit: orbit, kind: orbitKind(orbit, hz)}
+		if d := math.Abs(float64(orbit) - hz); p.kind == terrestrial && d < 1.5 {
+			// the best worlds are found in the center of the zone
+			p.habitability = r.Intn(26) - int(math.Round(5*d))
+			if p.habitability < 0 {
+				p.habitability = 0
+			}
+		}
+		planets = append(planets, p)
+	}
+	return planets
+}
+
+// orbitKind returns the kind of planet usually found in the orbit
+// of a star with its habitable zone centered on the orbit hz.
+func orbitKind(orbit int, hz float64) planetKind {
+	switch {
+	case float64(orbit) < hz+1.5:
+		return terrestrial
+	case float64(orbit) < hz+4.5:
+		return gasGiant
+	}
+	return asteroidBelt
+}
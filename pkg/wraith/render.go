@@ -0,0 +1,466 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Projection is a fixed 2D view of the cluster for printed maps.
+type Projection int
+
+const (
+	// XY looks down on the cluster from above (+z).
+	XY Projection = iota
+	// XZ looks at the cluster from the front (-y).
+	XZ
+	// YZ looks at the cluster from the side (+x).
+	YZ
+	// Isometric looks at the cluster from above and to one side.
+	Isometric
+)
+
+// Projections lists every projection.
+var Projections = []Projection{XY, XZ, YZ, Isometric}
+
+// String implements the Stringer interface.
+func (p Projection) String() string {
+	switch p {
+	case XY:
+		return "xy"
+	case XZ:
+		return "xz"
+	case YZ:
+		return "yz"
+	case Isometric:
+		return "iso"
+	}
+	return "unknown"
+}
+
+// ParseProjection returns the projection matching the string.
+func ParseProjection(s string) (Projection, error) {
+	for _, p := range Projections {
+		if p.String() == strings.ToLower(s) {
+			return p, nil
+		}
+	}
+	return XY, fmt.Errorf("unknown projection %q (want xy, xz, yz, or iso)", s)
+}
+
+// project returns the position of the point on the page, in light years,
+// with u to the right and v down the page. Points with a greater depth
+// are closer to the viewer.
+func (p Projection) project(c coords) (u, v, depth float64) {
+	switch p {
+	case XZ:
+		return c.x, -c.z, -c.y
+	case YZ:
+		return c.y, -c.z, c.x
+	case Isometric:
+		return (c.x - c.y) * math.Cos(math.Pi/6), (c.x+c.y)*math.Sin(math.Pi/6) - c.z, c.x + c.y + c.z
+	}
+	return c.x, -c.y, c.z
+}
+
+// ground returns the point on the circle of the given radius in the plane
+// that the projection shows flat (the XY plane for the isometric view).
+func (p Projection) ground(radius, angle float64) coords {
+	a, b := radius*math.Cos(angle), radius*math.Sin(angle)
+	switch p {
+	case XZ:
+		return coords{x: a, z: b}
+	case YZ:
+		return coords{y: a, z: b}
+	}
+	return coords{x: a, y: b}
+}
+
+// MapOptions control how a map is drawn.
+type MapOptions struct {
+	Projection Projection
+	// Size is the width and height of the map in pixels.
+	// The default is 1024.
+	Size int
+	// Visible limits the map to the systems the viewer can see.
+	// The default is every system.
+	Visible Visibility
+	// Owners maps system ids to the nations that hold them (see State.Owners).
+	// Owned systems are circled in the nation's color.
+	Owners map[int][]string
+	// Labels adds the system names to the map.
+	Labels bool
+	// RingStep is the distance between the ring circles in light years.
+	// The default is 5.
+	RingStep int
+	// Title is printed in the corner of the map.
+	Title string
+}
+
+// nationColors are the colors for nations on printed maps.
+// Nations are assigned colors in sorted order.
+var nationColors = []string{"#e6194b", "#3cb44b", "#4363d8", "#f58231", "#911eb4", "#42d4f4", "#f032e6", "#9a6324", "#800000", "#000075"}
+
+// Owners returns the nations holding each system: the nation's home system,
+// its colonies, and every system where the nation has ships that are not
+// in transit.
+// The nations for each system are sorted.
+func (s *State) Owners(nations ...*Nation) map[int][]string {
+	if nations == nil {
+		for _, id := range s.nationIds() {
+			nations = append(nations, s.Nations[id])
+		}
+	}
+	owners := make(map[int][]string)
+	add := func(id int, nation string) {
+		for _, o := range owners[id] {
+			if o == nation {
+				return
+			}
+		}
+		owners[id] = append(owners[id], nation)
+		sort.Strings(owners[id])
+	}
+	for _, n := range nations {
+		add(n.HomeSystem, n.Id)
+		for _, col := range n.Colonies {
+			add(col.System, n.Id)
+		}
+		for _, sh := range n.Ships {
+			if !sh.InTransit() {
+				add(sh.System, n.Id)
+			}
+		}
+	}
+	return owners
+}
+
+// mapScene is a map laid out in pixels, ready to be written as SVG or PNG.
+type mapScene struct {
+	size   int
+	title  string
+	rings  []mapRing
+	lanes  [][4]float64 // x0, y0, x1, y1
+	dots   []mapDot     // sorted from back to front
+	legend []mapLegend
+}
+
+type mapRing struct {
+	label  string
+	lx, ly float64 // where the label goes
+	points [][2]float64
+}
+
+type mapDot struct {
+	x, y, r  float64
+	fill     string
+	outlines []string // owner colors, innermost first
+	label    string
+	depth    float64
+}
+
+type mapLegend struct {
+	color string
+	text  string
+}
+
+// scene lays out the map.
+func (c *Cluster) scene(opts MapOptions) *mapScene {
+	if opts.Size <= 0 {
+		opts.Size = 1024
+	}
+	if opts.Visible == nil {
+		opts.Visible = All
+	}
+	if opts.RingStep <= 0 {
+		opts.RingStep = 5
+	}
+	proj := opts.Projection
+	sc := &mapScene{size: opts.Size, title: opts.Title}
+	if sc.title == "" {
+		sc.title = proj.String() + " projection"
+	}
+
+	// the rings and the scale use every system so that the map doesn't
+	// depend on how much of the cluster the viewer can see.
+	maxRing := 0
+	for _, sys := range c.systems {
+		if sys.ring > maxRing {
+			maxRing = sys.ring
+		}
+	}
+	var radii []int
+	for r := opts.RingStep; r < maxRing+opts.RingStep; r += opts.RingStep {
+		radii = append(radii, r)
+	}
+	extent := 1.0
+	for _, r := range radii {
+		for i := 0; i < 72; i++ {
+			u, v, _ := proj.project(proj.ground(float64(r), float64(i)*math.Pi/36))
+			extent = math.Max(extent, math.Max(math.Abs(u), math.Abs(v)))
+		}
+	}
+	for _, sys := range c.systems {
+		u, v, _ := proj.project(sys.coords)
+		extent = math.Max(extent, math.Max(math.Abs(u), math.Abs(v)))
+	}
+	center := float64(opts.Size) / 2
+	scale := (center - 40) / extent
+	toPage := func(pt coords) (x, y, depth float64) {
+		u, v, depth := proj.project(pt)
+		return center + u*scale, center + v*scale, depth
+	}
+
+	for _, r := range radii {
+		ring := mapRing{label: fmt.Sprintf("%d ly", r)}
+		for i := 0; i < 72; i++ {
+			x, y, _ := toPage(proj.ground(float64(r), float64(i)*math.Pi/36))
+			ring.points = append(ring.points, [2]float64{x, y})
+		}
+		// label the ring where it crosses the right side of the page
+		ring.lx, ring.ly = ring.points[0][0]+3, ring.points[0][1]-3
+		sc.rings = append(sc.rings, ring)
+	}
+
+	colors := make(map[string]string)
+	var nations []string
+	for _, list := range opts.Owners {
+		for _, id := range list {
+			if _, ok := colors[id]; !ok {
+				colors[id] = ""
+				nations = append(nations, id)
+			}
+		}
+	}
+	sort.Strings(nations)
+	for i, id := range nations {
+		colors[id] = nationColors[i%len(nationColors)]
+		sc.legend = append(sc.legend, mapLegend{color: colors[id], text: id})
+	}
+
+	for _, sys := range c.systems {
+		if !opts.Visible(sys.id) {
+			continue
+		}
+		x, y, depth := toPage(sys.coords)
+		dot := mapDot{x: x, y: y, r: 2 + float64(len(sys.stars)), fill: sys.color(), depth: depth}
+		if opts.Labels {
+			dot.label = sys.label()
+		}
+		for _, id := range opts.Owners[sys.id] {
+			dot.outlines = append(dot.outlines, colors[id])
+		}
+		sc.dots = append(sc.dots, dot)
+	}
+	for _, lane := range c.lanes() {
+		if opts.Visible(lane[0]) && opts.Visible(lane[1]) {
+			x0, y0, _ := toPage(c.systems[lane[0]].coords)
+			x1, y1, _ := toPage(c.systems[lane[1]].coords)
+			sc.lanes = append(sc.lanes, [4]float64{x0, y0, x1, y1})
+		}
+	}
+	sort.SliceStable(sc.dots, func(i, j int) bool {
+		return sc.dots[i].depth < sc.dots[j].depth
+	})
+	return sc
+}
+
+// RenderSVG writes the map as an SVG image.
+func (c *Cluster) RenderSVG(w io.Writer, opts MapOptions) error {
+	sc := c.scene(opts)
+	bw := bufio.NewWriter(w)
+	text := func(s string) string {
+		var sb strings.Builder
+		_ = xml.EscapeText(&sb, []byte(s))
+		return sb.String()
+	}
+	num := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', 1, 64)
+	}
+
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"sans-serif\">\n", sc.size, sc.size, sc.size, sc.size)
+	fmt.Fprintf(bw, "<rect width=\"100%%\" height=\"100%%\" fill=\"white\"/>\n")
+	for _, ring := range sc.rings {
+		var pts []string
+		for _, pt := range ring.points {
+			pts = append(pts, num(pt[0])+","+num(pt[1]))
+		}
+		fmt.Fprintf(bw, "<polygon points=\"%s\" fill=\"none\" stroke=\"#c0c0c0\" stroke-dasharray=\"4 3\"/>\n", strings.Join(pts, " "))
+		fmt.Fprintf(bw, "<text x=\"%s\" y=\"%s\" font-size=\"10\" fill=\"#a0a0a0\">%s</text>\n", num(ring.lx), num(ring.ly), text(ring.label))
+	}
+	for _, lane := range sc.lanes {
+		fmt.Fprintf(bw, "<line x1=\"%s\" y1=\"%s\" x2=\"%s\" y2=\"%s\" stroke=\"#8080a0\"/>\n", num(lane[0]), num(lane[1]), num(lane[2]), num(lane[3]))
+	}
+	for _, dot := range sc.dots {
+		for i, outline := range dot.outlines {
+			fmt.Fprintf(bw, "<circle cx=\"%s\" cy=\"%s\" r=\"%s\" fill=\"none\" stroke=\"%s\" stroke-width=\"2\"/>\n", num(dot.x), num(dot.y), num(dot.r+2.5+2*float64(i)), outline)
+		}
+		fmt.Fprintf(bw, "<circle cx=\"%s\" cy=\"%s\" r=\"%s\" fill=\"%s\" stroke=\"#404040\" stroke-width=\"0.5\"/>\n", num(dot.x), num(dot.y), num(dot.r), dot.fill)
+		if dot.label != "" {
+			fmt.Fprintf(bw, "<text x=\"%s\" y=\"%s\" font-size=\"9\">%s</text>\n", num(dot.x+dot.r+3), num(dot.y-3), text(dot.label))
+		}
+	}
+	fmt.Fprintf(bw, "<text x=\"10\" y=\"20\" font-size=\"14\">%s</text>\n", text(sc.title))
+	for i, l := range sc.legend {
+		y := 38 + 16*i
+		fmt.Fprintf(bw, "<rect x=\"10\" y=\"%d\" width=\"10\" height=\"10\" fill=\"%s\"/>\n", y-9, l.color)
+		fmt.Fprintf(bw, "<text x=\"26\" y=\"%d\" font-size=\"11\">%s</text>\n", y, text(l.text))
+	}
+	fmt.Fprintf(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+// RenderPNG writes the map as a PNG image.
+// Labels are drawn in a small bitmap font, in upper case.
+func (c *Cluster) RenderPNG(w io.Writer, opts MapOptions) error {
+	sc := c.scene(opts)
+	cv := newCanvas(sc.size)
+	ringColor, labelColor, textColor := hexColor("#c0c0c0"), hexColor("#a0a0a0"), hexColor("#000000")
+	for _, ring := range sc.rings {
+		for i, pt := range ring.points {
+			next := ring.points[(i+1)%len(ring.points)]
+			cv.line(pt[0], pt[1], next[0], next[1], ringColor)
+		}
+		cv.text(ring.lx, ring.ly-glyphHeight, ring.label, labelColor)
+	}
+	for _, lane := range sc.lanes {
+		cv.line(lane[0], lane[1], lane[2], lane[3], hexColor("#8080a0"))
+	}
+	for _, dot := range sc.dots {
+		for i, outline := range dot.outlines {
+			r := dot.r + 2.5 + 2*float64(i)
+			cv.ring(dot.x, dot.y, r-1, r+1, hexColor(outline))
+		}
+		cv.disc(dot.x, dot.y, dot.r, hexColor("#404040"))
+		cv.disc(dot.x, dot.y, dot.r-0.75, hexColor(dot.fill))
+		if dot.label != "" {
+			cv.text(dot.x+dot.r+3, dot.y-3-glyphHeight, dot.label, textColor)
+		}
+	}
+	cv.text(10, 10, sc.title, textColor)
+	for i, l := range sc.legend {
+		y := float64(28 + 16*i)
+		cv.disc(15, y+3, 5, hexColor(l.color))
+		cv.text(26, y, l.text, textColor)
+	}
+	return png.Encode(w, cv.img)
+}
+
+// canvas draws on an image with the standard library only.
+type canvas struct {
+	img *image.RGBA
+}
+
+func newCanvas(size int) *canvas {
+	cv := &canvas{img: image.NewRGBA(image.Rect(0, 0, size, size))}
+	for i := range cv.img.Pix {
+		cv.img.Pix[i] = 0xff
+	}
+	return cv
+}
+
+func (cv *canvas) set(x, y int, c color.RGBA) {
+	if image.Pt(x, y).In(cv.img.Rect) {
+		cv.img.SetRGBA(x, y, c)
+	}
+}
+
+// line draws a line with Bresenham's algorithm.
+func (cv *canvas) line(x0, y0, x1, y1 float64, c color.RGBA) {
+	ax, ay, bx, by := int(math.Round(x0)), int(math.Round(y0)), int(math.Round(x1)), int(math.Round(y1))
+	dx, dy := abs(bx-ax), -abs(by-ay)
+	sx, sy := 1, 1
+	if ax > bx {
+		sx = -1
+	}
+	if ay > by {
+		sy = -1
+	}
+	for e := dx + dy; ; {
+		cv.set(ax, ay, c)
+		if ax == bx && ay == by {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e, ax = e+dy, ax+sx
+		}
+		if e2 <= dx {
+			e, ay = e+dx, ay+sy
+		}
+	}
+}
+
+// ring fills the pixels between the two radii.
+func (cv *canvas) ring(cx, cy, inner, outer float64, c color.RGBA) {
+	for y := int(cy - outer - 1); y <= int(cy+outer+1); y++ {
+		for x := int(cx - outer - 1); x <= int(cx+outer+1); x++ {
+			if d := math.Hypot(float64(x)-cx, float64(y)-cy); inner <= d && d <= outer {
+				cv.set(x, y, c)
+			}
+		}
+	}
+}
+
+// disc fills a circle.
+func (cv *canvas) disc(cx, cy, r float64, c color.RGBA) {
+	cv.ring(cx, cy, 0, r, c)
+}
+
+// text draws the string with its top left corner at the point.
+func (cv *canvas) text(x, y float64, s string, c color.RGBA) {
+	left, top := int(math.Round(x)), int(math.Round(y))
+	for i, ch := range []rune(strings.ToUpper(s)) {
+		glyph := glyphs[ch]
+		for row := 0; row < glyphHeight; row++ {
+			for col := 0; col < glyphWidth; col++ {
+				if glyph[row]&(1<<(glyphWidth-1-col)) != 0 {
+					cv.set(left+i*(glyphWidth+1)+col, top+row, c)
+				}
+			}
+		}
+	}
+}
+
+// hexColor returns the color for a string like "#ff8000".
+// Anything else is black.
+func hexColor(s string) color.RGBA {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 32)
+	if err != nil || len(s) != 7 {
+		return color.RGBA{A: 0xff}
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
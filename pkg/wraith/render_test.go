@@ -0,0 +1,106 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	c, err := Generate("sphere", Params{Seed: 4, Systems: 64, Homes: 2, MinStars: 40, Radius: 12, Clearance: 4.5})
+	if err != nil {
+		t.Fatalf("generate: %v\n", err)
+	}
+	s, err := NewState(c, "alpha", "beta")
+	if err != nil {
+		t.Fatalf("state: %v\n", err)
+	}
+	n := s.Nations["alpha"]
+
+	for _, proj := range Projections {
+		opts := MapOptions{Projection: proj, Size: 512, Visible: NationVisibility(n), Owners: s.Owners(n), Labels: true}
+		bw := &bytes.Buffer{}
+		if err := c.RenderSVG(bw, opts); err != nil {
+			t.Fatalf("%s: svg: %v\n", proj, err)
+		}
+		svg := bw.String()
+		for _, sys := range c.systems {
+			label := ">" + sys.name + "<"
+			if n.Knows(sys.id) && !strings.Contains(svg, label) {
+				t.Errorf("%s: svg: expected %q: missing\n", proj, sys.name)
+			} else if !n.Knows(sys.id) && strings.Contains(svg, label) {
+				t.Errorf("%s: svg: expected %q to be hidden: found it\n", proj, sys.name)
+			}
+		}
+		if strings.Contains(svg, ">beta<") {
+			t.Errorf("%s: svg: expected only alpha's holdings: found beta\n", proj)
+		}
+
+		bw.Reset()
+		if err := c.RenderPNG(bw, opts); err != nil {
+			t.Fatalf("%s: png: %v\n", proj, err)
+		}
+		img, err := png.Decode(bw)
+		if err != nil {
+			t.Fatalf("%s: png: %v\n", proj, err)
+		} else if b := img.Bounds(); b.Dx() != 512 || b.Dy() != 512 {
+			t.Errorf("%s: png: expected 512x512: got %dx%d\n", proj, b.Dx(), b.Dy())
+		}
+	}
+
+	for _, tc := range []struct {
+		id     int
+		input  string
+		expect Projection
+		ok     bool
+	}{
+		{1, "xy", XY, true},
+		{2, "ISO", Isometric, true},
+		{3, "yz", YZ, true},
+		{4, "zz", XY, false},
+	} {
+		p, err := ParseProjection(tc.input)
+		if (err == nil) != tc.ok || (tc.ok && p != tc.expect) {
+			t.Errorf("%d: parse %q: expected %v %v: got %v %v\n", tc.id, tc.input, tc.expect, tc.ok, p, err)
+		}
+	}
+
+	owners := s.Owners()
+	for _, id := range []string{"alpha", "beta"} {
+		home := s.Nations[id].HomeSystem
+		if got := strings.Join(owners[home], ","); got != id {
+			t.Errorf("owners: expected %s in its home system: got %q\n", id, got)
+		}
+	}
+}
+
+func TestCanvasText(t *testing.T) {
+	// a letter without a glyph takes one column, however many bytes it is
+	black := color.RGBA{A: 0xff}
+	a, b := newCanvas(64), newCanvas(64)
+	a.text(0, 0, "éA", black)
+	b.text(0, 0, "?A", black)
+	if !bytes.Equal(a.img.Pix, b.img.Pix) {
+		t.Errorf("text: expected A in the second column: got a different image\n")
+	}
+}
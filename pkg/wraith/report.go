@@ -0,0 +1,69 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Report is the turn report for a single nation.
+type Report struct {
+	Turn     int
+	Nation   string
+	Sections []*Section
+}
+
+// Section is a titled part of a report.
+type Section struct {
+	Title string
+	Lines []string
+}
+
+// Add returns a new section at the end of the report.
+func (r *Report) Add(title string) *Section {
+	s := &Section{Title: title}
+	r.Sections = append(r.Sections, s)
+	return s
+}
+
+// Printf adds a formatted line to the section.
+func (s *Section) Printf(format string, a ...interface{}) {
+	s.Lines = append(s.Lines, fmt.Sprintf(format, a...))
+}
+
+// Text returns the report as plain text.
+func (r *Report) Text() []byte {
+	bw := &bytes.Buffer{}
+	_, _ = fmt.Fprintf(bw, "Turn %d report for %s\n", r.Turn, r.Nation)
+	for _, s := range r.Sections {
+		_, _ = fmt.Fprintf(bw, "\n%s\n", s.Title)
+		for range s.Title {
+			bw.WriteByte('-')
+		}
+		bw.WriteByte('\n')
+		if len(s.Lines) == 0 {
+			bw.WriteString("  (none)\n")
+		}
+		for _, line := range s.Lines {
+			_, _ = fmt.Fprintf(bw, "  %s\n", line)
+		}
+	}
+	return bw.Bytes()
+}
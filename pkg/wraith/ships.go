@@ -0,0 +1,163 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"fmt"
+	"math"
+)
+
+// Design is a class of ship that nations may build.
+type Design struct {
+	Name  string  `json:"name"`
+	Speed float64 `json:"speed"` // light years per turn
+	Cost  int     `json:"cost"`  // industry needed to build one ship
+	// Sensors is the range of the design's sensor array in light years.
+	// Designs without one can't detect anything.
+	Sensors float64 `json:"sensors,omitempty"`
+	// Stealth is the rating of the design's stealth hull. It is subtracted
+	// from the chance, in percent, that sensors detect the ship.
+	Stealth int `json:"stealth,omitempty"`
+	// Cargo is the room in the design's holds. A unit of people or goods
+	// takes a unit of room.
+	Cargo int `json:"cargo,omitempty"`
+	// LifeSupport is the most people the design can carry.
+	LifeSupport int `json:"life-support,omitempty"`
+}
+
+// TurnsToArrive returns the number of turns a ship of this design
+// needs to travel the distance.
+func (d *Design) TurnsToArrive(distance float64) int {
+	if distance <= 0 {
+		return 0
+	} else if d.Speed <= 0 {
+		return math.MaxInt32
+	}
+	return int(math.Ceil(distance / d.Speed))
+}
+
+// defaultDesigns returns the designs available at the start of a game.
+func defaultDesigns() map[string]*Design {
+	return map[string]*Design{
+		"scout":     {Name: "scout", Speed: 3, Cost: 10, Sensors: 2.5, Stealth: 20},
+		"transport": {Name: "transport", Speed: 1.5, Cost: 20, Sensors: 1, Cargo: 50, LifeSupport: 20},
+	}
+}
+
+// Ship is a single ship owned by a nation.
+// A ship is stationary when its destination is the system it is in.
+// Otherwise, it has traveled some distance from System toward Destination.
+// In clusters with jump lanes, Destination is the next system on the
+// ship's path and Route holds the systems after it.
+type Ship struct {
+	Id          string  `json:"id"`
+	Name        string  `json:"name,omitempty"`
+	Design      string  `json:"design"`
+	System      int     `json:"system"`
+	Destination int     `json:"destination"`
+	Route       []int   `json:"route,omitempty"`
+	Traveled    float64 `json:"traveled,omitempty"`
+	People      int     `json:"people,omitempty"` // cargo
+	Goods       int     `json:"goods,omitempty"`  // cargo
+	Supply      *Supply `json:"supply,omitempty"` // set if the ship runs a supply route
+}
+
+// InTransit returns true if the ship is travelling between systems.
+func (sh *Ship) InTransit() bool {
+	return sh.System != sh.Destination
+}
+
+// position returns the current location of the ship.
+func (sh *Ship) position(c *Cluster) coords {
+	from := c.systems[sh.System].coords
+	if !sh.InTransit() {
+		return from
+	}
+	to := c.systems[sh.Destination].coords
+	total := from.distance(to)
+	if total == 0 {
+		return to
+	}
+	f := sh.Traveled / total
+	return coords{x: from.x + f*(to.x-from.x), y: from.y + f*(to.y-from.y), z: from.z + f*(to.z-from.z)}
+}
+
+// FinalDestination returns the system at the end of the ship's route.
+func (sh *Ship) FinalDestination() int {
+	if len(sh.Route) != 0 {
+		return sh.Route[len(sh.Route)-1]
+	}
+	return sh.Destination
+}
+
+// follow sends the ship along the path, which starts with the system the ship is in.
+func (sh *Ship) follow(path []int) {
+	sh.Destination, sh.Route, sh.Traveled = path[len(path)-1], nil, 0
+	if len(path) > 1 {
+		sh.Destination = path[1]
+		if len(path) > 2 {
+			sh.Route = append([]int(nil), path[2:]...)
+		}
+	}
+}
+
+// advance moves the ship along its route for a turn at the speed.
+// Time left over at a system on the route carries on to the next leg.
+// Legs that start or end in a nebula are flown at reduced speed.
+func (sh *Ship) advance(c *Cluster, speed float64) {
+	budget := speed // distance left this turn, as if outside of nebulae
+	for sh.InTransit() {
+		leg := c.systems[sh.System].coords.distance(c.systems[sh.Destination].coords)
+		f := c.legSpeed(sh.System, sh.Destination)
+		remaining := (leg - sh.Traveled) / f
+		if remaining > budget {
+			sh.Traveled += budget * f
+			break
+		}
+		budget -= remaining
+		sh.System, sh.Traveled = sh.Destination, 0
+		if len(sh.Route) != 0 {
+			sh.Destination, sh.Route = sh.Route[0], sh.Route[1:]
+			if len(sh.Route) == 0 {
+				sh.Route = nil
+			}
+		}
+	}
+	if !sh.InTransit() {
+		sh.Traveled = 0
+	}
+}
+
+// String implements the Stringer interface.
+func (sh *Ship) String() string {
+	if sh.Name == "" {
+		return fmt.Sprintf("%s (%s)", sh.Id, sh.Design)
+	}
+	return fmt.Sprintf("%s %q (%s)", sh.Id, sh.Name, sh.Design)
+}
+
+// ship returns the nation's ship with the given id.
+func (n *Nation) ship(id string) *Ship {
+	for _, sh := range n.Ships {
+		if sh.Id == id {
+			return sh
+		}
+	}
+	return nil
+}
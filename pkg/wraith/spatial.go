@@ -0,0 +1,213 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"math"
+	"sort"
+)
+
+// kdTree is a 3-d tree over system coordinates.
+// It answers nearest, k-nearest, and radius queries in roughly
+// logarithmic time instead of scanning every system.
+//
+// Systems may be inserted one at a time, which is what the generators
+// do as they place systems. The tree isn't rebalanced, but randomly
+// placed systems keep it shallow enough. Use buildKDTree to index a
+// finished cluster.
+type kdTree struct {
+	root *kdNode
+	size int
+}
+
+type kdNode struct {
+	sys         *system
+	axis        int // 0, 1, or 2 for x, y, or z
+	left, right *kdNode
+}
+
+// neighbor is a system found by a query and its distance from the query point.
+type neighbor struct {
+	sys      *system
+	distance float64
+}
+
+// axis returns the x, y, or z coordinate.
+func (c coords) axis(i int) float64 {
+	switch i {
+	case 0:
+		return c.x
+	case 1:
+		return c.y
+	}
+	return c.z
+}
+
+// buildKDTree returns a balanced tree holding the systems.
+func buildKDTree(systems []*system) *kdTree {
+	list := make([]*system, len(systems))
+	copy(list, systems)
+	return &kdTree{root: buildKDNode(list, 0), size: len(list)}
+}
+
+func buildKDNode(list []*system, depth int) *kdNode {
+	if len(list) == 0 {
+		return nil
+	}
+	axis := depth % 3
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].coords.axis(axis) < list[j].coords.axis(axis)
+	})
+	mid := len(list) / 2
+	return &kdNode{
+		sys:   list[mid],
+		axis:  axis,
+		left:  buildKDNode(list[:mid], depth+1),
+		right: buildKDNode(list[mid+1:], depth+1),
+	}
+}
+
+// Len returns the number of systems in the tree.
+func (t *kdTree) Len() int {
+	return t.size
+}
+
+// insert adds the system to the tree.
+func (t *kdTree) insert(sys *system) {
+	t.size++
+	link, depth := &t.root, 0
+	for *link != nil {
+		n := *link
+		if sys.coords.axis(n.axis) < n.sys.coords.axis(n.axis) {
+			link = &n.left
+		} else {
+			link = &n.right
+		}
+		depth++
+	}
+	*link = &kdNode{sys: sys, axis: depth % 3}
+}
+
+// nearest returns the system closest to the point.
+// Systems for which skip returns true are ignored; skip may be nil.
+// It returns false if the tree has no eligible systems.
+func (t *kdTree) nearest(pt coords, skip func(*system) bool) (neighbor, bool) {
+	list := t.kNearest(pt, 1, skip)
+	if len(list) == 0 {
+		return neighbor{}, false
+	}
+	return list[0], true
+}
+
+// nearestDistance returns the distance from the point to the closest system.
+// It returns zero if the tree is empty.
+func (t *kdTree) nearestDistance(pt coords) float64 {
+	n, _ := t.nearest(pt, nil)
+	return n.distance
+}
+
+// kNearest returns up to k systems closest to the point, nearest first.
+// Systems for which skip returns true are ignored; skip may be nil.
+func (t *kdTree) kNearest(pt coords, k int, skip func(*system) bool) []neighbor {
+	if k < 1 {
+		return nil
+	}
+	best := make([]neighbor, 0, k+1)
+	var search func(n *kdNode)
+	search = func(n *kdNode) {
+		if n == nil {
+			return
+		}
+		if skip == nil || !skip(n.sys) {
+			d := pt.distance(n.sys.coords)
+			if len(best) < k || d < best[len(best)-1].distance {
+				i := sort.Search(len(best), func(i int) bool {
+					return best[i].distance > d
+				})
+				best = append(best, neighbor{})
+				copy(best[i+1:], best[i:])
+				best[i] = neighbor{sys: n.sys, distance: d}
+				if len(best) > k {
+					best = best[:k]
+				}
+			}
+		}
+		diff := pt.axis(n.axis) - n.sys.coords.axis(n.axis)
+		near, far := n.left, n.right
+		if diff >= 0 {
+			near, far = n.right, n.left
+		}
+		search(near)
+		// the far side can only hold a closer system if the
+		// splitting plane is closer than the worst candidate
+		if len(best) < k || math.Abs(diff) < best[len(best)-1].distance {
+			search(far)
+		}
+	}
+	search(t.root)
+	return best
+}
+
+// within returns the systems within the radius of the point,
+// sorted by distance and then by system id.
+func (t *kdTree) within(pt coords, radius float64) []neighbor {
+	var list []neighbor
+	t.visit(pt, radius, func(n neighbor) bool {
+		list = append(list, n)
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].distance != list[j].distance {
+			return list[i].distance < list[j].distance
+		}
+		return list[i].sys.id < list[j].sys.id
+	})
+	return list
+}
+
+// visit calls fn for every system within the radius of the point,
+// in no particular order, until fn returns false.
+// It returns false if fn stopped the visit.
+func (t *kdTree) visit(pt coords, radius float64, fn func(neighbor) bool) bool {
+	var search func(n *kdNode) bool
+	search = func(n *kdNode) bool {
+		if n == nil {
+			return true
+		}
+		if d := pt.distance(n.sys.coords); d <= radius && !fn(neighbor{sys: n.sys, distance: d}) {
+			return false
+		}
+		diff := pt.axis(n.axis) - n.sys.coords.axis(n.axis)
+		if diff <= radius && !search(n.left) {
+			return false
+		}
+		return diff < -radius || search(n.right)
+	}
+	return search(t.root)
+}
+
+// index returns the spatial index for the cluster's systems.
+// It is built the first time it is needed; the cluster must not
+// be changed after that.
+func (c *Cluster) index() *kdTree {
+	c.spatial.Do(func() {
+		c.spatial.tree = buildKDTree(c.systems)
+	})
+	return c.spatial.tree
+}
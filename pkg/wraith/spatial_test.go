@@ -0,0 +1,91 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestKDTree(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	point := func() coords {
+		return coords{x: r.Float64()*30 - 15, y: r.Float64()*30 - 15, z: r.Float64()*30 - 15}
+	}
+	var systems []*system
+	for id := 0; id < 300; id++ {
+		systems = append(systems, &system{id: id, coords: point()})
+	}
+
+	// the balanced tree and a tree built one insert at a time must both
+	// agree with a linear scan
+	inserted := &kdTree{}
+	for _, sys := range systems {
+		inserted.insert(sys)
+	}
+	for _, tc := range []struct {
+		id   int
+		tree *kdTree
+	}{
+		{1, buildKDTree(systems)},
+		{2, inserted},
+	} {
+		if tc.tree.Len() != len(systems) {
+			t.Errorf("%d: len: expected %d: got %d\n", tc.id, len(systems), tc.tree.Len())
+		}
+		for i := 0; i < 50; i++ {
+			pt := point()
+			var scan []neighbor
+			for _, sys := range systems {
+				scan = append(scan, neighbor{sys: sys, distance: pt.distance(sys.coords)})
+			}
+			sort.Slice(scan, func(i, j int) bool {
+				return scan[i].distance < scan[j].distance
+			})
+
+			if n, ok := tc.tree.nearest(pt, nil); !ok || n.sys != scan[0].sys {
+				t.Errorf("%d: nearest: expected %d: got %d\n", tc.id, scan[0].sys.id, n.sys.id)
+			}
+			skipNearest := func(sys *system) bool { return sys == scan[0].sys }
+			if n, _ := tc.tree.nearest(pt, skipNearest); n.sys != scan[1].sys {
+				t.Errorf("%d: nearest with skip: expected %d: got %d\n", tc.id, scan[1].sys.id, n.sys.id)
+			}
+			knn := tc.tree.kNearest(pt, 5, nil)
+			for j := range knn {
+				if knn[j].sys != scan[j].sys {
+					t.Errorf("%d: k-nearest %d: expected %d: got %d\n", tc.id, j, scan[j].sys.id, knn[j].sys.id)
+				}
+			}
+			expect := 0
+			for _, n := range scan {
+				if n.distance <= 6 {
+					expect++
+				}
+			}
+			if got := tc.tree.within(pt, 6); len(got) != expect {
+				t.Errorf("%d: within: expected %d: got %d\n", tc.id, expect, len(got))
+			}
+		}
+	}
+
+	if _, ok := (&kdTree{}).nearest(coords{}, nil); ok {
+		t.Errorf("empty: expected no nearest system\n")
+	}
+}
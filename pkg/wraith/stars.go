@@ -0,0 +1,291 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// spectralType is the Harvard spectral class of a star, from hottest to coolest.
+type spectralType int
+
+const (
+	typeO spectralType = iota
+	typeB
+	typeA
+	typeF
+	typeG
+	typeK
+	typeM
+)
+
+// spectralTypes lists the spectral types from hottest to coolest.
+var spectralTypes = []spectralType{typeO, typeB, typeA, typeF, typeG, typeK, typeM}
+
+// String implements the Stringer interface.
+func (t spectralType) String() string {
+	if typeO <= t && t <= typeM {
+		return "OBAFGKM"[t : t+1]
+	}
+	return "unknown"
+}
+
+// luminosity returns the luminosity of a main sequence star of the type,
+// relative to the sun.
+func (t spectralType) luminosity() float64 {
+	return [...]float64{typeO: 30_000, typeB: 200, typeA: 12, typeF: 2.5, typeG: 1, typeK: 0.3, typeM: 0.04}[t]
+}
+
+// color returns the color used to draw stars of the type.
+func (t spectralType) color() string {
+	return [...]string{typeO: "#9bb0ff", typeB: "#aabfff", typeA: "#cad7ff", typeF: "#f8f7ff", typeG: "#fff4ea", typeK: "#ffd2a1", typeM: "#ffad51"}[t]
+}
+
+// parseSpectralType returns the type matching the string.
+func parseSpectralType(s string) (spectralType, bool) {
+	for _, t := range spectralTypes {
+		if t.String() == strings.ToUpper(s) {
+			return t, true
+		}
+	}
+	return typeG, false
+}
+
+// sizeClass is the size of a star.
+type sizeClass int
+
+const (
+	dwarf sizeClass = iota
+	mainSequence
+	subgiant
+	giant
+	supergiant
+)
+
+// sizeClasses lists the size classes from smallest to largest.
+var sizeClasses = []sizeClass{dwarf, mainSequence, subgiant, giant, supergiant}
+
+// String implements the Stringer interface.
+func (s sizeClass) String() string {
+	switch s {
+	case dwarf:
+		return "dwarf"
+	case mainSequence:
+		return "main-sequence"
+	case subgiant:
+		return "subgiant"
+	case giant:
+		return "giant"
+	case supergiant:
+		return "supergiant"
+	}
+	return "unknown"
+}
+
+// luminosity returns the luminosity of a star of the size class relative
+// to a main sequence star of the same spectral type.
+func (s sizeClass) luminosity() float64 {
+	return [...]float64{dwarf: 0.1, mainSequence: 1, subgiant: 3, giant: 50, supergiant: 1_000}[s]
+}
+
+// parseSizeClass returns the size class matching the string.
+func parseSizeClass(s string) (sizeClass, bool) {
+	for _, sc := range sizeClasses {
+		if sc.String() == strings.ToLower(s) {
+			return sc, true
+		}
+	}
+	return mainSequence, false
+}
+
+// StarDistribution is the relative frequency of each spectral type and
+// size class. The keys are the names of the types ("O" through "M") and
+// classes ("dwarf", "main-sequence", "subgiant", "giant", "supergiant").
+// Missing keys have a weight of zero.
+type StarDistribution struct {
+	Types map[string]int
+	Sizes map[string]int
+}
+
+// DefaultStarDistribution returns the distribution used when the
+// parameters don't have one. It has more sun-like stars than the
+// real galaxy so that there are worlds worth fighting over.
+func DefaultStarDistribution() StarDistribution {
+	return StarDistribution{
+		Types: map[string]int{"O": 1, "B": 3, "A": 6, "F": 14, "G": 25, "K": 26, "M": 25},
+		Sizes: map[string]int{"dwarf": 6, "main-sequence": 80, "subgiant": 8, "giant": 5, "supergiant": 1},
+	}
+}
+
+// ParseWeights parses a list of weights like "G=25,K=26,M=25".
+// It is used for star distributions and anomalies.
+func ParseWeights(s string) (map[string]int, error) {
+	weights := make(map[string]int)
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: expected name=weight", field)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%q: weight must be a non-negative integer", field)
+		}
+		weights[strings.TrimSpace(key)] = n
+	}
+	return weights, nil
+}
+
+// Validate returns an error if the distribution can't be used.
+// An empty distribution is valid; it is replaced with the default.
+func (d StarDistribution) Validate() error {
+	if d.Types != nil {
+		if err := validateWeights("spectral type", d.Types, func(s string) bool { _, ok := parseSpectralType(s); return ok }); err != nil {
+			return err
+		}
+	}
+	if d.Sizes != nil {
+		if err := validateWeights("size class", d.Sizes, func(s string) bool { _, ok := parseSizeClass(s); return ok }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateWeights(what string, weights map[string]int, known func(string) bool) error {
+	total := 0
+	for key, n := range weights {
+		if !known(key) {
+			return fmt.Errorf("unknown %s %q", what, key)
+		} else if n < 0 {
+			return fmt.Errorf("%s %q: weight must not be negative", what, key)
+		}
+		total += n
+	}
+	if total == 0 {
+		return fmt.Errorf("at least one %s must have a weight", what)
+	}
+	return nil
+}
+
+// classify sets the spectral type, size class, and luminosity of every star.
+// Home systems always get a sun-like star.
+func (c *Cluster) classify(r *rand.Rand, d StarDistribution) {
+	def := DefaultStarDistribution()
+	if d.Types == nil {
+		d.Types = def.Types
+	}
+	if d.Sizes == nil {
+		d.Sizes = def.Sizes
+	}
+	var types, sizes []int
+	for _, t := range spectralTypes {
+		types = append(types, d.Types[t.String()])
+	}
+	for _, sc := range sizeClasses {
+		sizes = append(sizes, d.Sizes[sc.String()])
+	}
+	for _, sys := range c.systems {
+		for _, s := range sys.stars {
+			if sys.home {
+				s.spectral, s.size, s.luminosity = typeG, mainSequence, 1
+				continue
+			}
+			s.spectral = spectralTypes[pick(r, types)]
+			s.size = sizeClasses[pick(r, sizes)]
+			// vary the luminosity by up to a third either way, and round it
+			// so that it survives a trip through JSON unchanged
+			lum := s.spectral.luminosity() * s.size.luminosity() * (1 + (r.Float64()-0.5)*2/3)
+			s.luminosity = roundLuminosity(lum)
+		}
+	}
+}
+
+// pick returns an index chosen at random with the given weights.
+func pick(r *rand.Rand, weights []int) int {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	n := r.Intn(total)
+	for i, w := range weights {
+		if n < w {
+			return i
+		}
+		n -= w
+	}
+	panic("assert(weights are not negative)")
+}
+
+// roundLuminosity rounds to three significant digits.
+func roundLuminosity(lum float64) float64 {
+	if lum <= 0 {
+		return 0
+	}
+	scale := math.Pow(10, 2-math.Floor(math.Log10(lum)))
+	return math.Round(lum*scale) / scale
+}
+
+// orbitDistance returns the distance of the orbit from its star in AU.
+// Each orbit is 1.6 times farther out than the one inside it;
+// orbit 4 is about 1 AU.
+func orbitDistance(orbit int) float64 {
+	return 0.25 * math.Pow(1.6, float64(orbit-1))
+}
+
+// habitableOrbit returns the orbit, possibly fractional, at the center of
+// the star's habitable zone. It is 4 for a sun-like star.
+func (s *star) habitableOrbit() float64 {
+	lum := s.luminosity
+	if lum <= 0 {
+		lum = 1
+	}
+	// the zone is at the distance where the star is as bright as the sun is at 1 AU
+	return 1 + math.Log(math.Sqrt(lum)/orbitDistance(1))/math.Log(1.6)
+}
+
+// color returns the color used to draw the star.
+func (s *star) color() string {
+	return s.spectral.color()
+}
+
+// primary returns the brightest star in the system, or nil if the system has no stars.
+func (sys *system) primary() *star {
+	var brightest *star
+	for _, s := range sys.stars {
+		if brightest == nil || s.luminosity > brightest.luminosity {
+			brightest = s
+		}
+	}
+	return brightest
+}
+
+// color returns the color used to draw the system.
+func (sys *system) color() string {
+	if s := sys.primary(); s != nil {
+		return s.color()
+	}
+	return sys.anomaly.color()
+}
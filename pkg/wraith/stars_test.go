@@ -0,0 +1,126 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestStarDistribution(t *testing.T) {
+	p := Params{Seed: 5, Systems: 64, MinStars: 40, Radius: 12, Clearance: 4.5}
+	p.Stars = StarDistribution{Types: map[string]int{"M": 1}, Sizes: map[string]int{"giant": 1}}
+	c, err := Generate("sphere", p)
+	if err != nil {
+		t.Fatalf("generate: %v\n", err)
+	}
+	for _, sys := range c.systems {
+		for _, s := range sys.stars {
+			if sys.id == 0 {
+				if s.spectral != typeG || s.size != mainSequence || s.luminosity != 1 {
+					t.Errorf("home: expected G main-sequence 1: got %v %v %v\n", s.spectral, s.size, s.luminosity)
+				}
+			} else if s.spectral != typeM || s.size != giant {
+				t.Errorf("system %d: expected M giant: got %v %v\n", sys.id, s.spectral, s.size)
+			}
+		}
+	}
+
+	// the stars survive a trip through JSON
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again Cluster
+	if err = json.Unmarshal(data, &again); err != nil {
+		t.Fatal(err)
+	}
+	for i, sys := range c.systems {
+		for j, s := range sys.stars {
+			o := again.systems[i].stars[j]
+			if s.spectral != o.spectral || s.size != o.size || s.luminosity != o.luminosity {
+				t.Errorf("system %d star %d: expected %v %v %v: got %v %v %v\n", i, j, s.spectral, s.size, s.luminosity, o.spectral, o.size, o.luminosity)
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		id int
+		d  StarDistribution
+		ok bool
+	}{
+		{1, StarDistribution{}, true},
+		{2, DefaultStarDistribution(), true},
+		{3, StarDistribution{Types: map[string]int{"X": 1}}, false},
+		{4, StarDistribution{Types: map[string]int{"G": 0}}, false},
+		{5, StarDistribution{Sizes: map[string]int{"huge": 1}}, false},
+		{6, StarDistribution{Sizes: map[string]int{"dwarf": -1, "giant": 2}}, false},
+	} {
+		if err := tc.d.Validate(); (err == nil) != tc.ok {
+			t.Errorf("%d: validate: expected ok %v: got %v\n", tc.id, tc.ok, err)
+		}
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	w, err := ParseWeights("G=25, K = 26,M=0")
+	if err != nil {
+		t.Fatalf("parse: %v\n", err)
+	} else if len(w) != 3 || w["G"] != 25 || w["K"] != 26 || w["M"] != 0 {
+		t.Errorf("parse: expected G=25 K=26 M=0: got %v\n", w)
+	}
+	for _, s := range []string{"G", "G=x", "G=-1"} {
+		if _, err := ParseWeights(s); err == nil {
+			t.Errorf("parse %q: expected error: got nil\n", s)
+		}
+	}
+}
+
+func TestHabitableZone(t *testing.T) {
+	for _, tc := range []struct {
+		id         int
+		luminosity float64
+		min, max   float64
+	}{
+		{1, 1, 3.5, 4.5},
+		{2, 0.04, 0, 1.5},
+		{3, 12, 6, 7},
+	} {
+		s := &star{luminosity: tc.luminosity}
+		if hz := s.habitableOrbit(); hz < tc.min || hz > tc.max {
+			t.Errorf("%d: habitable orbit: expected %v to %v: got %v\n", tc.id, tc.min, tc.max, hz)
+		}
+	}
+
+	// habitable worlds are only found near the center of the zone
+	c, err := Generate("sphere", Params{Seed: 9, Systems: 256, MinStars: 128, Radius: 15, Clearance: 4.5})
+	if err != nil {
+		t.Fatalf("generate: %v\n", err)
+	}
+	for _, sys := range c.systems[1:] {
+		for _, s := range sys.stars {
+			for _, p := range s.planets {
+				if d := math.Abs(float64(p.orbit) - s.habitableOrbit()); p.habitability > 0 && d >= 1.5 {
+					t.Errorf("system %d: orbit %d is %.2f from the zone: got habitability %d\n", sys.id, p.orbit, d, p.habitability)
+				}
+			}
+		}
+	}
+}
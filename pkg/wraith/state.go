@@ -0,0 +1,182 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+const (
+	// homeSurveyRange is the distance from the home system, in light years,
+	// that every nation has surveyed before the game starts.
+	homeSurveyRange = 8.0
+	// startingIndustry is the industry every nation starts with.
+	startingIndustry = 20
+	// startingScouts is the number of scouts every nation starts with.
+	startingScouts = 2
+	// homeSensors is the range of the sensors at every nation's home colony.
+	homeSensors = 5.0
+	// startingPopulation and startingGoods are in every nation's home colony.
+	startingPopulation, startingGoods = 100, 50
+)
+
+// State is the state of a game at the end of a turn.
+type State struct {
+	Turn     int                `json:"turn"`
+	NextShip int                `json:"next-ship"` // sequence for ship ids
+	Designs  map[string]*Design `json:"designs"`
+	Nations  map[string]*Nation `json:"nations"`
+}
+
+// Nation is the state of a single nation.
+type Nation struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	HomeSystem int    `json:"home-system"`
+	// Industry is the amount of industry the nation can spend each turn.
+	Industry int `json:"industry"`
+	// Known is the sorted list of ids of the systems the nation knows about.
+	Known    []int     `json:"known,omitempty"`
+	Colonies []*Colony `json:"colonies,omitempty"`
+	Ships    []*Ship   `json:"ships,omitempty"`
+	// Contacts are the foreign ships and colonies detected during the last turn.
+	Contacts []*Contact `json:"contacts,omitempty"`
+}
+
+// NewState returns the starting state for a game.
+// Every nation starts with a colony in its own home system, in the order
+// the generator placed them, and knows the nearby systems that aren't
+// hidden by dust clouds. It returns an error if the cluster doesn't have
+// a home system for every nation.
+func NewState(c *Cluster, nations ...string) (*State, error) {
+	homes := c.homes()
+	if len(homes) < len(nations) {
+		return nil, fmt.Errorf("%d nations but only %d home systems", len(nations), len(homes))
+	}
+	s := &State{Designs: defaultDesigns(), Nations: make(map[string]*Nation)}
+	for i, id := range nations {
+		home := homes[i]
+		n := &Nation{Id: id, Name: id, HomeSystem: home.id, Industry: startingIndustry}
+		for _, nb := range c.index().within(home.coords, homeSurveyRange) {
+			if nb.sys == home || !c.obscured(home.coords, nb.sys.coords) {
+				n.Learn(nb.sys.id)
+			}
+		}
+		n.Colonies = append(n.Colonies, &Colony{System: n.HomeSystem, Sensors: homeSensors, Population: startingPopulation, Goods: startingGoods})
+		for i := 0; i < startingScouts; i++ {
+			s.newShip(n, "scout")
+		}
+		s.Nations[id] = n
+	}
+	return s, nil
+}
+
+// newShip adds a ship of the design to the nation's home system.
+func (s *State) newShip(n *Nation, design string) *Ship {
+	s.NextShip++
+	sh := &Ship{
+		Id:          fmt.Sprintf("S%d", s.NextShip),
+		Design:      design,
+		System:      n.HomeSystem,
+		Destination: n.HomeSystem,
+	}
+	n.Ships = append(n.Ships, sh)
+	return sh
+}
+
+// nationIds returns the ids of all the nations, sorted.
+func (s *State) nationIds() []string {
+	var ids []string
+	for id := range s.Nations {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// clone returns a deep copy of the state.
+func (s *State) clone() *State {
+	data, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+	var c State
+	if err = json.Unmarshal(data, &c); err != nil {
+		panic(err)
+	}
+	return &c
+}
+
+// Knows returns true if the nation knows about the system.
+func (n *Nation) Knows(id int) bool {
+	i := sort.SearchInts(n.Known, id)
+	return i < len(n.Known) && n.Known[i] == id
+}
+
+// Learn adds the system to the nation's knowledge.
+func (n *Nation) Learn(id int) {
+	i := sort.SearchInts(n.Known, id)
+	if i < len(n.Known) && n.Known[i] == id {
+		return
+	}
+	n.Known = append(n.Known, 0)
+	copy(n.Known[i+1:], n.Known[i:])
+	n.Known[i] = id
+}
+
+// ReadState loads the game state from a JSON file.
+func ReadState(filename string) (*State, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	s, err := DecodeState(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return s, nil
+}
+
+// DecodeState loads the game state from JSON.
+// Missing designs and nations are replaced with defaults.
+func DecodeState(data []byte) (*State, error) {
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.Designs == nil {
+		s.Designs = defaultDesigns()
+	}
+	if s.Nations == nil {
+		s.Nations = make(map[string]*Nation)
+	}
+	return &s, nil
+}
+
+// Write saves the game state to a JSON file.
+func (s *State) Write(filename string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0666)
+}
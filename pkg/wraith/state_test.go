@@ -0,0 +1,68 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"testing"
+)
+
+func TestHomeSystems(t *testing.T) {
+	for _, name := range []string{"rings", "sphere", "dense", "spiral", "clustered"} {
+		p := DefaultParams()
+		p.Seed, p.Homes = 5, 4
+		c, err := Generate(name, p)
+		if err != nil {
+			t.Fatalf("%s: generate: %v\n", name, err)
+		}
+		s, err := NewState(c, "n1", "n2", "n3", "n4")
+		if err != nil {
+			t.Fatalf("%s: state: %v\n", name, err)
+		}
+		if home := s.Nations["n1"].HomeSystem; home != 0 {
+			t.Errorf("%s: n1: expected the home system at the origin: got %d\n", name, home)
+		}
+		homes := make(map[int]string)
+		for _, id := range s.nationIds() {
+			n := s.Nations[id]
+			sys := c.systems[n.HomeSystem]
+			if other, ok := homes[sys.id]; ok {
+				t.Errorf("%s: %s: expected its own home system: got %d, same as %s\n", name, id, sys.id, other)
+			} else if !sys.home || len(sys.stars) != 1 {
+				t.Errorf("%s: %s: expected a home system with one star: got %d\n", name, id, sys.id)
+			} else if st := sys.stars[0]; st.spectral != typeG || st.size != mainSequence {
+				t.Errorf("%s: %s: expected a sun-like star: got %s %s\n", name, id, st.spectral, st.size)
+			} else if habitable := st.planets[homeOrbit-1].habitability; habitable != 25 {
+				t.Errorf("%s: %s: expected an ideal planet in the home orbit: got %d\n", name, id, habitable)
+			} else if col := n.colony(sys.id); col == nil || col.Population != startingPopulation {
+				t.Errorf("%s: %s: expected a colony in the home system: got %+v\n", name, id, col)
+			} else if !n.Knows(sys.id) {
+				t.Errorf("%s: %s: expected to know the home system\n", name, id)
+			}
+			for _, sh := range n.Ships {
+				if sh.System != sys.id {
+					t.Errorf("%s: %s: %s: expected ship in %d: got %d\n", name, id, sh.Id, sys.id, sh.System)
+				}
+			}
+			homes[sys.id] = id
+		}
+		if _, err = NewState(c, "n1", "n2", "n3", "n4", "n5"); err == nil {
+			t.Errorf("%s: five nations: expected error: got nil\n", name)
+		}
+	}
+}
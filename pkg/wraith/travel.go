@@ -0,0 +1,178 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// maxTravelOrigins is the most origins in the travel section of a text report.
+const maxTravelOrigins = 6
+
+// TravelMatrix holds the distances and travel times between every pair
+// of systems known to a nation. Row i and column i are Systems[i].
+// In clusters with jump lanes, the distance is the length of the shortest
+// path through the known systems, and -1 means there is no known path.
+type TravelMatrix struct {
+	Systems   []SystemView  `json:"systems"`
+	Distances [][]float64   `json:"distances"` // light years, rounded to 0.01
+	Designs   []TravelTimes `json:"designs"`
+}
+
+// TravelTimes are the turns a ship of the design needs to travel between
+// the systems in a TravelMatrix.
+type TravelTimes struct {
+	Design string  `json:"design"`
+	Speed  float64 `json:"speed"`
+	Turns  [][]int `json:"turns"`
+}
+
+// TravelMatrix returns the travel matrix for the systems known to the nation.
+// Designs that can't move are left out.
+func (c *Cluster) TravelMatrix(s *State, n *Nation) *TravelMatrix {
+	tm := &TravelMatrix{Systems: []SystemView{}, Distances: [][]float64{}, Designs: []TravelTimes{}}
+	for _, id := range n.Known {
+		tm.Systems = append(tm.Systems, c.systems[id].view(n.Knows))
+	}
+	designs, lanes := s.movers(), c.hasLanes()
+	for _, d := range designs {
+		tm.Designs = append(tm.Designs, TravelTimes{Design: d.Name, Speed: d.Speed})
+	}
+	for _, from := range n.Known {
+		reaches := c.reachable(from, n.Known, n.Knows, lanes)
+		row := make([]float64, len(n.Known))
+		for j, r := range reaches {
+			if r.ok {
+				row[j] = math.Round(r.distance*100) / 100
+			} else {
+				row[j] = -1
+			}
+		}
+		tm.Distances = append(tm.Distances, row)
+		for i, d := range designs {
+			turns := make([]int, len(n.Known))
+			for j, r := range reaches {
+				turns[j] = travelTurns(d, r)
+			}
+			tm.Designs[i].Turns = append(tm.Designs[i].Turns, turns)
+		}
+	}
+	return tm
+}
+
+// movers returns the designs that can move, sorted by name.
+func (s *State) movers() []*Design {
+	var list []*Design
+	for _, d := range s.Designs {
+		if d.Speed > 0 {
+			list = append(list, d)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// travelTurns returns the turns a ship of the design needs to travel the
+// path, or -1 if there is no path. It follows the movement rules: a ship
+// ordered to move travels its full speed on the same turn, and nebulae
+// slow it down.
+func travelTurns(d *Design, r reach) int {
+	if !r.ok {
+		return -1
+	}
+	return d.TurnsToArrive(r.cost)
+}
+
+// travelTable returns the travel section for a plain text report.
+// The rows are the known systems sorted by distance from home, and the
+// columns are the systems where the nation has ships. Each cell lists
+// the turns for every design that can move, separated by slashes,
+// or a dash if there is no known path.
+func (c *Cluster) travelTable(s *State, n *Nation) []string {
+	designs := s.movers()
+	if len(designs) == 0 {
+		return nil
+	}
+	var origins []int
+	seen := make(map[int]bool)
+	for _, sh := range n.Ships {
+		if !sh.InTransit() && !seen[sh.System] {
+			seen[sh.System] = true
+			origins = append(origins, sh.System)
+		}
+	}
+	if len(origins) == 0 {
+		origins = []int{n.HomeSystem}
+	}
+	sort.Ints(origins)
+	var lines []string
+	var names []string
+	for _, d := range designs {
+		names = append(names, d.Name)
+	}
+	lines = append(lines, fmt.Sprintf("turns to arrive for %s", strings.Join(names, "/")))
+	if len(origins) > maxTravelOrigins {
+		lines = append(lines, fmt.Sprintf("showing %d of %d systems with ships; the API has the full matrix", maxTravelOrigins, len(origins)))
+		origins = origins[:maxTravelOrigins]
+	}
+
+	width := 3*len(designs) - 1 // room for two digit turns
+	for _, from := range origins {
+		if l := len(c.systems[from].label()); l > width {
+			width = l
+		}
+	}
+	header := fmt.Sprintf("%-12s", "System")
+	for _, from := range origins {
+		header += fmt.Sprintf("  %*s", width, c.systems[from].label())
+	}
+	lines = append(lines, header)
+
+	home := c.systems[n.HomeSystem].coords
+	known := append([]int(nil), n.Known...)
+	sort.SliceStable(known, func(i, j int) bool {
+		return c.systems[known[i]].coords.distance(home) < c.systems[known[j]].coords.distance(home)
+	})
+	lanes := c.hasLanes()
+	reaches := make([][]reach, len(origins))
+	for i, from := range origins {
+		reaches[i] = c.reachable(from, known, n.Knows, lanes)
+	}
+	for j, to := range known {
+		line := fmt.Sprintf("%-12s", c.systems[to].label())
+		for i := range origins {
+			var turns []string
+			for _, d := range designs {
+				if k := travelTurns(d, reaches[i][j]); k < 0 {
+					turns = append(turns, "-")
+				} else {
+					turns = append(turns, fmt.Sprint(k))
+				}
+			}
+			line += fmt.Sprintf("  %*s", width, strings.Join(turns, "/"))
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,79 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTravelMatrix(t *testing.T) {
+	c := &Cluster{systems: []*system{
+		{name: "Home", home: true, stars: []*star{{}}},
+		{name: "Near", coords: coords{x: 3}},
+		{name: "Far", coords: coords{x: 3, y: 4}},
+		{name: "Hidden", coords: coords{x: -9}},
+	}}
+	c.link()
+	s := &State{Designs: defaultDesigns(), Nations: map[string]*Nation{}}
+	s.Designs["base"] = &Design{Name: "base", Cost: 50}
+	n := &Nation{Id: "alpha"}
+	for _, id := range []int{0, 1, 2} {
+		n.Learn(id)
+	}
+	s.Nations[n.Id] = n
+
+	tm := c.TravelMatrix(s, n)
+	if len(tm.Systems) != 3 || len(tm.Distances) != 3 {
+		t.Fatalf("systems: expected 3: got %d %d\n", len(tm.Systems), len(tm.Distances))
+	}
+	if len(tm.Designs) != 2 || tm.Designs[0].Design != "scout" || tm.Designs[1].Design != "transport" {
+		t.Fatalf("designs: expected scout and transport: got %+v\n", tm.Designs)
+	}
+	for _, tc := range []struct {
+		id       int
+		from, to int
+		distance float64
+		scout    int
+		trans    int
+	}{
+		{1, 0, 0, 0, 0, 0},
+		{2, 0, 1, 3, 1, 2},
+		{3, 0, 2, 5, 2, 4},
+		{4, 2, 1, 4, 2, 3},
+	} {
+		if got := tm.Distances[tc.from][tc.to]; got != tc.distance {
+			t.Errorf("%d: distance: expected %v: got %v\n", tc.id, tc.distance, got)
+		}
+		if got := tm.Designs[0].Turns[tc.from][tc.to]; got != tc.scout {
+			t.Errorf("%d: scout: expected %d: got %d\n", tc.id, tc.scout, got)
+		}
+		if got := tm.Designs[1].Turns[tc.from][tc.to]; got != tc.trans {
+			t.Errorf("%d: transport: expected %d: got %d\n", tc.id, tc.trans, got)
+		}
+	}
+
+	n.Ships = []*Ship{{Id: "S1", Design: "scout", System: 2, Destination: 2}}
+	lines := c.travelTable(s, n)
+	if len(lines) != 5 || !strings.Contains(lines[0], "scout/transport") {
+		t.Fatalf("table: expected a header and 3 systems: got %q\n", lines)
+	} else if got := strings.Fields(lines[2]); len(got) != 2 || got[0] != "Home" || got[1] != "2/4" {
+		t.Errorf("table: expected Home 2/4: got %q\n", lines[2])
+	}
+}
@@ -0,0 +1,268 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"fmt"
+	"github.com/mdhender/wraithe/pkg/orders"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// Process runs a turn.
+// It returns the state at the end of the turn and a report for every nation.
+// The previous state is not changed.
+//
+// Processing is deterministic: the same cluster, state, orders, and seed
+// always produce the same results.
+//
+// Orders are executed in phases. Every nation completes a phase before
+// any nation starts the next phase. Within a phase, nations are processed
+// in a random order (driven by the seed) so that no nation always goes first.
+func Process(c *Cluster, prev *State, ordersByNation map[string][]*orders.Order, seed int64) (*State, map[string]*Report) {
+	s := prev.clone()
+	s.Turn++
+
+	rng := rand.New(rand.NewSource(seed))
+
+	t := &turn{
+		cluster:   c,
+		state:     s,
+		orders:    ordersByNation,
+		reports:   make(map[string]*Report),
+		errors:    make(map[string][]orderError),
+		spent:     make(map[string]int),
+		shipments: make(map[string]*Section),
+		nations:   s.nationIds(),
+		rng:       rng,
+	}
+	rng.Shuffle(len(t.nations), func(i, j int) {
+		t.nations[i], t.nations[j] = t.nations[j], t.nations[i]
+	})
+	for _, id := range t.nations {
+		t.reports[id] = &Report{Turn: s.Turn, Nation: id}
+		t.shipments[id] = &Section{Title: "Shipments"}
+	}
+
+	for _, phase := range []struct {
+		verb string
+		fn   func(n *Nation, o *orders.Order) string
+	}{
+		{"name", t.name},
+		{"build", t.build},
+		{"unload", t.unload},
+		{"transfer", t.transfer},
+		{"load", t.load},
+		{"supply", t.supply},
+		{"move", t.move},
+	} {
+		for _, id := range t.nations {
+			n := s.Nations[id]
+			for _, o := range ordersByNation[id] {
+				if o.Verb != phase.verb {
+					continue
+				}
+				if msg := phase.fn(n, o); msg != "" {
+					t.errors[id] = append(t.errors[id], orderError{line: o.Line, text: fmt.Sprintf("line %d: %s: %s", o.Line, o, msg)})
+				}
+			}
+		}
+	}
+
+	t.logistics()
+	t.movement()
+	t.exploration()
+	t.detection()
+
+	for _, id := range t.nations {
+		r := t.reports[id]
+		if errs := t.errors[id]; len(errs) != 0 {
+			// errors are added to the report only if there are any,
+			// in the order the player wrote the orders
+			sort.SliceStable(errs, func(i, j int) bool {
+				return errs[i].line < errs[j].line
+			})
+			sect := r.Add("Order Errors")
+			for _, e := range errs {
+				sect.Lines = append(sect.Lines, e.text)
+			}
+		}
+		if len(t.shipments[id].Lines) != 0 {
+			r.Sections = append(r.Sections, t.shipments[id])
+		}
+		t.status(s.Nations[id], r)
+		t.charts(s.Nations[id], r)
+	}
+
+	return s, t.reports
+}
+
+// orderError is an order that failed and the line it was on.
+type orderError struct {
+	line int
+	text string
+}
+
+// turn holds the working data while a turn is processed.
+type turn struct {
+	cluster *Cluster
+	state   *State
+	orders  map[string][]*orders.Order
+	reports map[string]*Report
+	nations []string                // ids of the nations, in processing order
+	errors  map[string][]orderError // order errors for each nation
+	spent   map[string]int          // industry each nation has used this turn
+	// shipments records the loading and unloading on supply routes for each nation.
+	shipments map[string]*Section
+	rng       *rand.Rand // driven by the turn's seed
+}
+
+// name implements the "name SHIP TEXT" order.
+func (t *turn) name(n *Nation, o *orders.Order) string {
+	sh := n.ship(o.Args[0])
+	if sh == nil {
+		return "no such ship"
+	}
+	sh.Name = o.Args[1]
+	return ""
+}
+
+// build implements the "build DESIGN QUANTITY" order.
+// Ships are built in the home system. If the nation can't afford all
+// the ships, it builds as many as it can.
+func (t *turn) build(n *Nation, o *orders.Order) string {
+	d, ok := t.state.Designs[o.Args[0]]
+	if !ok {
+		return "no such design"
+	}
+	qty, _ := strconv.Atoi(o.Args[1])
+	built := 0
+	for ; built < qty && t.spent[n.Id]+d.Cost <= n.Industry; built++ {
+		t.spent[n.Id] += d.Cost
+		t.state.newShip(n, d.Name)
+	}
+	if built < qty {
+		return "not enough industry: built " + strconv.Itoa(built)
+	}
+	return ""
+}
+
+// move implements the "move SHIP SYSTEM" order.
+// Ships may only be sent to systems the nation knows about.
+// Ships already in transit can't change course.
+// Moving a ship stops its supply route.
+// In clusters with jump lanes, the ship takes the shortest path
+// through the systems the nation knows about.
+func (t *turn) move(n *Nation, o *orders.Order) string {
+	sh := n.ship(o.Args[0])
+	if sh == nil {
+		return "no such ship"
+	} else if sh.InTransit() {
+		return "ship is in transit"
+	}
+	dest, ok := t.cluster.Lookup(o.Args[1])
+	if !ok || !n.Knows(dest) {
+		return "no such system"
+	}
+	path, _, ok := t.cluster.route(sh.System, dest, n.Knows)
+	if !ok {
+		return "no known lanes lead to the system"
+	}
+	sh.follow(path)
+	sh.Supply = nil
+	return ""
+}
+
+// movement moves every ship that is in transit toward its destination.
+func (t *turn) movement() {
+	for _, id := range t.nations {
+		for _, sh := range t.state.Nations[id].Ships {
+			if !sh.InTransit() {
+				continue
+			}
+			d, ok := t.state.Designs[sh.Design]
+			if !ok {
+				continue
+			}
+			sh.advance(t.cluster, d.Speed)
+		}
+	}
+}
+
+// exploration adds the systems that ships have reached to their nation's
+// knowledge. Ships in transit have reached the system they left.
+func (t *turn) exploration() {
+	for _, id := range t.nations {
+		n := t.state.Nations[id]
+		for _, sh := range n.Ships {
+			n.Learn(sh.System)
+		}
+	}
+}
+
+// status adds the nation's status to the report.
+func (t *turn) status(n *Nation, r *Report) {
+	sect := r.Add("Status")
+	sect.Printf("industry: %d, spent: %d", n.Industry, t.spent[n.Id])
+	sect.Printf("known systems: %d", len(n.Known))
+
+	sect = r.Add("Colonies")
+	for _, col := range n.Colonies {
+		sect.Printf("%s: population %d, goods %d", t.cluster.SystemName(col.System), col.Population, col.Goods)
+	}
+
+	sect = r.Add("Ships")
+	for _, sh := range n.Ships {
+		var line string
+		if sh.InTransit() {
+			pos := sh.position(t.cluster)
+			if len(sh.Route) == 0 {
+				line = fmt.Sprintf("%s: in transit from %s to %s, at %s", sh, t.cluster.SystemName(sh.System), t.cluster.SystemName(sh.Destination), pos.xyz())
+			} else {
+				line = fmt.Sprintf("%s: in transit from %s to %s by way of %s, at %s", sh, t.cluster.SystemName(sh.System), t.cluster.SystemName(sh.FinalDestination()), t.cluster.SystemName(sh.Destination), pos.xyz())
+			}
+		} else {
+			line = fmt.Sprintf("%s: in %s", sh, t.cluster.SystemName(sh.System))
+		}
+		if m := sh.manifest(); m != "" {
+			line += ", " + m
+		}
+		if sup := sh.Supply; sup != nil {
+			line += fmt.Sprintf(", supplying %s from %s to %s", sup.Cargo, t.cluster.SystemName(sup.From), t.cluster.SystemName(sup.To))
+		}
+		sect.Printf("%s", line)
+	}
+
+	sect = r.Add("Contacts")
+	for _, ct := range n.Contacts {
+		sect.Printf("%s", ct.describe(t.cluster))
+	}
+	if len(n.Contacts) == 0 {
+		sect.Printf("none")
+	}
+}
+
+// charts adds the text map, the table of known systems, and the travel
+// times to the report.
+func (t *turn) charts(n *Nation, r *Report) {
+	r.Add("Known Systems").Lines = t.cluster.systemTable(n)
+	r.Add("Travel Times").Lines = t.cluster.travelTable(t.state, n)
+	r.Add("Map").Lines = t.cluster.asciiMap(n)
+}
@@ -0,0 +1,180 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"math"
+	"strings"
+)
+
+// SystemView is the public summary of a system.
+type SystemView struct {
+	Id       int     `json:"id"`
+	Name     string  `json:"name"`
+	Ring     int     `json:"ring"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Z        float64 `json:"z"`
+	Stars    int     `json:"stars"`
+	Color    string  `json:"color"`              // color of the brightest star or the anomaly
+	Anomaly  string  `json:"anomaly,omitempty"`  // "dead", "nebula", "dust", or "rogue" if there are no stars
+	Lanes    []int   `json:"lanes,omitempty"`    // visible systems joined by jump lanes
+	Distance float64 `json:"distance,omitempty"` // set by Within
+}
+
+// SystemDetail is the public view of a system with its stars and planets.
+type SystemDetail struct {
+	SystemView
+	Stars     []StarView `json:"stars"`
+	Resources int        `json:"resources,omitempty"` // on a rogue planet
+}
+
+// StarView is the public view of a star.
+type StarView struct {
+	Id         string  `json:"id"` // short id, like "Kessa-B"
+	Type       string  `json:"type"`
+	Size       string  `json:"size"`
+	Luminosity float64 `json:"luminosity"`
+	Color      string  `json:"color"`
+	// HabitableOrbit is the orbit at the center of the habitable zone.
+	HabitableOrbit float64      `json:"habitable-orbit"`
+	Planets        []PlanetView `json:"planets"`
+}
+
+// LegendEntry is a color used to draw systems and what it means.
+type LegendEntry struct {
+	Label string `json:"label"`
+	Color string `json:"color"`
+}
+
+// Legend returns the colors for every spectral type, hottest first,
+// followed by the colors for the anomalies in systems without stars.
+func Legend() []LegendEntry {
+	var list []LegendEntry
+	for _, t := range spectralTypes {
+		list = append(list, LegendEntry{Label: "Type " + t.String(), Color: t.color()})
+	}
+	for _, a := range anomalies {
+		label := a.describe()
+		list = append(list, LegendEntry{Label: strings.ToUpper(label[:1]) + label[1:], Color: a.color()})
+	}
+	return list
+}
+
+// PlanetView is the public view of a planet.
+type PlanetView struct {
+	Id           string `json:"id"` // short id, like "Kessa-B-IV"
+	Orbit        int    `json:"orbit"`
+	Kind         string `json:"kind"`
+	Habitability int    `json:"habitability"`
+}
+
+// Visibility reports whether the caller can see the system with the given id.
+type Visibility func(id int) bool
+
+// All is a Visibility that can see every system.
+func All(id int) bool {
+	return true
+}
+
+// NationVisibility returns a Visibility that can see the systems known
+// to any of the nations.
+func NationVisibility(nations ...*Nation) Visibility {
+	return func(id int) bool {
+		for _, n := range nations {
+			if n.Knows(id) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (sys *system) view(visible Visibility) SystemView {
+	sv := SystemView{
+		Id:    sys.id,
+		Name:  sys.name,
+		Ring:  sys.ring,
+		X:     sys.coords.x,
+		Y:     sys.coords.y,
+		Z:     sys.coords.z,
+		Stars: len(sys.stars),
+		Color: sys.color(),
+	}
+	if len(sys.stars) == 0 {
+		sv.Anomaly = sys.anomaly.String()
+	}
+	for _, id := range sys.lanes {
+		if visible(id) {
+			sv.Lanes = append(sv.Lanes, id)
+		}
+	}
+	return sv
+}
+
+// Systems returns the visible systems, sorted by id.
+func (c *Cluster) Systems(visible Visibility) []SystemView {
+	list := []SystemView{}
+	for _, sys := range c.systems {
+		if visible(sys.id) {
+			list = append(list, sys.view(visible))
+		}
+	}
+	return list
+}
+
+// System returns the details for the system with the given id.
+// It returns false if the system doesn't exist or isn't visible.
+func (c *Cluster) System(id int, visible Visibility) (SystemDetail, bool) {
+	if id < 0 || id >= len(c.systems) || !visible(id) {
+		return SystemDetail{}, false
+	}
+	sys := c.systems[id]
+	detail := SystemDetail{SystemView: sys.view(visible), Stars: []StarView{}, Resources: sys.resources}
+	for _, s := range sys.stars {
+		sv := StarView{
+			Id:             s.shortId(),
+			Type:           s.spectral.String(),
+			Size:           s.size.String(),
+			Luminosity:     s.luminosity,
+			Color:          s.color(),
+			HabitableOrbit: math.Round(s.habitableOrbit()*10) / 10,
+			Planets:        []PlanetView{},
+		}
+		for _, p := range s.planets {
+			sv.Planets = append(sv.Planets, PlanetView{Id: p.shortId(), Orbit: p.orbit, Kind: p.kind.String(), Habitability: p.habitability})
+		}
+		detail.Stars = append(detail.Stars, sv)
+	}
+	return detail, true
+}
+
+// Within returns the visible systems that are within the radius of the point,
+// sorted by distance from the point.
+func (c *Cluster) Within(x, y, z, radius float64, visible Visibility) []SystemView {
+	list := []SystemView{}
+	for _, n := range c.index().within(coords{x: x, y: y, z: z}, radius) {
+		if visible(n.sys.id) {
+			sv := n.sys.view(visible)
+			sv.Distance = n.distance
+			list = append(list, sv)
+		}
+	}
+	return list
+}
@@ -0,0 +1,79 @@
+/*
+ * wraith - a game engine
+ * Copyright (c) 2022 Michael D. Henderson
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wraith
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+)
+
+func TestClusterJSON(t *testing.T) {
+	rand.Seed(7)
+	c := G(1, 64, 32, 15.0)
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("marshal: %v\n", err)
+	}
+	var got Cluster
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal: %v\n", err)
+	}
+	if len(got.systems) != len(c.systems) {
+		t.Fatalf("systems: expected %d: got %d\n", len(c.systems), len(got.systems))
+	}
+	for i, sys := range c.systems {
+		a, _ := c.System(i, All)
+		b, _ := got.System(i, All)
+		if a.X != b.X || a.Y != b.Y || a.Z != b.Z || len(a.Stars) != len(b.Stars) {
+			t.Errorf("system %d: round trip does not match\n", i)
+		}
+		if got.systems[i].id != sys.id {
+			t.Errorf("system %d: expected id %d: got %d\n", i, sys.id, got.systems[i].id)
+		}
+	}
+}
+
+func TestVisibility(t *testing.T) {
+	rand.Seed(7)
+	c := G(1, 64, 32, 15.0)
+	s, err := NewState(c, "alpha")
+	if err != nil {
+		t.Fatalf("state: %v\n", err)
+	}
+	n := s.Nations["alpha"]
+
+	visible := NationVisibility(n)
+	systems := c.Systems(visible)
+	if len(systems) != len(n.Known) {
+		t.Errorf("systems: expected %d: got %d\n", len(n.Known), len(systems))
+	}
+	if _, ok := c.System(0, visible); !ok {
+		t.Errorf("home system: expected visible\n")
+	}
+	for _, sys := range c.Within(0, 0, 0, 100, visible) {
+		if !n.Knows(sys.Id) {
+			t.Errorf("within: system %d is not known\n", sys.Id)
+		}
+	}
+	if got := c.Within(0, 0, 0, 100, All); len(got) != len(c.systems) {
+		t.Errorf("within: expected %d: got %d\n", len(c.systems), len(got))
+	}
+}
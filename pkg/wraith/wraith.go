@@ -20,10 +20,58 @@
 package wraith
 
 type system struct {
+	id     int    // index of the system in the cluster
+	name   string // unique name (see names.go)
+	home   bool   // a nation may start here (see newPlacer)
 	ring   int
 	coords coords
 	stars  []*star
+	lanes  []int // ids of the systems joined by jump lanes, sorted
+	// anomaly and resources are set only for systems without stars (see anomalies.go)
+	anomaly   anomaly
+	resources int
 }
 type star struct {
-	system *system
+	system     *system
+	spectral   spectralType
+	size       sizeClass
+	luminosity float64 // relative to the sun
+	planets    []*planet
+}
+type planet struct {
+	star         *star
+	orbit        int
+	kind         planetKind
+	habitability int // 0 is uninhabitable, 25 is ideal
+}
+
+type planetKind int
+
+const (
+	terrestrial planetKind = iota
+	gasGiant
+	asteroidBelt
+)
+
+// String implements the Stringer interface.
+func (k planetKind) String() string {
+	switch k {
+	case terrestrial:
+		return "terrestrial"
+	case gasGiant:
+		return "gas-giant"
+	case asteroidBelt:
+		return "asteroid-belt"
+	}
+	return "unknown"
+}
+
+// parsePlanetKind returns the kind matching the string.
+func parsePlanetKind(s string) (planetKind, bool) {
+	for _, k := range []planetKind{terrestrial, gasGiant, asteroidBelt} {
+		if k.String() == s {
+			return k, true
+		}
+	}
+	return terrestrial, false
 }